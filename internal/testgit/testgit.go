@@ -0,0 +1,20 @@
+// Package testgit contains helpers for the tests of the modules that need a git repo to work with.
+package testgit
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs git with the given args in the given dir and returns its output, failing the test if git fails. The name and
+// email of the committer are set, as they may not be configured where the tests run (e.g., in CI).
+func Run(t testing.TestingT, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=terratest", "-c", "user.email=terratest@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
@@ -1,9 +1,11 @@
 package docker
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/shell"
@@ -57,6 +59,12 @@ type BuildOptions struct {
 
 	// Set a logger that should be used. See the logger package for more info.
 	Logger *logger.Logger
+
+	// If set, the docker command is interrupted when the context is cancelled. See shell.Command for details.
+	Context context.Context `json:"-"`
+
+	// The maximum amount of time the docker command is allowed to run before it is interrupted.
+	Timeout time.Duration
 }
 
 // Build runs the 'docker build' command at the given path with the given options and fails the test if there are any
@@ -83,6 +91,8 @@ func BuildE(t testing.TestingT, path string, options *BuildOptions) error {
 		Args:    formatDockerBuildArgs(path, options),
 		Logger:  options.Logger,
 		Env:     env,
+		Context: options.Context,
+		Timeout: options.Timeout,
 	}
 
 	if err := shell.RunCommandE(t, cmd); err != nil {
@@ -108,6 +118,8 @@ func BuildE(t testing.TestingT, path string, options *BuildOptions) error {
 			Command: "docker",
 			Args:    formatDockerBuildxLoadArgs(path, options),
 			Logger:  options.Logger,
+			Context: options.Context,
+			Timeout: options.Timeout,
 		}
 		return shell.RunCommandE(t, loadCmd)
 	}
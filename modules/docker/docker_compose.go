@@ -1,8 +1,10 @@
 package docker
 
 import (
+	"context"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/shell"
@@ -22,6 +24,12 @@ type Options struct {
 	// Set a logger that should be used. See the logger package for more info.
 	Logger      *logger.Logger
 	ProjectName string
+
+	// If set, docker compose commands are interrupted when the context is cancelled. See shell.Command for details.
+	Context context.Context `json:"-"`
+
+	// The maximum amount of time each docker compose command is allowed to run before it is interrupted.
+	Timeout time.Duration
 }
 
 // RunDockerCompose runs docker compose with the given arguments and options and return stdout/stderr.
@@ -72,6 +80,8 @@ func runDockerComposeE(t testing.TestingT, stdout bool, options *Options, args .
 			WorkingDir: options.WorkingDir,
 			Env:        options.EnvVars,
 			Logger:     options.Logger,
+			Context:    options.Context,
+			Timeout:    options.Timeout,
 		}
 	} else {
 		cmd = shell.Command{
@@ -82,6 +92,8 @@ func runDockerComposeE(t testing.TestingT, stdout bool, options *Options, args .
 			WorkingDir: options.WorkingDir,
 			Env:        options.EnvVars,
 			Logger:     options.Logger,
+			Context:    options.Context,
+			Timeout:    options.Timeout,
 		}
 	}
 
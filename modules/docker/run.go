@@ -1,6 +1,9 @@
 package docker
 
 import (
+	"context"
+	"time"
+
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/testing"
@@ -50,6 +53,12 @@ type RunOptions struct {
 
 	// Set a logger that should be used. See the logger package for more info.
 	Logger *logger.Logger
+
+	// If set, the docker command is interrupted when the context is cancelled. See shell.Command for details.
+	Context context.Context `json:"-"`
+
+	// The maximum amount of time the docker command is allowed to run before it is interrupted.
+	Timeout time.Duration
 }
 
 // Run runs the 'docker run' command on the given image with the given options and return stdout/stderr. This method
@@ -73,6 +82,8 @@ func RunE(t testing.TestingT, image string, options *RunOptions) (string, error)
 		Command: "docker",
 		Args:    args,
 		Logger:  options.Logger,
+		Context: options.Context,
+		Timeout: options.Timeout,
 	}
 
 	return shell.RunCommandAndGetOutputE(t, cmd)
@@ -100,6 +111,8 @@ func RunAndGetIDE(t testing.TestingT, image string, options *RunOptions) (string
 		Command: "docker",
 		Args:    args,
 		Logger:  options.Logger,
+		Context: options.Context,
+		Timeout: options.Timeout,
 	}
 
 	return shell.RunCommandAndGetStdOutE(t, cmd)
@@ -2,6 +2,7 @@
 package git
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
@@ -131,3 +132,74 @@ func GetRepoRootForDirE(t testing.TestingT, dir string) (string, error) {
 	}
 	return strings.TrimSpace(string(bytes)), nil
 }
+
+// CheckoutRefForDir checks out the given ref (branch, tag, or commit) in the repo in which dir resides. This fails the
+// test if there is an error.
+func CheckoutRefForDir(t testing.TestingT, dir string, ref string) {
+	require.NoError(t, CheckoutRefForDirE(t, dir, ref))
+}
+
+// CheckoutRefForDirE checks out the given ref (branch, tag, or commit) in the repo in which dir resides.
+func CheckoutRefForDirE(t testing.TestingT, dir string, ref string) error {
+	cmd := exec.Command("git", "checkout", "--quiet", ref)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to checkout ref %s in %s: %w: %s", ref, dir, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ResolveRefForDir returns the commit SHA that the given ref (branch, tag, commit, or relative ref like HEAD~1) points
+// to in the repo in which dir resides. This fails the test if there is an error.
+func ResolveRefForDir(t testing.TestingT, dir string, ref string) string {
+	out, err := ResolveRefForDirE(t, dir, ref)
+	require.NoError(t, err)
+	return out
+}
+
+// ResolveRefForDirE returns the commit SHA that the given ref (branch, tag, commit, or relative ref like HEAD~1) points
+// to in the repo in which dir resides.
+func ResolveRefForDirE(t testing.TestingT, dir string, ref string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	cmd.Dir = dir
+	bytes, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve ref %s in %s: %w", ref, dir, err)
+	}
+	return strings.TrimSpace(string(bytes)), nil
+}
+
+// AddWorktreeForDir checks out the given ref in a new worktree at worktreeDir of the repo in which dir resides, leaving
+// the checkout of dir untouched. Remove the worktree with RemoveWorktreeForDir when done. This fails the test if there
+// is an error.
+func AddWorktreeForDir(t testing.TestingT, dir string, worktreeDir string, ref string) {
+	require.NoError(t, AddWorktreeForDirE(t, dir, worktreeDir, ref))
+}
+
+// AddWorktreeForDirE checks out the given ref in a new worktree at worktreeDir of the repo in which dir resides, leaving
+// the checkout of dir untouched. Remove the worktree with RemoveWorktreeForDirE when done.
+func AddWorktreeForDirE(t testing.TestingT, dir string, worktreeDir string, ref string) error {
+	cmd := exec.Command("git", "worktree", "add", "--quiet", "--detach", worktreeDir, ref)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to add worktree for ref %s at %s: %w: %s", ref, worktreeDir, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RemoveWorktreeForDir removes the worktree at worktreeDir, along with any files in it, from the repo in which dir
+// resides. This fails the test if there is an error.
+func RemoveWorktreeForDir(t testing.TestingT, dir string, worktreeDir string) {
+	require.NoError(t, RemoveWorktreeForDirE(t, dir, worktreeDir))
+}
+
+// RemoveWorktreeForDirE removes the worktree at worktreeDir, along with any files in it, from the repo in which dir
+// resides.
+func RemoveWorktreeForDirE(t testing.TestingT, dir string, worktreeDir string) error {
+	cmd := exec.Command("git", "worktree", "remove", "--force", "--force", worktreeDir)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove worktree at %s: %w: %s", worktreeDir, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/internal/testgit"
 )
 
 func testGetCurrentBranchNameReturnsBranchName(t *testing.T) {
@@ -86,3 +88,57 @@ func TestGetRepoRoot(t *testing.T) {
 	repoRoot := GetRepoRoot(t)
 	assert.Equal(t, expectedRepoRoot, repoRoot)
 }
+
+func TestCheckoutRefForDir(t *testing.T) {
+	t.Parallel()
+
+	repoDir := t.TempDir()
+	filePath := filepath.Join(repoDir, "version.txt")
+	testgit.Run(t, repoDir, "init", "--quiet")
+	require.NoError(t, os.WriteFile(filePath, []byte("v1"), 0644))
+	testgit.Run(t, repoDir, "add", "version.txt")
+	testgit.Run(t, repoDir, "commit", "--quiet", "-m", "v1")
+	testgit.Run(t, repoDir, "tag", "v1")
+	require.NoError(t, os.WriteFile(filePath, []byte("v2"), 0644))
+	testgit.Run(t, repoDir, "commit", "--quiet", "-am", "v2")
+
+	CheckoutRefForDir(t, repoDir, "v1")
+	contents, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(contents))
+
+	assert.Error(t, CheckoutRefForDirE(t, repoDir, "does-not-exist"))
+}
+
+func TestResolveRefAndWorktreeForDir(t *testing.T) {
+	t.Parallel()
+
+	repoDir := t.TempDir()
+	filePath := filepath.Join(repoDir, "version.txt")
+	testgit.Run(t, repoDir, "init", "--quiet")
+	require.NoError(t, os.WriteFile(filePath, []byte("v1"), 0644))
+	testgit.Run(t, repoDir, "add", "version.txt")
+	testgit.Run(t, repoDir, "commit", "--quiet", "-m", "v1")
+	v1 := testgit.Run(t, repoDir, "rev-parse", "HEAD")
+	require.NoError(t, os.WriteFile(filePath, []byte("v2"), 0644))
+	testgit.Run(t, repoDir, "commit", "--quiet", "-am", "v2")
+
+	assert.Equal(t, v1, ResolveRefForDir(t, repoDir, "HEAD~1"))
+	_, err := ResolveRefForDirE(t, repoDir, "does-not-exist")
+	assert.Error(t, err)
+
+	worktreeDir := filepath.Join(t.TempDir(), "worktree")
+	AddWorktreeForDir(t, repoDir, worktreeDir, "HEAD~1")
+	contents, err := os.ReadFile(filepath.Join(worktreeDir, "version.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(contents))
+
+	// The checkout of the repo itself is untouched
+	contents, err = os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(contents))
+	assert.Equal(t, v1, ResolveRefForDir(t, repoDir, "HEAD~1"))
+
+	RemoveWorktreeForDir(t, repoDir, worktreeDir)
+	assert.NoDirExists(t, worktreeDir)
+}
@@ -75,6 +75,8 @@ func prepareHelmCommand(t testing.TestingT, options *Options, cmd string, additi
 		WorkingDir: ".",
 		Env:        options.EnvVars,
 		Logger:     options.Logger,
+		Context:    options.Context,
+		Timeout:    options.Timeout,
 	}
 	return helmCmd
 }
@@ -1,6 +1,9 @@
 package helm
 
 import (
+	"context"
+	"time"
+
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/logger"
 )
@@ -19,4 +22,6 @@ type Options struct {
 	ExtraArgs         map[string][]string // Extra arguments to pass to the helm install/upgrade/rollback/delete and helm repo add commands. The key signals the command (e.g., install) while the values are the extra arguments to pass through.
 	BuildDependencies bool                // If true, helm dependencies will be built before rendering template, installing or upgrade the chart.
 	SnapshotPath      string              // The path to the snapshot directory when using snapshot based testing. Empty string means use default ($PWD/__snapshot__).
+	Context           context.Context     `json:"-"` // If set, running helm commands are interrupted when the context is cancelled. See shell.Command for details.
+	Timeout           time.Duration       // The maximum amount of time each individual helm command is allowed to run before it is interrupted.
 }
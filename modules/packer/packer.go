@@ -2,6 +2,7 @@
 package packer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -34,6 +35,8 @@ type Options struct {
 	WorkingDir                 string            // The directory to run packer in
 	Logger                     *logger.Logger    // If set, use a non-default logger
 	DisableTemporaryPluginPath bool              // If set, do not use a temporary directory for Packer plugins.
+	Context                    context.Context   `json:"-"` // If set, running Packer commands are interrupted when the context is cancelled. See shell.Command for details.
+	Timeout                    time.Duration     // The maximum amount of time each individual Packer command is allowed to run before it is interrupted.
 }
 
 // BuildArtifacts can take a map of identifierName <-> Options and then parallelize
@@ -124,11 +127,13 @@ func BuildArtifactE(t testing.TestingT, options *Options) (string, error) {
 		Args:       formatPackerArgs(options),
 		Env:        options.Env,
 		WorkingDir: options.WorkingDir,
+		Context:    options.Context,
+		Timeout:    options.Timeout,
 	}
 
 	description := fmt.Sprintf("%s %v", cmd.Command, cmd.Args)
 	output, err := retry.DoWithRetryableErrorsE(t, description, options.RetryableErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
-		return shell.RunCommandAndGetOutputE(t, cmd)
+		return shell.FatalIfCommandCancelled(shell.RunCommandAndGetOutputE(t, cmd))
 	})
 
 	if err != nil {
@@ -224,11 +229,13 @@ func packerInit(t testing.TestingT, options *Options) error {
 		Args:       []string{"init", options.Template},
 		Env:        options.Env,
 		WorkingDir: options.WorkingDir,
+		Context:    options.Context,
+		Timeout:    options.Timeout,
 	}
 
 	description := "Running Packer init"
 	_, err = retry.DoWithRetryableErrorsE(t, description, options.RetryableErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
-		return shell.RunCommandAndGetOutputE(t, cmd)
+		return shell.FatalIfCommandCancelled(shell.RunCommandAndGetOutputE(t, cmd))
 	})
 
 	if err != nil {
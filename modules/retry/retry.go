@@ -216,3 +216,7 @@ type FatalError struct {
 func (err FatalError) Error() string {
 	return fmt.Sprintf("FatalError{Underlying: %v}", err.Underlying)
 }
+
+func (err FatalError) Unwrap() error {
+	return err.Underlying
+}
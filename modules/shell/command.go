@@ -2,6 +2,7 @@ package shell
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -10,8 +11,10 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -24,8 +27,33 @@ type Command struct {
 	Env        map[string]string // Additional environment variables to set
 	// Use the specified logger for the command's output. Use logger.Discard to not print the output while executing the command.
 	Logger *logger.Logger
+	// The input to pass to the command on stdin. Defaults to the stdin of this Go program. Only one of Stdin, StdinText,
+	// and Expect can be set.
+	Stdin io.Reader
+	// The text to pass to the command on stdin, as a shorthand for setting Stdin to a strings.Reader.
+	StdinText string
+	// A script to answer the prompts of an interactive command (e.g., terraform apply without -auto-approve), in the
+	// manner of expect: for each step in order, wait for its pattern to show up in the output of the command and then send
+	// its text on stdin. Once all the steps are done, stdin is closed. If a step does not match, the command is
+	// interrupted in the same way as when Context is cancelled and an ErrExpectFailed with a transcript of the
+	// interaction is returned.
+	Expect []ExpectStep
+
+	// If set, the command is interrupted when the context is cancelled or its deadline expires. The process group is
+	// first sent SIGINT so the command can shut down gracefully (e.g., terraform releasing its state lock), and is then
+	// sent SIGKILL if it is still running after GracePeriod.
+	Context context.Context
+	// If set, the maximum amount of time the command is allowed to run before it is interrupted in the same way as a
+	// cancelled Context.
+	Timeout time.Duration
+	// How long to wait after interrupting the command before killing it. Defaults to DefaultGracePeriod.
+	GracePeriod time.Duration
 }
 
+// DefaultGracePeriod is how long a cancelled or timed out command is given to exit after being interrupted before it is
+// killed.
+const DefaultGracePeriod = 30 * time.Second
+
 // RunCommand runs a shell command and redirects its stdout and stderr to the stdout of the atomic script itself. If
 // there are any errors, fail the test.
 func RunCommand(t testing.TestingT, command Command) {
@@ -93,16 +121,66 @@ func (e *ErrWithCmdOutput) Error() string {
 	return fmt.Sprintf("error while running command: %v; %s", e.Underlying, e.Output.Stderr())
 }
 
+func (e *ErrWithCmdOutput) Unwrap() error {
+	return e.Underlying
+}
+
+// ErrCommandCancelled is returned when a command was interrupted because its Context was cancelled or its Timeout
+// expired.
+type ErrCommandCancelled struct {
+	Command string
+	Cause   error // The context error, either context.Canceled or context.DeadlineExceeded
+}
+
+func (e *ErrCommandCancelled) Error() string {
+	return fmt.Sprintf("command %s was interrupted: %v", e.Command, e.Cause)
+}
+
+func (e *ErrCommandCancelled) Unwrap() error {
+	return e.Cause
+}
+
+// IsCommandCancelled returns true if the given error (or any error it wraps) is an ErrCommandCancelled.
+func IsCommandCancelled(err error) bool {
+	var cancelledErr *ErrCommandCancelled
+	return errors.As(err, &cancelledErr)
+}
+
+// FatalIfCommandCancelled wraps the given error in a retry.FatalError if the command was interrupted because its Context
+// was cancelled or its Timeout expired, so that retry.DoWithRetryableErrorsE does not retry it even if the partial output
+// happens to match one of the retryable errors. The output is returned as is.
+func FatalIfCommandCancelled(out string, err error) (string, error) {
+	if IsCommandCancelled(err) {
+		return out, retry.FatalError{Underlying: err}
+	}
+	return out, err
+}
+
 // runCommand runs a shell command and stores each line from stdout and stderr in Output. Depending on the logger, the
 // stdout and stderr of that command will also be printed to the stdout and stderr of this Go program to make debugging
 // easier.
 func runCommand(t testing.TestingT, command Command) (*output, error) {
 	command.Logger.Logf(t, "Running command %s with args %s", command.Command, command.Args)
 
+	ctx, cancel := commandContext(command)
+	defer cancel()
+	if ctx.Err() != nil {
+		return nil, &ErrCommandCancelled{Command: command.Command, Cause: ctx.Err()}
+	}
+
 	cmd := exec.Command(command.Command, command.Args...)
 	cmd.Dir = command.WorkingDir
-	cmd.Stdin = os.Stdin
 	cmd.Env = formatEnvVars(command)
+	if command.Context != nil || command.Timeout > 0 || len(command.Expect) > 0 {
+		// Only detach into a separate process group when the command can be cancelled, so that by default a Ctrl+C in
+		// the terminal still reaches the command directly.
+		setProcessGroup(cmd)
+	}
+
+	expect, expectStdin, err := setStdin(cmd, command, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -119,18 +197,137 @@ func runCommand(t testing.TestingT, command Command) (*output, error) {
 		return nil, err
 	}
 
-	output, err := readStdoutAndStderr(t, command.Logger, stdout, stderr)
+	// A failed expect script interrupts the command in the same way as a cancelled context
+	commandCtx, interrupt := context.WithCancel(ctx)
+	defer interrupt()
+
+	done := make(chan struct{})
+	defer close(done)
+	go interruptOnCancel(t, commandCtx, command, cmd, done)
+
+	var stdoutReader, stderrReader io.Reader = stdout, stderr
+	var expectErr chan error
+	if expect != nil {
+		stdoutReader = io.TeeReader(stdout, expect)
+		stderrReader = io.TeeReader(stderr, expect)
+		expectErr = make(chan error, 1)
+		go func() {
+			err := expect.run(t, command, expectStdin)
+			if err != nil {
+				command.Logger.Logf(t, "%v", err)
+				interrupt()
+			}
+			expectErr <- err
+		}()
+	}
+
+	output, err := readStdoutAndStderr(t, command.Logger, stdoutReader, stderrReader)
+	if expect != nil {
+		expect.closeOutput()
+		if scriptErr := <-expectErr; scriptErr != nil && ctx.Err() == nil {
+			cmd.Wait()
+			return output, scriptErr
+		}
+	}
 	if err != nil {
 		return output, err
 	}
 
-	return output, cmd.Wait()
+	// A command that completed successfully just as the context was cancelled is not reported as cancelled
+	err = cmd.Wait()
+	if err != nil && ctx.Err() != nil {
+		return output, &ErrCommandCancelled{Command: command.Command, Cause: ctx.Err()}
+	}
+	return output, err
+}
+
+// setStdin sets the stdin of the given command according to its settings, or to defaultStdin if it has none. If the
+// command has an expect script, it returns the expecter to run it along with the stdin pipe of the command.
+func setStdin(cmd *exec.Cmd, command Command, defaultStdin io.Reader) (*expecter, io.WriteCloser, error) {
+	sources := 0
+	for _, isSet := range []bool{command.Stdin != nil, command.StdinText != "", len(command.Expect) > 0} {
+		if isSet {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, nil, errors.New("only one of Stdin, StdinText, and Expect can be set on a command")
+	}
+
+	switch {
+	case command.Stdin != nil:
+		cmd.Stdin = command.Stdin
+	case command.StdinText != "":
+		cmd.Stdin = strings.NewReader(command.StdinText)
+	case len(command.Expect) > 0:
+		expect, err := newExpecter(command.Expect)
+		if err != nil {
+			return nil, nil, err
+		}
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		return expect, stdin, nil
+	default:
+		cmd.Stdin = defaultStdin
+	}
+	return nil, nil, nil
+}
+
+// commandContext returns the context that bounds the execution of the given command, taking into account both the
+// Context and Timeout settings.
+func commandContext(command Command) (context.Context, context.CancelFunc) {
+	ctx := command.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if command.Timeout > 0 {
+		return context.WithTimeout(ctx, command.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// interruptOnCancel waits for either the command to finish (signaled by closing done) or the context to be cancelled.
+// In the latter case, the process group of the command is interrupted, and then killed if it does not exit within the
+// grace period.
+func interruptOnCancel(t testing.TestingT, ctx context.Context, command Command, cmd *exec.Cmd, done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	gracePeriod := command.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultGracePeriod
+	}
+
+	command.Logger.Logf(t, "Interrupting command %s (%v), will kill it if it is still running in %s", command.Command, ctx.Err(), gracePeriod)
+	if err := interruptProcessGroup(cmd); err != nil {
+		command.Logger.Logf(t, "Failed to interrupt command %s: %v", command.Command, err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(gracePeriod):
+		command.Logger.Logf(t, "Command %s did not exit within %s of being interrupted, killing it", command.Command, gracePeriod)
+		if err := killProcessGroup(cmd); err != nil {
+			command.Logger.Logf(t, "Failed to kill command %s: %v", command.Command, err)
+		}
+	}
 }
 
 // This function captures stdout and stderr into the given variables while still printing it to the stdout and stderr
 // of this Go program
-func readStdoutAndStderr(t testing.TestingT, log *logger.Logger, stdout, stderr io.ReadCloser) (*output, error) {
+func readStdoutAndStderr(t testing.TestingT, log *logger.Logger, stdout, stderr io.Reader) (*output, error) {
 	out := newOutput()
+	err := readStdoutAndStderrInto(t, log, stdout, stderr, out.stdout, out.stderr)
+	return out, err
+}
+
+// readStdoutAndStderrInto is like readStdoutAndStderr, but writes each line of stdout and stderr to the given writers.
+func readStdoutAndStderrInto(t testing.TestingT, log *logger.Logger, stdout, stderr io.Reader, stdoutWriter, stderrWriter io.StringWriter) error {
 	stdoutReader := bufio.NewReader(stdout)
 	stderrReader := bufio.NewReader(stderr)
 
@@ -140,22 +337,18 @@ func readStdoutAndStderr(t testing.TestingT, log *logger.Logger, stdout, stderr
 	var stdoutErr, stderrErr error
 	go func() {
 		defer wg.Done()
-		stdoutErr = readData(t, log, stdoutReader, out.stdout)
+		stdoutErr = readData(t, log, stdoutReader, stdoutWriter)
 	}()
 	go func() {
 		defer wg.Done()
-		stderrErr = readData(t, log, stderrReader, out.stderr)
+		stderrErr = readData(t, log, stderrReader, stderrWriter)
 	}()
 	wg.Wait()
 
 	if stdoutErr != nil {
-		return out, stdoutErr
+		return stdoutErr
 	}
-	if stderrErr != nil {
-		return out, stderrErr
-	}
-
-	return out, nil
+	return stderrErr
 }
 
 func readData(t testing.TestingT, log *logger.Logger, reader *bufio.Reader, writer io.StringWriter) error {
@@ -205,6 +398,15 @@ func GetExitCodeForRunCommandError(err error) (int, error) {
 		err = errWithOutput.Underlying
 	}
 
+	// A cancelled command, or one interrupted by its expect script, has no meaningful exit code, and must not be mistaken
+	// for a successful one.
+	if cancelledErr, ok := err.(*ErrCommandCancelled); ok {
+		return 1, cancelledErr
+	}
+	if expectErr, ok := err.(*ErrExpectFailed); ok {
+		return 1, expectErr
+	}
+
 	// http://stackoverflow.com/a/10385867/483528
 	if exitErr, ok := err.(*exec.ExitError); ok {
 		// The program has exited with an exit code != 0
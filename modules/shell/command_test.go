@@ -2,15 +2,20 @@ package shell
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
 )
 
 func TestRunCommandAndGetOutput(t *testing.T) {
@@ -183,3 +188,244 @@ func TestCommandOutputType(t *testing.T) {
 		assert.Len(t, o.Output.Combined(), len(stdout)+len(stderr)+1) // +1 for newline
 	}
 }
+
+func TestRunCommandWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	out, err := RunCommandAndGetOutputE(t, Command{
+		Command:     "bash",
+		Args:        []string{"-c", "echo started && sleep 60"},
+		Timeout:     500 * time.Millisecond,
+		GracePeriod: 5 * time.Second,
+		Logger:      logger.Discard,
+	})
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, "started", out)
+	assert.True(t, IsCommandCancelled(err))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	code, exitCodeErr := GetExitCodeForRunCommandError(err)
+	assert.Error(t, exitCodeErr)
+	assert.Equal(t, 1, code)
+}
+
+func TestRunCommandWithCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		cancel()
+	}()
+
+	err := RunCommandE(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", "sleep 60"},
+		Context: ctx,
+		Logger:  logger.Discard,
+	})
+
+	assert.True(t, IsCommandCancelled(err))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestRunCommandKilledAfterGracePeriod ensures a command that ignores SIGINT is killed once the grace period expires.
+func TestRunCommandKilledAfterGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	err := RunCommandE(t, Command{
+		Command:     "bash",
+		Args:        []string{"-c", "trap '' INT; sleep 60"},
+		Timeout:     500 * time.Millisecond,
+		GracePeriod: time.Second,
+		Logger:      logger.Discard,
+	})
+
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.True(t, IsCommandCancelled(err))
+}
+
+func TestRunCommandWithTimeoutCompletesNormally(t *testing.T) {
+	t.Parallel()
+
+	out, err := RunCommandAndGetOutputE(t, Command{
+		Command: "echo",
+		Args:    []string{"hello"},
+		Timeout: time.Minute,
+		Logger:  logger.Discard,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", out)
+}
+
+func TestRunCommandWithStdin(t *testing.T) {
+	t.Parallel()
+
+	cmd := Command{
+		Command: "cat",
+		Stdin:   strings.NewReader("Hello, Stdin"),
+	}
+
+	out := RunCommandAndGetOutput(t, cmd)
+	assert.Equal(t, "Hello, Stdin", strings.TrimSpace(out))
+}
+
+func TestRunCommandWithStdinText(t *testing.T) {
+	t.Parallel()
+
+	out := RunCommandAndGetOutput(t, Command{
+		Command:   "cat",
+		StdinText: "Hello, Stdin",
+	})
+	assert.Equal(t, "Hello, Stdin", strings.TrimSpace(out))
+}
+
+func TestRunCommandWithMoreThanOneStdinSource(t *testing.T) {
+	t.Parallel()
+
+	err := RunCommandE(t, Command{
+		Command:   "cat",
+		Stdin:     strings.NewReader("Hello"),
+		StdinText: "Hello",
+	})
+	assert.EqualError(t, err, "error while running command: only one of Stdin, StdinText, and Expect can be set on a command; ")
+}
+
+func TestRunCommandWithExpectScript(t *testing.T) {
+	t.Parallel()
+
+	out := RunCommandAndGetStdOut(t, Command{
+		Command: "bash",
+		Args: []string{"-c", `
+printf "Name: "; read name
+printf "Token: " >&2; read token
+echo "Hello, $name (${#token} characters)"
+read -p "Continue? " answer || echo "stdin closed"`},
+		Expect: []ExpectStep{
+			{Pattern: `Name: $`, Send: "terratest\n"},
+			{Pattern: `Token:`, Send: "s3cr3t\n", Sensitive: true},
+		},
+	})
+	assert.Equal(t, "Name: Hello, terratest (6 characters)\nstdin closed", out)
+}
+
+func TestRunCommandWithExpectScriptTimeout(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	err := RunCommandE(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", `printf "Name: "; read name; printf "Password: "; read password`},
+		Expect: []ExpectStep{
+			{Pattern: `Name:`, Send: "terratest\n"},
+			{Pattern: `Token:`, Send: "s3cr3t\n", Timeout: 500 * time.Millisecond},
+		},
+		GracePeriod: 5 * time.Second,
+		Logger:      logger.Discard,
+	})
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	var expectErr *ErrExpectFailed
+	if assert.ErrorAs(t, err, &expectErr) {
+		assert.Equal(t, 1, expectErr.Step)
+		assert.Equal(t, "did not match within 500ms", expectErr.Reason)
+		assert.Equal(t, "Name: [sent \"terratest\\n\"]\nPassword: ", expectErr.Transcript)
+	}
+	assert.False(t, IsCommandCancelled(err))
+
+	code, exitCodeErr := GetExitCodeForRunCommandError(err)
+	assert.Error(t, exitCodeErr)
+	assert.Equal(t, 1, code)
+}
+
+func TestRunCommandWithExpectScriptOutputClosed(t *testing.T) {
+	t.Parallel()
+
+	err := RunCommandE(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", `printf "Token: "; read -s token; echo "Done"`},
+		Expect: []ExpectStep{
+			{Pattern: `Token:`, Send: "s3cr3t\n", Sensitive: true},
+			{Pattern: `Continue\?`, Send: "yes\n"},
+		},
+		Logger: logger.Discard,
+	})
+
+	var expectErr *ErrExpectFailed
+	if assert.ErrorAs(t, err, &expectErr) {
+		assert.Equal(t, 1, expectErr.Step)
+		assert.Equal(t, "did not match before the command closed its output", expectErr.Reason)
+		assert.Equal(t, "Token: [sent <sensitive>]\nDone\n", expectErr.Transcript)
+		assert.NotContains(t, expectErr.Error(), "s3cr3t")
+	}
+}
+
+// nopWriteCloser stands in for the stdin pipe of a command.
+type nopWriteCloser struct {
+	bytes.Buffer
+}
+
+func (w *nopWriteCloser) Close() error { return nil }
+
+func TestExpecterStopsBufferingWhenDone(t *testing.T) {
+	t.Parallel()
+
+	expect, err := newExpecter([]ExpectStep{{Pattern: `Continue\?`, Send: "yes\n"}})
+	assert.NoError(t, err)
+
+	stdin := &nopWriteCloser{}
+	expect.Write([]byte("Continue? "))
+	assert.NoError(t, expect.run(t, Command{Command: "apply", Logger: logger.Discard}, stdin))
+	assert.Equal(t, "yes\n", stdin.String())
+
+	// The output of a long running command after the last step is not kept in memory
+	expect.Write([]byte(strings.Repeat("Still applying...\n", 1000)))
+	assert.Empty(t, expect.unmatched)
+	assert.Zero(t, expect.transcript.Len())
+}
+
+func TestRunCommandWithInvalidExpectPattern(t *testing.T) {
+	t.Parallel()
+
+	err := RunCommandE(t, Command{
+		Command: "cat",
+		Expect:  []ExpectStep{{Pattern: `(`, Send: "yes\n"}},
+	})
+	assert.ErrorContains(t, err, "step 0 of the expect script has an invalid pattern")
+}
+
+func TestRunCommandWithContextCancelledBeforeStart(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dir := t.TempDir()
+	err := RunCommandE(t, Command{
+		Command:    "touch",
+		Args:       []string{"started"},
+		WorkingDir: dir,
+		Context:    ctx,
+		Logger:     logger.Discard,
+	})
+
+	assert.True(t, IsCommandCancelled(err))
+	assert.NoFileExists(t, filepath.Join(dir, "started"))
+}
+
+func TestFatalIfCommandCancelled(t *testing.T) {
+	t.Parallel()
+
+	cancelledErr := &ErrWithCmdOutput{Underlying: &ErrCommandCancelled{Command: "terraform", Cause: context.Canceled}}
+	out, err := FatalIfCommandCancelled("partial output", cancelledErr)
+	assert.Equal(t, "partial output", out)
+	assert.Equal(t, retry.FatalError{Underlying: cancelledErr}, err)
+
+	otherErr := errors.New("exit status 1")
+	_, err = FatalIfCommandCancelled("", otherErr)
+	assert.Equal(t, otherErr, err)
+}
@@ -0,0 +1,22 @@
+//go:build windows
+// +build windows
+
+package shell
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, which has no process groups that can be signaled.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// interruptProcessGroup kills the process of the given command, as Windows does not support sending interrupts to
+// other processes.
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// killProcessGroup kills the process of the given command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,193 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// DefaultExpectTimeout is how long a step of an expect script waits for its pattern to show up in the output of the
+// command if the step has no Timeout.
+const DefaultExpectTimeout = time.Minute
+
+// ExpectStep is a step of an expect script (see Command.Expect): wait for the output of the command to match Pattern,
+// and then send Send to its stdin.
+type ExpectStep struct {
+	// The regular expression to look for in the stdout and stderr of the command, since the match of the previous step.
+	// Note that prompts usually don't end with a newline, so don't anchor the pattern to the end of a line.
+	Pattern string
+	// The text to send to the command once the pattern matches. Add a "\n" to the end to submit a line.
+	Send string
+	// How long to wait for the pattern to match. Defaults to DefaultExpectTimeout.
+	Timeout time.Duration
+	// If true, the text that is sent is not logged or included in the transcript (e.g., for passwords or tokens).
+	Sensitive bool
+}
+
+// ErrExpectFailed is returned when a step of the expect script of a command did not match. The command is interrupted
+// in the same way as when its Context is cancelled.
+type ErrExpectFailed struct {
+	Command    string
+	Step       int // The index of the step that failed
+	Pattern    string
+	Reason     string
+	Transcript string // The output of the command, interleaved with the text that was sent to it
+}
+
+func (e *ErrExpectFailed) Error() string {
+	return fmt.Sprintf("step %d of the expect script of command %s (pattern %q) %s. Transcript:\n%s", e.Step, e.Command, e.Pattern, e.Reason, e.Transcript)
+}
+
+// expecter runs the expect script of a command. It is written the output of the command as it comes in, and sends the
+// text of each step to the stdin of the command once the pattern of the step matches the output. The output is only
+// buffered until the script is done, as the rest of it can't affect the outcome of the script.
+type expecter struct {
+	steps    []ExpectStep
+	patterns []*regexp.Regexp
+
+	mutex sync.Mutex
+	// The output of the command since the last match
+	unmatched string
+	// The output of the command, interleaved with the text that was sent to it
+	transcript strings.Builder
+	// Whether the command has closed its stdout and stderr
+	outputClosed bool
+	// Whether the script is done, after which the output is no longer buffered
+	finished bool
+	// Closed and replaced whenever there is new output or the output is closed
+	changed chan struct{}
+}
+
+// newExpecter returns an expecter for the given steps, or an error if any of their patterns is not a valid regular
+// expression.
+func newExpecter(steps []ExpectStep) (*expecter, error) {
+	patterns := make([]*regexp.Regexp, len(steps))
+	for i, step := range steps {
+		pattern, err := regexp.Compile(step.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("step %d of the expect script has an invalid pattern: %w", i, err)
+		}
+		patterns[i] = pattern
+	}
+	return &expecter{steps: steps, patterns: patterns, changed: make(chan struct{})}, nil
+}
+
+// Write records output of the command.
+func (e *expecter) Write(p []byte) (int, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.finished {
+		return len(p), nil
+	}
+	e.unmatched += string(p)
+	e.transcript.Write(p)
+	e.notify()
+	return len(p), nil
+}
+
+// closeOutput records that the command has closed its stdout and stderr, so no step can match anymore.
+func (e *expecter) closeOutput() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.outputClosed = true
+	e.notify()
+}
+
+// notify wakes up the steps waiting for output. The caller must hold the mutex.
+func (e *expecter) notify() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// run runs the steps in order, sending their text to stdin, which is closed once all the steps are done.
+func (e *expecter) run(t testing.TestingT, command Command, stdin io.WriteCloser) error {
+	defer stdin.Close()
+	defer e.finish()
+
+	for i, step := range e.steps {
+		timeout := step.Timeout
+		if timeout <= 0 {
+			timeout = DefaultExpectTimeout
+		}
+		if reason := e.waitForMatch(i, timeout); reason != "" {
+			return e.failure(command, i, reason)
+		}
+
+		sent := fmt.Sprintf("%q", step.Send)
+		if step.Sensitive {
+			sent = "<sensitive>"
+		}
+		command.Logger.Logf(t, "Output of command %s matched %q, sending %s", command.Command, step.Pattern, sent)
+		e.record(fmt.Sprintf("[sent %s]\n", sent))
+
+		if _, err := io.WriteString(stdin, step.Send); err != nil {
+			return e.failure(command, i, fmt.Sprintf("matched, but sending the text failed: %v", err))
+		}
+	}
+	return nil
+}
+
+// waitForMatch waits for the pattern of the given step to match the output. It returns an empty string if it matched,
+// or the reason it didn't otherwise.
+func (e *expecter) waitForMatch(step int, timeout time.Duration) string {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		e.mutex.Lock()
+		if match := e.patterns[step].FindStringIndex(e.unmatched); match != nil {
+			e.unmatched = e.unmatched[match[1]:]
+			e.mutex.Unlock()
+			return ""
+		}
+		if e.outputClosed {
+			e.mutex.Unlock()
+			return "did not match before the command closed its output"
+		}
+		changed := e.changed
+		e.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return fmt.Sprintf("did not match within %s", timeout)
+		}
+	}
+}
+
+// finish stops buffering the output of the command, and drops what was buffered so far.
+func (e *expecter) finish() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.finished = true
+	e.unmatched = ""
+	e.transcript.Reset()
+}
+
+func (e *expecter) record(text string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.transcript.WriteString(text)
+}
+
+func (e *expecter) failure(command Command, step int, reason string) *ErrExpectFailed {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return &ErrExpectFailed{
+		Command:    command.Command,
+		Step:       step,
+		Pattern:    e.steps[step].Pattern,
+		Reason:     reason,
+		Transcript: e.transcript.String(),
+	}
+}
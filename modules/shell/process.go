@@ -0,0 +1,436 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// Process is a command running in the background (e.g., a mock server or kubectl proxy), started with Start. All of the
+// output of the command is kept in memory for as long as the Process is, so that it can be read with Stdout, Stderr,
+// and Combined, and included in errors. Keep that in mind for processes that run for a long time and log a lot.
+type Process struct {
+	Command Command
+
+	cmd *exec.Cmd
+
+	// Guards the fields below, which change while the process runs
+	mutex sync.Mutex
+	// The output of the command split into lines, as with RunCommandAndGetOutput
+	output *output
+	// The output of the command as it came in, including lines that are not complete yet (e.g., prompts)
+	rawOutput strings.Builder
+	// Closed and replaced whenever there is new output or the process exits
+	changed chan struct{}
+	stopped bool
+	exited  bool
+	waitErr error
+
+	// Closed once the process has exited and all of its output has been read
+	done chan struct{}
+}
+
+// cleanupRegistrar is implemented by *testing.T.
+type cleanupRegistrar interface {
+	Cleanup(f func())
+}
+
+// ErrProcessWaitFailed is returned when a background process did not print the expected output or open the expected
+// port in time, or exited before doing so.
+type ErrProcessWaitFailed struct {
+	Command   string
+	Condition string // What was waited for (e.g., output matching a pattern)
+	Reason    string
+	Output    string // The output of the process so far
+}
+
+func (e *ErrProcessWaitFailed) Error() string {
+	return fmt.Sprintf("command %s did not %s: %s. Output:\n%s", e.Command, e.Condition, e.Reason, e.Output)
+}
+
+// Start starts a command in the background and returns a handle to wait for it to be ready, read its output, and stop
+// it. The output of the command is logged as it comes in, as with RunCommand. If t is a *testing.T, the process is
+// stopped when the test completes if it is still running, otherwise make sure to call Stop. Unless Stdin or StdinText
+// is set, the command gets no input. Expect scripts are not supported, use WaitForOutput instead. If the Context of the
+// command is cancelled or its Timeout expires, the process is stopped in the same way as with RunCommand. If there are
+// any errors, fail the test.
+//
+// Example:
+//
+//	proxy := shell.Start(t, shell.Command{Command: "kubectl", Args: []string{"proxy", "--port", "8001"}})
+//	proxy.WaitForPort(t, 8001, 30*time.Second)
+//	http_helper.HttpGetWithRetry(t, "http://localhost:8001/version", nil, 200, expected, 10, time.Second)
+//	proxy.Stop(t, 10*time.Second)
+func Start(t testing.TestingT, command Command) *Process {
+	process, err := StartE(t, command)
+	require.NoError(t, err)
+	return process
+}
+
+// StartE starts a command in the background and returns a handle to wait for it to be ready, read its output, and stop
+// it. See Start for more info.
+func StartE(t testing.TestingT, command Command) (*Process, error) {
+	if len(command.Expect) > 0 {
+		return nil, errors.New("Start does not support expect scripts, use WaitForOutput instead")
+	}
+
+	command.Logger.Logf(t, "Starting command %s with args %s in the background", command.Command, command.Args)
+
+	cmd := exec.Command(command.Command, command.Args...)
+	cmd.Dir = command.WorkingDir
+	cmd.Env = formatEnvVars(command)
+	// Always run the process in its own process group, so that stopping it also stops any child processes it spawned
+	setProcessGroup(cmd)
+	if _, _, err := setStdin(cmd, command, nil); err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := commandContext(command)
+	if ctx.Err() != nil {
+		cancel()
+		return nil, &ErrCommandCancelled{Command: command.Command, Cause: ctx.Err()}
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	process := &Process{
+		Command: command,
+		cmd:     cmd,
+		output:  newOutput(),
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	exited := make(chan struct{})
+	go interruptOnCancel(t, ctx, command, cmd, exited)
+
+	go func() {
+		defer cancel()
+
+		raw := processRawOutput{process}
+		readErr := readStdoutAndStderrInto(
+			t,
+			command.Logger,
+			io.TeeReader(stdout, raw),
+			io.TeeReader(stderr, raw),
+			processOutputStream{process, process.output.stdout},
+			processOutputStream{process, process.output.stderr},
+		)
+		waitErr := cmd.Wait()
+		close(exited)
+
+		process.mutex.Lock()
+		switch {
+		case waitErr != nil && ctx.Err() != nil && !process.stopped:
+			process.waitErr = &ErrCommandCancelled{Command: command.Command, Cause: ctx.Err()}
+		case waitErr != nil:
+			process.waitErr = waitErr
+		default:
+			process.waitErr = readErr
+		}
+		process.exited = true
+		process.notify()
+		process.mutex.Unlock()
+
+		close(process.done)
+	}()
+
+	if registrar, canRegister := t.(cleanupRegistrar); canRegister {
+		registrar.Cleanup(func() {
+			if !process.Exited() {
+				command.Logger.Logf(t, "Stopping command %s as the test completed", command.Command)
+			}
+			if err := process.StopE(t, command.GracePeriod); err != nil {
+				t.Errorf("Failed to stop command %s: %v", command.Command, err)
+			}
+		})
+	}
+
+	return process, nil
+}
+
+// processRawOutput records the output of a process as it comes in.
+type processRawOutput struct {
+	process *Process
+}
+
+func (raw processRawOutput) Write(p []byte) (int, error) {
+	raw.process.mutex.Lock()
+	defer raw.process.mutex.Unlock()
+
+	raw.process.rawOutput.Write(p)
+	raw.process.notify()
+	return len(p), nil
+}
+
+// processOutputStream writes the lines of stdout or stderr of a process while holding its lock, so that they can be
+// read while the process runs.
+type processOutputStream struct {
+	process *Process
+	stream  io.StringWriter
+}
+
+func (stream processOutputStream) WriteString(s string) (int, error) {
+	stream.process.mutex.Lock()
+	defer stream.process.mutex.Unlock()
+
+	return stream.stream.WriteString(s)
+}
+
+// notify wakes up the calls waiting for output. The caller must hold the mutex.
+func (process *Process) notify() {
+	close(process.changed)
+	process.changed = make(chan struct{})
+}
+
+// Stdout returns the stdout of the process so far.
+func (process *Process) Stdout() string {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	return process.output.Stdout()
+}
+
+// Stderr returns the stderr of the process so far.
+func (process *Process) Stderr() string {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	return process.output.Stderr()
+}
+
+// Combined returns the stdout and stderr of the process so far, interleaved in the order the lines came in.
+func (process *Process) Combined() string {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	return process.output.Combined()
+}
+
+// Exited returns true if the process has exited.
+func (process *Process) Exited() bool {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	return process.exited
+}
+
+// WaitForOutput waits for the output of the process (stdout or stderr) to match the given regular expression, and
+// returns the text that matched. The whole output since the process started is searched, including lines that are not
+// complete yet. Once the output has been searched, only the output that comes in after that is searched, along with
+// the last few KiB (waitForOutputOverlap) before it, starting from the beginning of a line, so a match may span several
+// writes of the process as long as it is not longer than that. If the output does not match within the timeout, or the
+// process exits before it matches, fail the test.
+func (process *Process) WaitForOutput(t testing.TestingT, pattern string, timeout time.Duration) string {
+	match, err := process.WaitForOutputE(t, pattern, timeout)
+	require.NoError(t, err)
+	return match
+}
+
+// WaitForOutputE waits for the output of the process (stdout or stderr) to match the given regular expression, and
+// returns the text that matched. See WaitForOutput for more info. If the output does not match within the timeout, or
+// the process exits before it matches, the returned error is an ErrProcessWaitFailed.
+func (process *Process) WaitForOutputE(t testing.TestingT, pattern string, timeout time.Duration) (string, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	process.Command.Logger.Logf(t, "Waiting up to %s for the output of command %s to match %q", timeout, process.Command.Command, pattern)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	searchFrom := 0
+	for {
+		process.mutex.Lock()
+		output := process.rawOutput.String()
+		exited := process.exited
+		changed := process.changed
+		process.mutex.Unlock()
+
+		if match := regex.FindStringIndex(output[searchFrom:]); match != nil {
+			return output[searchFrom+match[0] : searchFrom+match[1]], nil
+		}
+		searchFrom = nextSearchStart(output)
+		if exited {
+			return "", process.waitFailed(fmt.Sprintf("print output matching %q", pattern), process.exitReason())
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return "", process.waitFailed(fmt.Sprintf("print output matching %q", pattern), fmt.Sprintf("timed out after %s", timeout))
+		}
+	}
+}
+
+// waitForOutputOverlap is how much of the output of a process that has already been searched by WaitForOutput is
+// searched again when more output comes in, so that matches that span several writes of the process are found.
+const waitForOutputOverlap = 4 * 1024
+
+// nextSearchStart returns where WaitForOutput continues searching once the given output has been searched: at the start
+// of the line that is waitForOutputOverlap bytes before the end of the output, so that ^ keeps matching at the start of a
+// line, or exactly there if that line is a lot longer.
+func nextSearchStart(output string) int {
+	start := len(output) - waitForOutputOverlap
+	if start <= 0 {
+		return 0
+	}
+	lineStart := strings.LastIndexByte(output[:start], '\n') + 1
+	if start-lineStart > waitForOutputOverlap {
+		return start
+	}
+	return lineStart
+}
+
+// WaitForPort waits for the process to accept TCP connections on the given port of localhost. If it does not within the
+// timeout, or the process exits before it does, fail the test.
+func (process *Process) WaitForPort(t testing.TestingT, port int, timeout time.Duration) {
+	require.NoError(t, process.WaitForPortE(t, port, timeout))
+}
+
+// WaitForPortE waits for the process to accept TCP connections on the given port of localhost. If it does not within
+// the timeout, or the process exits before it does, the returned error is an ErrProcessWaitFailed. Note that any
+// process listening on the port counts, so use a port that is not used by anything else.
+func (process *Process) WaitForPortE(t testing.TestingT, port int, timeout time.Duration) error {
+	process.Command.Logger.Logf(t, "Waiting up to %s for command %s to open port %d", timeout, process.Command.Command, port)
+	address := net.JoinHostPort("localhost", fmt.Sprintf("%d", port))
+	condition := fmt.Sprintf("open port %d", port)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if process.Exited() {
+			return process.waitFailed(condition, process.exitReason())
+		}
+		if time.Now().After(deadline) {
+			return process.waitFailed(condition, fmt.Sprintf("timed out after %s (last error: %v)", timeout, err))
+		}
+
+		select {
+		case <-process.done:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (process *Process) exitReason() string {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	if process.waitErr != nil {
+		return fmt.Sprintf("the process exited: %v", process.waitErr)
+	}
+	return "the process exited"
+}
+
+func (process *Process) waitFailed(condition string, reason string) *ErrProcessWaitFailed {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	return &ErrProcessWaitFailed{
+		Command:   process.Command.Command,
+		Condition: condition,
+		Reason:    reason,
+		Output:    process.rawOutput.String(),
+	}
+}
+
+// Signal sends the given signal to the process (but not to any child processes it spawned). If there are any errors,
+// fail the test.
+func (process *Process) Signal(t testing.TestingT, signal os.Signal) {
+	require.NoError(t, process.SignalE(t, signal))
+}
+
+// SignalE sends the given signal to the process (but not to any child processes it spawned). Note that sending signals
+// other than os.Kill is not supported on Windows.
+func (process *Process) SignalE(t testing.TestingT, signal os.Signal) error {
+	process.Command.Logger.Logf(t, "Sending signal %v to command %s", signal, process.Command.Command)
+	return process.cmd.Process.Signal(signal)
+}
+
+// Wait waits for the process to exit on its own. If it fails, fail the test.
+func (process *Process) Wait(t testing.TestingT) {
+	require.NoError(t, process.WaitE(t))
+}
+
+// WaitE waits for the process to exit on its own. Any returned error will be of type ErrWithCmdOutput, containing the
+// output streams and the underlying error. Nothing is returned for a process that was stopped with Stop.
+func (process *Process) WaitE(t testing.TestingT) error {
+	<-process.done
+
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+
+	if process.waitErr != nil && !process.stopped {
+		return &ErrWithCmdOutput{process.waitErr, process.output}
+	}
+	return nil
+}
+
+// Stop stops the process, along with any child processes it spawned, if it is still running. It is first interrupted
+// (SIGINT) so it can shut down gracefully, and then killed if it has not exited within the grace period (which
+// defaults to DefaultGracePeriod). On Windows, it is killed right away. If there are any errors, fail the test.
+func (process *Process) Stop(t testing.TestingT, gracePeriod time.Duration) {
+	require.NoError(t, process.StopE(t, gracePeriod))
+}
+
+// StopE stops the process, along with any child processes it spawned, if it is still running. See Stop for more info.
+// Calling it on a process that has exited has no effect.
+func (process *Process) StopE(t testing.TestingT, gracePeriod time.Duration) error {
+	process.mutex.Lock()
+	if process.exited {
+		process.mutex.Unlock()
+		return nil
+	}
+	process.stopped = true
+	process.mutex.Unlock()
+
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultGracePeriod
+	}
+
+	process.Command.Logger.Logf(t, "Stopping command %s, will kill it if it is still running in %s", process.Command.Command, gracePeriod)
+	if err := interruptProcessGroup(process.cmd); err != nil && !process.Exited() {
+		return err
+	}
+
+	select {
+	case <-process.done:
+		return nil
+	case <-time.After(gracePeriod):
+	}
+
+	process.Command.Logger.Logf(t, "Command %s did not exit within %s of being interrupted, killing it", process.Command.Command, gracePeriod)
+	if err := killProcessGroup(process.cmd); err != nil && !process.Exited() {
+		return err
+	}
+	<-process.done
+	return nil
+}
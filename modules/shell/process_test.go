@@ -0,0 +1,218 @@
+package shell
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+func TestStartWaitForOutputAndStop(t *testing.T) {
+	t.Parallel()
+
+	process := Start(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", `echo "starting"; sleep 0.2; printf "listening on port 8123"; sleep 60`},
+	})
+
+	assert.Equal(t, "listening on port 8123", process.WaitForOutput(t, `listening on port \d+`, 10*time.Second))
+	assert.False(t, process.Exited())
+	assert.Equal(t, "starting", process.Stdout())
+
+	start := time.Now()
+	process.Stop(t, 5*time.Second)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.True(t, process.Exited())
+	assert.Equal(t, "starting\nlistening on port 8123", process.Stdout())
+	assert.NoError(t, process.WaitE(t))
+}
+
+func TestStartWaitForOutputProcessExited(t *testing.T) {
+	t.Parallel()
+
+	process := Start(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", `echo "address already in use" >&2; exit 3`},
+		Logger:  logger.Discard,
+	})
+
+	_, err := process.WaitForOutputE(t, `listening`, time.Minute)
+	var waitErr *ErrProcessWaitFailed
+	if assert.ErrorAs(t, err, &waitErr) {
+		assert.Equal(t, "the process exited: exit status 3", waitErr.Reason)
+		assert.Equal(t, "address already in use\n", waitErr.Output)
+	}
+	assert.Equal(t, "address already in use", process.Stderr())
+
+	err = process.WaitE(t)
+	code, exitCodeErr := GetExitCodeForRunCommandError(err)
+	assert.NoError(t, exitCodeErr)
+	assert.Equal(t, 3, code)
+}
+
+func TestStartWaitForOutputTimeout(t *testing.T) {
+	t.Parallel()
+
+	process := Start(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", `echo "starting"; sleep 60`},
+		Logger:  logger.Discard,
+	})
+
+	_, err := process.WaitForOutputE(t, `listening`, 500*time.Millisecond)
+	var waitErr *ErrProcessWaitFailed
+	if assert.ErrorAs(t, err, &waitErr) {
+		assert.Equal(t, "timed out after 500ms", waitErr.Reason)
+		assert.Equal(t, "starting\n", waitErr.Output)
+	}
+	assert.False(t, process.Exited())
+}
+
+func TestWaitForOutputSearchesOnlyNewOutput(t *testing.T) {
+	t.Parallel()
+
+	process := &Process{
+		Command: Command{Command: "mock-server", Logger: logger.Discard},
+		output:  newOutput(),
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	raw := processRawOutput{process}
+	go func() {
+		for i := 0; i < 1000; i++ {
+			raw.Write([]byte(fmt.Sprintf("request %d: 200 OK\n", i)))
+		}
+		// The match spans two writes
+		raw.Write([]byte("Listening on "))
+		raw.Write([]byte("port 8080\n"))
+	}()
+
+	match := process.WaitForOutput(t, `Listening on port \d+`, 10*time.Second)
+	assert.Equal(t, "Listening on port 8080", match)
+}
+
+func TestNextSearchStart(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, nextSearchStart("short output\n"))
+
+	lines := strings.Repeat(strings.Repeat("x", 99)+"\n", 100)
+	start := nextSearchStart(lines)
+	assert.Equal(t, len(lines)-waitForOutputOverlap-4, start)
+	assert.Equal(t, byte('\n'), lines[start-1])
+
+	noNewlines := strings.Repeat("x", 3*waitForOutputOverlap)
+	assert.Equal(t, 2*waitForOutputOverlap, nextSearchStart(noNewlines))
+}
+
+func TestStartWaitForPort(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	process := Start(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", "sleep 60"},
+		Logger:  logger.Discard,
+	})
+
+	// The port is opened by the test itself after a while, as the process would
+	listeners := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		listener, _ := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+		listeners <- listener
+	}()
+
+	process.WaitForPort(t, port, 10*time.Second)
+	if listener := <-listeners; listener != nil {
+		listener.Close()
+	}
+}
+
+func TestStartWaitForPortProcessExited(t *testing.T) {
+	t.Parallel()
+
+	process := Start(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", "exit 1"},
+		Logger:  logger.Discard,
+	})
+
+	err := process.WaitForPortE(t, 1, time.Minute)
+	var waitErr *ErrProcessWaitFailed
+	if assert.ErrorAs(t, err, &waitErr) {
+		assert.Equal(t, "open port 1", waitErr.Condition)
+		assert.Equal(t, "the process exited: exit status 1", waitErr.Reason)
+	}
+}
+
+func TestStartSignal(t *testing.T) {
+	t.Parallel()
+
+	process := Start(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", `trap 'echo "interrupted"; exit 0' INT; echo "ready"; while true; do sleep 0.1; done`},
+		Logger:  logger.Discard,
+	})
+
+	process.WaitForOutput(t, "ready", 10*time.Second)
+	process.Signal(t, os.Interrupt)
+	process.WaitForOutput(t, "interrupted", 10*time.Second)
+	process.Wait(t)
+}
+
+func TestStartKilledAfterGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	process := Start(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", `trap '' INT; echo "ready"; sleep 60`},
+		Logger:  logger.Discard,
+	})
+	process.WaitForOutput(t, "ready", 10*time.Second)
+
+	start := time.Now()
+	process.Stop(t, time.Second)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.True(t, process.Exited())
+}
+
+func TestStartStoppedOnCleanup(t *testing.T) {
+	t.Parallel()
+
+	var process *Process
+	t.Run("forgets to stop", func(t *testing.T) {
+		process = Start(t, Command{
+			Command:     "bash",
+			Args:        []string{"-c", "sleep 60"},
+			GracePeriod: time.Second,
+			Logger:      logger.Discard,
+		})
+	})
+	assert.True(t, process.Exited())
+}
+
+func TestStartWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	process := Start(t, Command{
+		Command: "bash",
+		Args:    []string{"-c", "sleep 60"},
+		Timeout: 500 * time.Millisecond,
+		Logger:  logger.Discard,
+	})
+
+	err := process.WaitE(t)
+	assert.True(t, IsCommandCancelled(err))
+}
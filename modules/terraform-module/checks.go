@@ -0,0 +1,146 @@
+package terraform_module
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check is a single rule of a module contract. It returns a description of every violation of the rule in the module.
+type Check func(module *Module) []string
+
+// DefaultChecks are the checks that are run by AssertContract and RequireContract when no checks are given.
+var DefaultChecks = []Check{
+	VariablesHaveTypes,
+	VariablesHaveDescriptions,
+	NoAnyTypedVariables,
+	OutputsHaveDescriptions,
+	RequiredProvidersHaveVersions,
+}
+
+// anyType matches the any type keyword anywhere in a type constraint, e.g., in map(any).
+var anyType = regexp.MustCompile(`\bany\b`)
+
+// VariablesHaveTypes checks that every variable has a type constraint.
+func VariablesHaveTypes(module *Module) []string {
+	violations := []string{}
+	for _, name := range module.VariableNames() {
+		if variable := module.Variables[name]; variable.Type == "" {
+			violations = append(violations, fmt.Sprintf("variable %q (%s) has no type", name, variable.Pos))
+		}
+	}
+	return violations
+}
+
+// VariablesHaveDescriptions checks that every variable has a non-empty description.
+func VariablesHaveDescriptions(module *Module) []string {
+	violations := []string{}
+	for _, name := range module.VariableNames() {
+		if variable := module.Variables[name]; strings.TrimSpace(variable.Description) == "" {
+			violations = append(violations, fmt.Sprintf("variable %q (%s) has no description", name, variable.Pos))
+		}
+	}
+	return violations
+}
+
+// NoAnyTypedVariables checks that no variable uses the any type, not even nested in a collection type (e.g.,
+// map(any)).
+func NoAnyTypedVariables(module *Module) []string {
+	violations := []string{}
+	for _, name := range module.VariableNames() {
+		if variable := module.Variables[name]; anyType.MatchString(variable.Type) {
+			violations = append(violations, fmt.Sprintf("variable %q (%s) uses the any type: %s", name, variable.Pos, variable.Type))
+		}
+	}
+	return violations
+}
+
+// OutputsHaveDescriptions checks that every output has a non-empty description.
+func OutputsHaveDescriptions(module *Module) []string {
+	violations := []string{}
+	for _, name := range module.OutputNames() {
+		if output := module.Outputs[name]; strings.TrimSpace(output.Description) == "" {
+			violations = append(violations, fmt.Sprintf("output %q (%s) has no description", name, output.Pos))
+		}
+	}
+	return violations
+}
+
+// RequiredProvidersHaveVersions checks that every provider the module uses (other than the built-in terraform
+// provider) is declared in required_providers with a version constraint that pins it, i.e., that has an upper bound
+// (e.g., "~> 5.0", ">= 5.0, < 6.0", or an exact version), so that a new major version of the provider can't break the
+// module.
+func RequiredProvidersHaveVersions(module *Module) []string {
+	violations := []string{}
+	for _, name := range module.ProviderNames() {
+		provider, isRequired := module.RequiredProviders[name]
+		switch {
+		case !isRequired:
+			violations = append(violations, fmt.Sprintf("provider %q is not declared in required_providers", name))
+		case len(provider.VersionConstraints) == 0:
+			violations = append(violations, fmt.Sprintf("provider %q (%s) has no version constraint", name, provider.Pos))
+		case !hasUpperBound(provider.VersionConstraints):
+			violations = append(violations, fmt.Sprintf("provider %q (%s) has no upper bound in its version constraint %q", name, provider.Pos, strings.Join(provider.VersionConstraints, ", ")))
+		}
+	}
+	return violations
+}
+
+// hasUpperBound returns true if any of the given version constraints (each of which may be a comma separated list)
+// limits the versions from above: a pessimistic (~>), less than (<, <=), or exact (=, or no operator) constraint.
+func hasUpperBound(constraints []string) bool {
+	for _, constraint := range constraints {
+		for _, part := range strings.Split(constraint, ",") {
+			part = strings.TrimSpace(part)
+			switch {
+			case part == "":
+			case strings.HasPrefix(part, "~>"), strings.HasPrefix(part, "<"):
+				return true
+			case strings.HasPrefix(part, ">"), strings.HasPrefix(part, "!="):
+			default:
+				// An exact version, with or without the = operator
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequiredVersionIsSet checks that the module constrains the version of Terraform with required_version.
+func RequiredVersionIsSet(module *Module) []string {
+	if len(module.RequiredVersions) == 0 {
+		return []string{"the module does not set required_version in a terraform block"}
+	}
+	return []string{}
+}
+
+// ContractViolations runs the given checks (or DefaultChecks if none are given) against the module and returns all the
+// violations.
+func ContractViolations(module *Module, checks ...Check) []string {
+	if len(checks) == 0 {
+		checks = DefaultChecks
+	}
+	violations := []string{}
+	for _, check := range checks {
+		violations = append(violations, check(module)...)
+	}
+	return violations
+}
+
+// AssertContract checks that the module passes the given checks (or DefaultChecks if none are given), failing the test
+// with the list of violations if it doesn't.
+func AssertContract(t testing.TestingT, module *Module, checks ...Check) bool {
+	violations := ContractViolations(module, checks...)
+	return assert.Emptyf(t, violations, "Module %s violates its contract:\n%s", module.Dir, strings.Join(violations, "\n"))
+}
+
+// RequireContract checks that the module passes the given checks (or DefaultChecks if none are given), failing and
+// halting the test with the list of violations if it doesn't.
+func RequireContract(t testing.TestingT, module *Module, checks ...Check) {
+	violations := ContractViolations(module, checks...)
+	require.Emptyf(t, violations, "Module %s violates its contract:\n%s", module.Dir, strings.Join(violations, "\n"))
+}
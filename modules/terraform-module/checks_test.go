@@ -0,0 +1,76 @@
+package terraform_module
+
+import (
+	"testing"
+
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractViolations(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, ContractViolations(LoadModule(t, compliantModuleDir), append(DefaultChecks, RequiredVersionIsSet)...))
+
+	module := LoadModule(t, nonCompliantModuleDir)
+	testCases := []struct {
+		name     string
+		check    Check
+		expected []string
+	}{
+		{"types", VariablesHaveTypes, []string{`variable "untyped" (` + nonCompliantModuleDir + `/main.tf:19) has no type`}},
+		{"variable descriptions", VariablesHaveDescriptions, []string{`variable "settings" (` + nonCompliantModuleDir + `/main.tf:23) has no description`}},
+		{"any", NoAnyTypedVariables, []string{`variable "settings" (` + nonCompliantModuleDir + `/main.tf:23) uses the any type: map(any)`}},
+		{"output descriptions", OutputsHaveDescriptions, []string{`output "pet" (` + nonCompliantModuleDir + `/main.tf:35) has no description`}},
+		{"provider versions", RequiredProvidersHaveVersions, []string{
+			`provider "aws" is not declared in required_providers`,
+			`provider "null" (` + nonCompliantModuleDir + `/main.tf:4) has no version constraint`,
+		}},
+		{"required version", RequiredVersionIsSet, []string{"the module does not set required_version in a terraform block"}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expected, ContractViolations(module, testCase.check))
+		})
+	}
+}
+
+func TestRequiredProvidersHaveVersionsRequiresAnUpperBound(t *testing.T) {
+	t.Parallel()
+
+	module := &Module{RequiredProviders: map[string]*RequiredProvider{}}
+	for name, constraints := range map[string][]string{
+		"aws":     {"~> 5.0"},
+		"google":  {">= 4.0, < 6.0"},
+		"null":    {"3.2.1"},
+		"random":  {"= 3.5.1"},
+		"archive": {">= 2.0", "<= 2.4.0"},
+		"local":   {">= 0"},
+		"tls":     {">= 4.0, != 4.0.1"},
+	} {
+		module.RequiredProviders[name] = &RequiredProvider{Name: name, VersionConstraints: constraints, Pos: SourcePos{Filename: "main.tf", Line: 1}}
+	}
+
+	assert.Equal(t, []string{
+		`provider "local" (main.tf:1) has no upper bound in its version constraint ">= 0"`,
+		`provider "tls" (main.tf:1) has no upper bound in its version constraint ">= 4.0, != 4.0.1"`,
+	}, RequiredProvidersHaveVersions(module))
+}
+
+func TestForEachModule(t *testing.T) {
+	t.Parallel()
+
+	opts, err := test_structure.NewValidationOptions("../../test/fixtures/terraform-module-contract", nil, []string{"non-compliant"})
+	require.NoError(t, err)
+
+	loaded := []string{}
+	ForEachModule(t, opts, func(t *testing.T, module *Module) {
+		loaded = append(loaded, t.Name())
+		AssertContract(t, module)
+	})
+	assert.Equal(t, []string{"TestForEachModule/compliant", "TestForEachModule/compliant/modules/label"}, loaded)
+}
@@ -0,0 +1,17 @@
+package terraform_module
+
+import "fmt"
+
+// ModuleNotFound is returned when a directory does not contain any Terraform source files.
+type ModuleNotFound string
+
+func (dir ModuleNotFound) Error() string {
+	return fmt.Sprintf("no Terraform source files (*.tf or *.tf.json) found in %s", string(dir))
+}
+
+// UnsupportedFileType is returned when the validation options target Terragrunt instead of Terraform files.
+type UnsupportedFileType string
+
+func (fileType UnsupportedFileType) Error() string {
+	return fmt.Sprintf("only Terraform modules (*.tf) can be loaded, but the file type is %s", string(fileType))
+}
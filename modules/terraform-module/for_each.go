@@ -0,0 +1,50 @@
+package terraform_module
+
+import (
+	"path/filepath"
+	"sort"
+	go_test "testing"
+
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+// ForEachModule finds all the folders in opts.RootDir that contain .tf files (subject to opts.IncludeDirs and
+// opts.ExcludeDirs, see test_structure.NewValidationOptions) and calls fn with the module loaded from each of them, in a
+// subtest named after the path of the folder relative to the RootDir. Note that go_test is an alias to Golang's native
+// testing package, as Terratest's testing.T does not implement Run.
+//
+// Example:
+//
+//	opts, err := test_structure.NewValidationOptions("../", nil, []string{"test"})
+//	require.NoError(t, err)
+//	terraform_module.ForEachModule(t, opts, func(t *testing.T, module *terraform_module.Module) {
+//		terraform_module.AssertContract(t, module)
+//	})
+func ForEachModule(t *go_test.T, opts *test_structure.ValidationOptions, fn func(t *go_test.T, module *Module)) {
+	if opts.FileType != test_structure.TF {
+		require.NoError(t, UnsupportedFileType(opts.FileType))
+	}
+
+	dirs, err := test_structure.FindTerraformModulePathsInRootE(opts)
+	require.NoError(t, err)
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		dir := dir
+		name, err := filepath.Rel(opts.RootDir, dir)
+		require.NoError(t, err)
+		t.Run(filepath.ToSlash(name), func(t *go_test.T) {
+			fn(t, LoadModule(t, dir))
+		})
+	}
+}
+
+// AssertContractForAllModules checks that every module found with the given validation options passes the given checks
+// (or DefaultChecks if none are given), reporting the violations of each module in a separate subtest. See
+// ForEachModule for how the modules are found.
+func AssertContractForAllModules(t *go_test.T, opts *test_structure.ValidationOptions, checks ...Check) {
+	ForEachModule(t, opts, func(t *go_test.T, module *Module) {
+		AssertContract(t, module, checks...)
+	})
+}
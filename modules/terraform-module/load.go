@@ -0,0 +1,383 @@
+package terraform_module
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+var moduleSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "provider", LabelNames: []string{"name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+	},
+}
+
+var terraformBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "required_version"}},
+	Blocks:     []hcl.BlockHeaderSchema{{Type: "required_providers"}},
+}
+
+var variableBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "type"}, {Name: "description"}, {Name: "default"}, {Name: "sensitive"}},
+	Blocks:     []hcl.BlockHeaderSchema{{Type: "validation"}},
+}
+
+var outputBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "description"}, {Name: "sensitive"}},
+}
+
+var providerBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "alias"}},
+}
+
+var moduleBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "source"}, {Name: "version"}},
+}
+
+var resourceBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "provider"}},
+}
+
+// LoadModule parses the .tf and .tf.json files in the given directory (but not in its subdirectories) into the
+// interface of a single module. Terraform is not run, so expressions that reference other values (e.g., a description
+// built with format) can't be evaluated and are left empty. This will fail the test if there is an error.
+func LoadModule(t testing.TestingT, dir string) *Module {
+	module, err := LoadModuleE(dir)
+	require.NoError(t, err)
+	return module
+}
+
+// LoadModuleE parses the .tf and .tf.json files in the given directory (but not in its subdirectories) into the
+// interface of a single module. Terraform is not run, so expressions that reference other values (e.g., a description
+// built with format) can't be evaluated and are left empty.
+func LoadModuleE(dir string) (*Module, error) {
+	paths, err := moduleSourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, ModuleNotFound(dir)
+	}
+
+	module := &Module{
+		Dir:               dir,
+		Variables:         map[string]*Variable{},
+		Outputs:           map[string]*Output{},
+		RequiredVersions:  []string{},
+		RequiredProviders: map[string]*RequiredProvider{},
+		ProviderConfigs:   map[string]*ProviderConfig{},
+		ModuleCalls:       map[string]*ModuleCall{},
+		ManagedResources:  map[string]*Resource{},
+		DataResources:     map[string]*Resource{},
+	}
+
+	parser := hclparse.NewParser()
+	for _, path := range paths {
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if strings.HasSuffix(path, ".json") {
+			file, diags = parser.ParseJSONFile(path)
+		} else {
+			file, diags = parser.ParseHCLFile(path)
+		}
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		if err := loadFile(module, file); err != nil {
+			return nil, err
+		}
+	}
+	return module, nil
+}
+
+// moduleSourceFiles returns the paths of the Terraform source files of the module in the given directory, sorted.
+// Override files are not supported and are skipped.
+func moduleSourceFiles(dir string) ([]string, error) {
+	tfFiles, err := files.FindTerraformSourceFilesInDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// FindTerraformSourceFilesInDir only finds .tf files
+	jsonFiles, err := filepath.Glob(filepath.Join(dir, "*.tf.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, path := range append(tfFiles, jsonFiles...) {
+		// FindTerraformSourceFilesInDir also finds the files of nested modules, which are separate modules
+		if filepath.Clean(filepath.Dir(path)) != filepath.Clean(dir) {
+			continue
+		}
+		base := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".json"), ".tf")
+		if base == "override" || strings.HasSuffix(base, "_override") {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func loadFile(module *Module, file *hcl.File) error {
+	content, _, diags := file.Body.PartialContent(moduleSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	for _, block := range content.Blocks {
+		var err error
+		switch block.Type {
+		case "terraform":
+			err = loadTerraformBlock(module, block)
+		case "variable":
+			err = loadVariableBlock(module, file, block)
+		case "output":
+			err = loadOutputBlock(module, block)
+		case "provider":
+			err = loadProviderBlock(module, block)
+		case "module":
+			err = loadModuleBlock(module, block)
+		case "resource", "data":
+			err = loadResourceBlock(module, block)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadTerraformBlock(module *Module, block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(terraformBlockSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+	if attr, hasAttr := content.Attributes["required_version"]; hasAttr {
+		if version := stringValue(attr.Expr); version != "" {
+			module.RequiredVersions = append(module.RequiredVersions, version)
+		}
+	}
+
+	for _, requiredProvidersBlock := range content.Blocks {
+		attrs, diags := requiredProvidersBlock.Body.JustAttributes()
+		if diags.HasErrors() {
+			return diags
+		}
+		for name, attr := range attrs {
+			provider := &RequiredProvider{Name: name, VersionConstraints: []string{}, Pos: sourcePos(attr.Range)}
+			if version := stringValue(attr.Expr); version != "" {
+				// The legacy syntax, which only sets a version constraint (e.g., aws = "~> 5.0")
+				provider.VersionConstraints = append(provider.VersionConstraints, version)
+			} else {
+				pairs, diags := hcl.ExprMap(attr.Expr)
+				if diags.HasErrors() {
+					return diags
+				}
+				for _, pair := range pairs {
+					switch stringValue(pair.Key) {
+					case "source":
+						provider.Source = stringValue(pair.Value)
+					case "version":
+						if version := stringValue(pair.Value); version != "" {
+							provider.VersionConstraints = append(provider.VersionConstraints, version)
+						}
+					}
+				}
+			}
+			if existing, isRequired := module.RequiredProviders[name]; isRequired {
+				// Required providers can be declared in multiple terraform blocks, in which case the constraints add up.
+				provider.VersionConstraints = append(existing.VersionConstraints, provider.VersionConstraints...)
+				if provider.Source == "" {
+					provider.Source = existing.Source
+				}
+				provider.Pos = existing.Pos
+			}
+			module.RequiredProviders[name] = provider
+		}
+	}
+	return nil
+}
+
+func loadVariableBlock(module *Module, file *hcl.File, block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(variableBlockSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	variable := &Variable{Name: block.Labels[0], Validations: len(content.Blocks), Pos: sourcePos(block.DefRange)}
+	if attr, hasAttr := content.Attributes["type"]; hasAttr {
+		variable.Type = typeConstraint(file, attr.Expr)
+	}
+	if attr, hasAttr := content.Attributes["description"]; hasAttr {
+		variable.Description = stringValue(attr.Expr)
+	}
+	if attr, hasAttr := content.Attributes["sensitive"]; hasAttr {
+		variable.Sensitive = boolValue(attr.Expr)
+	}
+	if attr, hasAttr := content.Attributes["default"]; hasAttr {
+		variable.HasDefault = true
+		value, err := goValue(attr.Expr)
+		if err != nil {
+			return err
+		}
+		variable.Default = value
+	}
+	module.Variables[variable.Name] = variable
+	return nil
+}
+
+func loadOutputBlock(module *Module, block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(outputBlockSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	output := &Output{Name: block.Labels[0], Pos: sourcePos(block.DefRange)}
+	if attr, hasAttr := content.Attributes["description"]; hasAttr {
+		output.Description = stringValue(attr.Expr)
+	}
+	if attr, hasAttr := content.Attributes["sensitive"]; hasAttr {
+		output.Sensitive = boolValue(attr.Expr)
+	}
+	module.Outputs[output.Name] = output
+	return nil
+}
+
+func loadProviderBlock(module *Module, block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(providerBlockSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	config := &ProviderConfig{Name: block.Labels[0], Pos: sourcePos(block.DefRange)}
+	key := config.Name
+	if attr, hasAttr := content.Attributes["alias"]; hasAttr {
+		config.Alias = stringValue(attr.Expr)
+		key += "." + config.Alias
+	}
+	module.ProviderConfigs[key] = config
+	return nil
+}
+
+func loadModuleBlock(module *Module, block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(moduleBlockSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	call := &ModuleCall{Name: block.Labels[0], Pos: sourcePos(block.DefRange)}
+	if attr, hasAttr := content.Attributes["source"]; hasAttr {
+		call.Source = stringValue(attr.Expr)
+	}
+	if attr, hasAttr := content.Attributes["version"]; hasAttr {
+		call.Version = stringValue(attr.Expr)
+	}
+	module.ModuleCalls[call.Name] = call
+	return nil
+}
+
+func loadResourceBlock(module *Module, block *hcl.Block) error {
+	content, _, diags := block.Body.PartialContent(resourceBlockSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	resource := &Resource{Mode: ManagedResourceMode, Type: block.Labels[0], Name: block.Labels[1], Pos: sourcePos(block.DefRange)}
+	if block.Type == "data" {
+		resource.Mode = DataResourceMode
+	}
+	if attr, hasAttr := content.Attributes["provider"]; hasAttr {
+		traversal, diags := hcl.AbsTraversalForExpr(attr.Expr)
+		if diags.HasErrors() {
+			return diags
+		}
+		resource.Provider = traversalString(traversal)
+	}
+
+	if resource.Mode == DataResourceMode {
+		module.DataResources[resource.Address()] = resource
+	} else {
+		module.ManagedResources[resource.Address()] = resource
+	}
+	return nil
+}
+
+// typeConstraint returns the type constraint of a variable as written in the source. In JSON files, type constraints
+// are written as strings.
+func typeConstraint(file *hcl.File, expr hcl.Expression) string {
+	if value := stringValue(expr); value != "" {
+		return value
+	}
+	return strings.TrimSpace(string(expr.Range().SliceBytes(file.Bytes)))
+}
+
+// stringValue returns the value of a static string expression, or an empty string if the expression is not one.
+func stringValue(expr hcl.Expression) string {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+		return ""
+	}
+	return value.AsString()
+}
+
+// boolValue returns the value of a static bool expression, or false if the expression is not one.
+func boolValue(expr hcl.Expression) bool {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || value.IsNull() || !value.IsKnown() || value.Type() != cty.Bool {
+		return false
+	}
+	return value.True()
+}
+
+// goValue converts the value of a static expression to the corresponding Go type, by way of JSON.
+func goValue(expr hcl.Expression) (interface{}, error) {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	if value.IsNull() {
+		return nil, nil
+	}
+	jsonBytes, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(jsonBytes, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func traversalString(traversal hcl.Traversal) string {
+	parts := []string{}
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			parts = append(parts, step.Name)
+		case hcl.TraverseAttr:
+			parts = append(parts, step.Name)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func sourcePos(rng hcl.Range) SourcePos {
+	return SourcePos{Filename: rng.Filename, Line: rng.Start.Line}
+}
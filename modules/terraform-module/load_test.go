@@ -0,0 +1,89 @@
+package terraform_module
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const compliantModuleDir = "../../test/fixtures/terraform-module-contract/compliant"
+const nonCompliantModuleDir = "../../test/fixtures/terraform-module-contract/non-compliant"
+
+func TestLoadModule(t *testing.T) {
+	t.Parallel()
+
+	module := LoadModule(t, compliantModuleDir)
+
+	assert.Equal(t, []string{"name", "password", "tags"}, module.VariableNames())
+	name := module.Variables["name"]
+	assert.Equal(t, "string", name.Type)
+	assert.Equal(t, "The name of the bucket.", name.Description)
+	assert.True(t, name.Required())
+	assert.Equal(t, 1, name.Validations)
+	assert.Equal(t, SourcePos{Filename: filepath.Join(compliantModuleDir, "variables.tf"), Line: 1}, name.Pos)
+
+	tags := module.Variables["tags"]
+	assert.Equal(t, "map(string)", tags.Type)
+	assert.Equal(t, "The tags to apply to the bucket.\n", tags.Description)
+	assert.False(t, tags.Required())
+	assert.Equal(t, map[string]interface{}{"Team": "platform"}, tags.Default)
+
+	password := module.Variables["password"]
+	assert.False(t, password.Required())
+	assert.Nil(t, password.Default)
+	assert.True(t, password.Sensitive)
+
+	assert.Equal(t, []string{"bucket_arn", "password"}, module.OutputNames())
+	assert.Equal(t, "The ARN of the bucket.", module.Outputs["bucket_arn"].Description)
+	assert.True(t, module.Outputs["password"].Sensitive)
+
+	assert.Equal(t, []string{">= 1.3.0"}, module.RequiredVersions)
+	require.Contains(t, module.RequiredProviders, "aws")
+	assert.Equal(t, "hashicorp/aws", module.RequiredProviders["aws"].Source)
+	assert.Equal(t, []string{"~> 5.0"}, module.RequiredProviders["aws"].VersionConstraints)
+	assert.Empty(t, module.ProviderConfigs)
+
+	require.Contains(t, module.ModuleCalls, "label")
+	assert.Equal(t, "./modules/label", module.ModuleCalls["label"].Source)
+
+	assert.Len(t, module.ManagedResources, 3)
+	require.Contains(t, module.ManagedResources, "aws_s3_bucket.replica")
+	assert.Equal(t, "aws.replica", module.ManagedResources["aws_s3_bucket.replica"].Provider)
+	assert.Equal(t, "aws", module.ManagedResources["aws_s3_bucket.replica"].ProviderName())
+	require.Contains(t, module.DataResources, "data.aws_caller_identity.current")
+	assert.Equal(t, DataResourceMode, module.DataResources["data.aws_caller_identity.current"].Mode)
+
+	assert.Equal(t, []string{"aws"}, module.ProviderNames())
+}
+
+func TestLoadModuleWithJSONAndLegacySyntax(t *testing.T) {
+	t.Parallel()
+
+	module := LoadModule(t, nonCompliantModuleDir)
+
+	assert.Equal(t, []string{"from_json", "settings", "untyped"}, module.VariableNames())
+	assert.Equal(t, "list(string)", module.Variables["from_json"].Type)
+	assert.Equal(t, []interface{}{"a", "b"}, module.Variables["from_json"].Default)
+	assert.Equal(t, "map(any)", module.Variables["settings"].Type)
+	assert.Equal(t, "", module.Variables["untyped"].Type)
+	assert.Equal(t, "An output declared in JSON.", module.Outputs["from_json"].Description)
+
+	assert.Empty(t, module.RequiredVersions)
+	assert.Equal(t, []string{"~> 3.0"}, module.RequiredProviders["random"].VersionConstraints)
+	assert.Equal(t, "hashicorp/null", module.RequiredProviders["null"].Source)
+	assert.Empty(t, module.RequiredProviders["null"].VersionConstraints)
+
+	assert.Contains(t, module.ProviderConfigs, "aws")
+	require.Contains(t, module.ProviderConfigs, "aws.west")
+	assert.Equal(t, "west", module.ProviderConfigs["aws.west"].Alias)
+	assert.Equal(t, []string{"aws", "null", "random"}, module.ProviderNames())
+}
+
+func TestLoadModuleWithoutSourceFiles(t *testing.T) {
+	t.Parallel()
+
+	_, err := LoadModuleE(t.TempDir())
+	assert.IsType(t, ModuleNotFound(""), err)
+}
@@ -0,0 +1,178 @@
+// Package terraform_module allows to inspect the interface of a Terraform module (its variables, outputs, providers,
+// module calls, and resources) from its HCL source, without running Terraform, and to check that it follows the
+// conventions of a module contract (e.g., every variable has a type and description).
+package terraform_module
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Module is the interface of a single Terraform module, as declared in the .tf and .tf.json files of its directory.
+type Module struct {
+	// The directory the module was loaded from.
+	Dir string
+
+	Variables map[string]*Variable
+	Outputs   map[string]*Output
+
+	// The version constraints of Terraform itself, from the required_version attributes of the terraform blocks.
+	RequiredVersions []string
+	// The providers the module requires, keyed by their local name.
+	RequiredProviders map[string]*RequiredProvider
+	// The provider configurations in the module, keyed by their local name, followed by .ALIAS for aliased ones.
+	ProviderConfigs map[string]*ProviderConfig
+
+	// The calls to other modules, keyed by the name of the module block.
+	ModuleCalls map[string]*ModuleCall
+	// The managed resources, keyed by TYPE.NAME.
+	ManagedResources map[string]*Resource
+	// The data resources, keyed by data.TYPE.NAME.
+	DataResources map[string]*Resource
+}
+
+// SourcePos is the position of a block in the source of a module.
+type SourcePos struct {
+	Filename string
+	Line     int
+}
+
+func (pos SourcePos) String() string {
+	return fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+}
+
+// Variable is an input variable declared with a variable block.
+type Variable struct {
+	Name string
+	// The type constraint as written in the source (e.g., "list(string)"), or empty if the variable has no type.
+	Type        string
+	Description string
+	// Whether the variable has a default value, i.e., is optional.
+	HasDefault bool
+	// The default value, converted to the corresponding Go type (e.g., map[string]interface{} for an object).
+	Default     interface{}
+	Sensitive   bool
+	Validations int // The number of validation blocks
+	Pos         SourcePos
+}
+
+// Required returns true if a value must be set for this variable when calling the module.
+func (variable *Variable) Required() bool {
+	return !variable.HasDefault
+}
+
+// Output is an output value declared with an output block.
+type Output struct {
+	Name        string
+	Description string
+	Sensitive   bool
+	Pos         SourcePos
+}
+
+// RequiredProvider is a provider declared in the required_providers block.
+type RequiredProvider struct {
+	Name string
+	// The source address (e.g., hashicorp/aws), or empty if it is implied from the name.
+	Source             string
+	VersionConstraints []string
+	Pos                SourcePos
+}
+
+// ProviderConfig is a provider block.
+type ProviderConfig struct {
+	Name  string
+	Alias string
+	Pos   SourcePos
+}
+
+// ModuleCall is a module block.
+type ModuleCall struct {
+	Name    string
+	Source  string
+	Version string
+	Pos     SourcePos
+}
+
+// ResourceMode tells managed resources (resource blocks) and data resources (data blocks) apart.
+type ResourceMode string
+
+const (
+	ManagedResourceMode ResourceMode = "managed"
+	DataResourceMode    ResourceMode = "data"
+)
+
+// Resource is a resource or data block.
+type Resource struct {
+	Mode ResourceMode
+	Type string
+	Name string
+	// The provider configuration set with the provider meta-argument (e.g., aws.east), or empty if it is not set.
+	Provider string
+	Pos      SourcePos
+}
+
+// Address returns the address of the resource in the module (e.g., aws_instance.web or data.aws_ami.ubuntu).
+func (resource *Resource) Address() string {
+	if resource.Mode == DataResourceMode {
+		return fmt.Sprintf("data.%s.%s", resource.Type, resource.Name)
+	}
+	return fmt.Sprintf("%s.%s", resource.Type, resource.Name)
+}
+
+// ProviderName returns the local name of the provider the resource belongs to: the one set with the provider
+// meta-argument, or the one implied by the resource type (e.g., aws for aws_instance).
+func (resource *Resource) ProviderName() string {
+	if resource.Provider != "" {
+		return strings.SplitN(resource.Provider, ".", 2)[0]
+	}
+	return strings.SplitN(resource.Type, "_", 2)[0]
+}
+
+// VariableNames returns the names of all the variables in the module, sorted.
+func (module *Module) VariableNames() []string {
+	names := make([]string, 0, len(module.Variables))
+	for name := range module.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OutputNames returns the names of all the outputs in the module, sorted.
+func (module *Module) OutputNames() []string {
+	names := make([]string, 0, len(module.Outputs))
+	for name := range module.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProviderNames returns the local names of all the providers the module uses, either through required_providers,
+// provider blocks, or resources, sorted. The built-in terraform provider is not included.
+func (module *Module) ProviderNames() []string {
+	set := map[string]bool{}
+	for name := range module.RequiredProviders {
+		set[name] = true
+	}
+	for _, config := range module.ProviderConfigs {
+		set[config.Name] = true
+	}
+	for _, resources := range []map[string]*Resource{module.ManagedResources, module.DataResources} {
+		for _, resource := range resources {
+			set[resource.ProviderName()] = true
+		}
+	}
+	delete(set, builtInProvider)
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// builtInProvider is the provider that is built into Terraform and therefore never has to be required.
+const builtInProvider = "terraform"
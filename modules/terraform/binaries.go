@@ -0,0 +1,344 @@
+package terraform
+
+import (
+	"archive/zip"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	gotesting "testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+)
+
+// BinaryCacheDirEnvVar is the env var with the directory of the binary cache that ForEachVersion uses.
+const BinaryCacheDirEnvVar = "TERRATEST_BINARY_CACHE_DIR"
+
+// The products whose binaries the binary cache holds.
+const (
+	TerraformProduct = "terraform"
+	OpenTofuProduct  = "tofu"
+)
+
+// BinaryCache is a local directory of Terraform and OpenTofu releases, which allows to test modules against specific
+// versions of either. The releases are stored exactly as they are published, i.e., the zip file for the current
+// platform along with the SHA256SUMS file of the release:
+//
+//	CACHE_DIR/terraform/1.5.7/terraform_1.5.7_linux_amd64.zip
+//	CACHE_DIR/terraform/1.5.7/terraform_1.5.7_SHA256SUMS
+//	CACHE_DIR/tofu/1.6.2/tofu_1.6.2_linux_amd64.zip
+//	CACHE_DIR/tofu/1.6.2/tofu_1.6.2_SHA256SUMS
+//
+// The checksum of a zip file is verified against the SHA256SUMS file before its binary is extracted next to it and used.
+// Note that the signatures of the SHA256SUMS files are not verified, so only put releases in the cache from a source
+// you trust.
+type BinaryCache struct {
+	// The directory of the cache.
+	Dir string
+
+	mutex    sync.Mutex
+	verified map[string]bool
+}
+
+// Binary is a release of Terraform or OpenTofu in a binary cache.
+type Binary struct {
+	// The product, i.e., terraform or tofu.
+	Product string
+	Version *version.Version
+	// The path of the binary, to use as the TerraformBinary of the options.
+	Path string
+}
+
+// Name returns the name of the release (e.g., terraform-1.5.7).
+func (binary *Binary) Name() string {
+	return fmt.Sprintf("%s-%s", binary.Product, binary.Version)
+}
+
+// NewBinaryCache returns the binary cache in the given directory.
+func NewBinaryCache(dir string) *BinaryCache {
+	return &BinaryCache{Dir: dir, verified: map[string]bool{}}
+}
+
+// DefaultBinaryCache returns the binary cache in the directory set in the TERRATEST_BINARY_CACHE_DIR env var. This will
+// fail the test if the env var is not set.
+func DefaultBinaryCache(t testing.TestingT) *BinaryCache {
+	cache, err := DefaultBinaryCacheE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+// DefaultBinaryCacheE returns the binary cache in the directory set in the TERRATEST_BINARY_CACHE_DIR env var.
+func DefaultBinaryCacheE() (*BinaryCache, error) {
+	dir := os.Getenv(BinaryCacheDirEnvVar)
+	if dir == "" {
+		return nil, fmt.Errorf("set the %s env var to the directory of the Terraform and OpenTofu releases to test with", BinaryCacheDirEnvVar)
+	}
+	return NewBinaryCache(dir), nil
+}
+
+// Versions returns the versions of the given product (terraform or tofu) in the cache, sorted from oldest to newest.
+func (cache *BinaryCache) Versions(product string) ([]*version.Version, error) {
+	entries, err := os.ReadDir(filepath.Join(cache.Dir, product))
+	if os.IsNotExist(err) {
+		return []*version.Version{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []*version.Version{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if v, err := version.NewVersion(entry.Name()); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Sort(version.Collection(versions))
+	return versions, nil
+}
+
+// Binary returns the path of the binary of the given version of the given product (terraform or tofu), after verifying
+// its checksum. This will fail the test if there is an error.
+func (cache *BinaryCache) Binary(t testing.TestingT, product string, binaryVersion string) string {
+	path, err := cache.BinaryE(product, binaryVersion)
+	require.NoError(t, err)
+	return path
+}
+
+// BinaryE returns the path of the binary of the given version of the given product (terraform or tofu). The first time
+// a version is used, the checksum of its zip file is verified against its SHA256SUMS file, and the binary is extracted.
+func (cache *BinaryCache) BinaryE(product string, binaryVersion string) (string, error) {
+	v, err := version.NewVersion(binaryVersion)
+	if err != nil {
+		return "", err
+	}
+	versionDir := filepath.Join(cache.Dir, product, v.Original())
+	if _, err := os.Stat(versionDir); err != nil {
+		return "", BinaryVersionNotFound{Product: product, Version: binaryVersion, CacheDir: cache.Dir}
+	}
+
+	binaryName := product
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	binaryPath := filepath.Join(versionDir, binaryName)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.verified[binaryPath] {
+		return binaryPath, nil
+	}
+
+	zipName := fmt.Sprintf("%s_%s_%s_%s.zip", product, v.Original(), runtime.GOOS, runtime.GOARCH)
+	zipPath := filepath.Join(versionDir, zipName)
+	if err := verifyBinaryChecksum(zipPath, filepath.Join(versionDir, fmt.Sprintf("%s_%s_SHA256SUMS", product, v.Original()))); err != nil {
+		return "", err
+	}
+	if err := extractBinary(zipPath, binaryName, binaryPath); err != nil {
+		return "", err
+	}
+
+	if cache.verified == nil {
+		cache.verified = map[string]bool{}
+	}
+	cache.verified[binaryPath] = true
+	return binaryPath, nil
+}
+
+// Resolve returns the releases in the cache that match any of the given constraints, sorted by product and then from
+// oldest to newest. Each constraint is the product followed by version constraints in the same syntax as the
+// required_version of Terraform and the version-checker module (e.g., "terraform >= 1.5.0, < 1.8.0" or "tofu ~> 1.6.0").
+// Returns an error if no release matches one of the constraints, so that tests don't silently test nothing.
+func (cache *BinaryCache) Resolve(constraints ...string) ([]*Binary, error) {
+	matches := map[string]bool{}
+	out := []*Binary{}
+	for _, constraint := range constraints {
+		product, versionConstraints, err := parseBinaryConstraint(constraint)
+		if err != nil {
+			return nil, err
+		}
+		versions, err := cache.Versions(product)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for _, v := range versions {
+			if !versionConstraints.Check(v) {
+				continue
+			}
+			found = true
+			binary := &Binary{Product: product, Version: v}
+			if matches[binary.Name()] {
+				continue
+			}
+			if binary.Path, err = cache.BinaryE(product, v.Original()); err != nil {
+				return nil, err
+			}
+			matches[binary.Name()] = true
+			out = append(out, binary)
+		}
+		if !found {
+			return nil, NoBinaryVersionMatches{Constraint: constraint, CacheDir: cache.Dir}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Product != out[j].Product {
+			return out[i].Product < out[j].Product
+		}
+		return out[i].Version.LessThan(out[j].Version)
+	})
+	return out, nil
+}
+
+// parseBinaryConstraint splits a constraint such as "terraform >= 1.5.0, < 1.8.0" into the product and the version
+// constraints.
+func parseBinaryConstraint(constraint string) (string, version.Constraints, error) {
+	parts := strings.SplitN(strings.TrimSpace(constraint), " ", 2)
+	product := parts[0]
+	if product != TerraformProduct && product != OpenTofuProduct {
+		return "", nil, fmt.Errorf("invalid binary constraint '%s': it must start with %s or %s", constraint, TerraformProduct, OpenTofuProduct)
+	}
+	if len(parts) == 1 || strings.TrimSpace(parts[1]) == "" {
+		return "", nil, fmt.Errorf("invalid binary constraint '%s': missing version constraints", constraint)
+	}
+	versionConstraints, err := version.NewConstraint(parts[1])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid binary constraint '%s': %w", constraint, err)
+	}
+	return product, versionConstraints, nil
+}
+
+// verifyBinaryChecksum checks that the SHA-256 checksum of the given file matches the one in the given SHA256SUMS file.
+func verifyBinaryChecksum(path string, sumsPath string) error {
+	expected, err := readBinaryChecksum(sumsPath, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return BinaryChecksumMismatch{Path: path, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// readBinaryChecksum returns the checksum of the given file name in the given SHA256SUMS file, whose lines are in the
+// format of the sha256sum command: CHECKSUM  FILENAME.
+func readBinaryChecksum(sumsPath string, name string) (string, error) {
+	sums, err := os.Open(sumsPath)
+	if err != nil {
+		return "", err
+	}
+	defer sums.Close()
+
+	scanner := bufio.NewScanner(sums)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", name, sumsPath)
+}
+
+// extractBinary extracts the file with the given name from the given zip file to binaryPath. The file is written to a
+// temp file first and then renamed, so that other test binaries never see a partial binary.
+func extractBinary(zipPath string, name string, binaryPath string) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+		in, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.CreateTemp(filepath.Dir(binaryPath), name+".tmp-")
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(out.Name(), 0755)
+		}
+		if err == nil {
+			err = os.Rename(out.Name(), binaryPath)
+		}
+		if err != nil {
+			os.Remove(out.Name())
+		}
+		return err
+	}
+	return fmt.Errorf("%s does not contain %s", zipPath, name)
+}
+
+// ForEachVersion runs the given function as a subtest of t for each release of Terraform or OpenTofu in the default
+// binary cache (see DefaultBinaryCache) that matches any of the given constraints (see BinaryCache.Resolve), with
+// options whose TerraformBinary is the binary of that release. The subtests are named after the releases (e.g.,
+// terraform-1.5.7) and run one after the other, unless the function calls t.Parallel(), in which case make sure each
+// subtest uses its own copy of the module folder (e.g., with test_structure.CopyTerraformFolderToTemp). This will fail
+// the test if no release matches one of the constraints.
+//
+// Example:
+//
+//	terraform.ForEachVersion(t, []string{"terraform >= 1.5.0, < 1.8.0", "tofu ~> 1.6.0"}, func(t *testing.T, options *terraform.Options) {
+//		options.TerraformDir = "../examples/terraform-hello-world-example"
+//		defer terraform.Destroy(t, options)
+//		terraform.InitAndApply(t, options)
+//	})
+func ForEachVersion(t *gotesting.T, constraints []string, fn func(t *gotesting.T, options *Options)) {
+	ForEachVersionInCache(t, DefaultBinaryCache(t), constraints, fn)
+}
+
+// ForEachVersionInCache is like ForEachVersion, but uses the given binary cache.
+func ForEachVersionInCache(t *gotesting.T, cache *BinaryCache, constraints []string, fn func(t *gotesting.T, options *Options)) {
+	binaries, err := cache.Resolve(constraints...)
+	require.NoError(t, err)
+
+	for _, binary := range binaries {
+		binary := binary
+		t.Run(binary.Name(), func(t *gotesting.T) {
+			logger.Logf(t, "Running with %s from %s", binary.Name(), binary.Path)
+			fn(t, &Options{TerraformBinary: binary.Path})
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package terraform
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addFakeRelease adds a release of the given product and version to the binary cache in cacheDir, whose binary is a
+// shell script that prints the version.
+func addFakeRelease(t *testing.T, cacheDir string, product string, version string) {
+	versionDir := filepath.Join(cacheDir, product, version)
+	require.NoError(t, os.MkdirAll(versionDir, 0755))
+
+	zipName := fmt.Sprintf("%s_%s_%s_%s.zip", product, version, runtime.GOOS, runtime.GOARCH)
+	zipPath := filepath.Join(versionDir, zipName)
+	zipFile, err := os.Create(zipPath)
+	require.NoError(t, err)
+	writer := zip.NewWriter(zipFile)
+	binaryName := product
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	entry, err := writer.Create(binaryName)
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(entry, "#!/bin/sh\necho %s v%s\n", product, version)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, zipFile.Close())
+
+	contents, err := os.ReadFile(zipPath)
+	require.NoError(t, err)
+	sum := sha256.Sum256(contents)
+	sums := fmt.Sprintf("%s  %s_%s_other_arch.zip\n%s  %s\n", hex.EncodeToString(sum[:]), product, version, hex.EncodeToString(sum[:]), zipName)
+	require.NoError(t, os.WriteFile(filepath.Join(versionDir, fmt.Sprintf("%s_%s_SHA256SUMS", product, version)), []byte(sums), 0644))
+}
+
+func TestBinaryCacheResolve(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+	for _, release := range []string{"terraform 1.5.7", "terraform 1.6.6", "terraform 1.7.5", "terraform 1.8.0", "tofu 1.6.2", "tofu 1.7.1"} {
+		var product, version string
+		fmt.Sscan(release, &product, &version)
+		addFakeRelease(t, cacheDir, product, version)
+	}
+	cache := NewBinaryCache(cacheDir)
+
+	binaries, err := cache.Resolve("tofu ~> 1.6.0", "terraform >= 1.5.0, < 1.8.0", "terraform ~> 1.7.0")
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, binary := range binaries {
+		names = append(names, binary.Name())
+		assert.FileExists(t, binary.Path)
+	}
+	assert.Equal(t, []string{"terraform-1.5.7", "terraform-1.6.6", "terraform-1.7.5", "tofu-1.6.2"}, names)
+	assert.Equal(t, filepath.Join(cacheDir, "tofu", "1.6.2"), filepath.Dir(binaries[3].Path))
+
+	_, err = cache.Resolve("terraform >= 2.0.0")
+	assert.Equal(t, NoBinaryVersionMatches{Constraint: "terraform >= 2.0.0", CacheDir: cacheDir}, err)
+	_, err = cache.Resolve(">= 1.5.0")
+	assert.Error(t, err)
+	_, err = cache.Resolve("terraform")
+	assert.Error(t, err)
+	_, err = cache.BinaryE("terraform", "1.4.0")
+	assert.Equal(t, BinaryVersionNotFound{Product: "terraform", Version: "1.4.0", CacheDir: cacheDir}, err)
+}
+
+func TestBinaryCacheChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+	addFakeRelease(t, cacheDir, "terraform", "1.5.7")
+	zipPath := filepath.Join(cacheDir, "terraform", "1.5.7", fmt.Sprintf("terraform_1.5.7_%s_%s.zip", runtime.GOOS, runtime.GOARCH))
+	file, err := os.OpenFile(zipPath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString("tampered")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = NewBinaryCache(cacheDir).BinaryE("terraform", "1.5.7")
+	require.Error(t, err)
+	assert.IsType(t, BinaryChecksumMismatch{}, err)
+	assert.NoFileExists(t, filepath.Join(cacheDir, "terraform", "1.5.7", "terraform"))
+}
+
+func TestForEachVersionInCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The fake releases are shell scripts")
+	}
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+	addFakeRelease(t, cacheDir, "terraform", "1.5.7")
+	addFakeRelease(t, cacheDir, "tofu", "1.6.2")
+
+	ran := []string{}
+	ForEachVersionInCache(t, NewBinaryCache(cacheDir), []string{"terraform ~> 1.5", "tofu ~> 1.6"}, func(t *testing.T, options *Options) {
+		out, err := RunTerraformCommandE(t, options, "version")
+		require.NoError(t, err)
+		ran = append(ran, out)
+	})
+	assert.Equal(t, []string{"terraform v1.5.7", "tofu v1.6.2"}, ran)
+}
@@ -2,9 +2,13 @@ package terraform
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"strings"
+	"sync"
 
 	"github.com/gruntwork-io/terratest/modules/collections"
+	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/testing"
@@ -17,6 +21,11 @@ func generateCommand(options *Options, args ...string) shell.Command {
 		WorkingDir: options.TerraformDir,
 		Env:        options.EnvVars,
 		Logger:     options.Logger,
+		Context:    options.Context,
+		Timeout:    options.Timeout,
+	}
+	if options.JSONOutput {
+		cmd.Logger = logger.New(uiEventLogger{delegate: options.Logger, handler: options.UIEventHandler, mutex: &sync.Mutex{}})
 	}
 	return cmd
 }
@@ -79,22 +88,45 @@ func RunTerraformCommand(t testing.TestingT, additionalOptions *Options, args ..
 func RunTerraformCommandE(t testing.TestingT, additionalOptions *Options, additionalArgs ...string) (string, error) {
 	options, args := GetCommonOptions(additionalOptions, additionalArgs...)
 
+	removeAutoVarFile, err := writeAutoVarFile(t, options)
+	if err != nil {
+		return "", err
+	}
+	defer removeAutoVarFile()
+
 	cmd := generateCommand(options, args...)
 	description := fmt.Sprintf("%s %v", options.TerraformBinary, args)
 	return retry.DoWithRetryableErrorsE(t, description, options.RetryableTerraformErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
-		return shell.RunCommandAndGetOutputE(t, cmd)
+		out, err := shell.RunCommandAndGetOutputE(t, cmd)
+		return shell.FatalIfCommandCancelled(withDiagnostics(options, out, err))
 	})
 }
 
 // RunTerraformCommandAndGetStdoutE runs terraform with the given arguments and options and returns solely its stdout
 // (but not stderr).
 func RunTerraformCommandAndGetStdoutE(t testing.TestingT, additionalOptions *Options, additionalArgs ...string) (string, error) {
+	return runTerraformCommandWithStdinAndGetStdoutE(t, additionalOptions, "", additionalArgs...)
+}
+
+// runTerraformCommandWithStdinAndGetStdoutE runs terraform with the given arguments and options, passing it the given
+// input on stdin (or the stdin of this Go program if the input is empty), and returns solely its stdout.
+func runTerraformCommandWithStdinAndGetStdoutE(t testing.TestingT, additionalOptions *Options, stdin string, additionalArgs ...string) (string, error) {
 	options, args := GetCommonOptions(additionalOptions, additionalArgs...)
 
+	removeAutoVarFile, err := writeAutoVarFile(t, options)
+	if err != nil {
+		return "", err
+	}
+	defer removeAutoVarFile()
+
 	cmd := generateCommand(options, args...)
 	description := fmt.Sprintf("%s %v", options.TerraformBinary, args)
 	return retry.DoWithRetryableErrorsE(t, description, options.RetryableTerraformErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
-		return shell.RunCommandAndGetStdOutE(t, cmd)
+		if stdin != "" {
+			cmd.Stdin = strings.NewReader(stdin)
+		}
+		out, err := shell.RunCommandAndGetStdOutE(t, cmd)
+		return shell.FatalIfCommandCancelled(withDiagnostics(options, out, err))
 	})
 }
 
@@ -111,9 +143,15 @@ func GetExitCodeForTerraformCommand(t testing.TestingT, additionalOptions *Optio
 func GetExitCodeForTerraformCommandE(t testing.TestingT, additionalOptions *Options, additionalArgs ...string) (int, error) {
 	options, args := GetCommonOptions(additionalOptions, additionalArgs...)
 
+	removeAutoVarFile, err := writeAutoVarFile(t, options)
+	if err != nil {
+		return DefaultErrorExitCode, err
+	}
+	defer removeAutoVarFile()
+
 	additionalOptions.Logger.Logf(t, "Running %s with args %v", options.TerraformBinary, args)
 	cmd := generateCommand(options, args...)
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err = shell.RunCommandAndGetOutputE(t, cmd)
 	if err == nil {
 		return DefaultSuccessExitCode, nil
 	}
@@ -124,6 +162,31 @@ func GetExitCodeForTerraformCommandE(t testing.TestingT, additionalOptions *Opti
 	return DefaultErrorExitCode, getExitCodeErr
 }
 
+// writeAutoVarFile writes options.Vars to a temporary .auto.tfvars.json file in options.TerraformDir if
+// WriteVarsToFile is set, so that Terraform loads them automatically. The returned function removes the file again.
+func writeAutoVarFile(t testing.TestingT, options *Options) (func(), error) {
+	if !options.WriteVarsToFile || len(options.Vars) == 0 {
+		return func() {}, nil
+	}
+
+	dir := options.TerraformDir
+	if dir == "" {
+		dir = "."
+	}
+	varFile, err := os.CreateTemp(dir, "terratest-*.auto.tfvars.json")
+	if err != nil {
+		return nil, err
+	}
+	if err := varFile.Close(); err != nil {
+		return nil, err
+	}
+	if err := WriteVarFileE(t, varFile.Name(), options.Vars); err != nil {
+		os.Remove(varFile.Name())
+		return nil, err
+	}
+	return func() { os.Remove(varFile.Name()) }, nil
+}
+
 func defaultTerraformExecutable() string {
 	cmd := exec.Command(TerraformDefaultPath, "-version")
 	cmd.Stdin = nil
@@ -0,0 +1,164 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Eval evaluates the given expression (e.g., local.subnet_cidrs or cidrsubnet(var.vpc_cidr, 8, 1)) with terraform
+// console against the state of the module at options.TerraformDir, or against a plan if EvalAgainstPlan is set, and
+// returns the result converted to Go types in the same way as the terraform output functions (e.g., a map becomes a
+// map[string]interface{} and whole numbers become ints). This allows to unit test the locals, for expressions, and
+// functions of a module without applying it. The module must be initialized. This will fail the test if there is an
+// error.
+//
+// Example:
+//
+//	terraform.Init(t, options)
+//	assert.Equal(t, []interface{}{"10.0.1.0/24", "10.0.2.0/24"}, terraform.Eval(t, options, "local.private_subnets"))
+func Eval(t testing.TestingT, options *Options, expression string) interface{} {
+	out, err := EvalE(t, options, expression)
+	require.NoError(t, err)
+	return out
+}
+
+// EvalE evaluates the given expression with terraform console against the state of the module at
+// options.TerraformDir, or against a plan if EvalAgainstPlan is set, and returns the result converted to Go types in
+// the same way as the terraform output functions. The module must be initialized.
+func EvalE(t testing.TestingT, options *Options, expression string) (interface{}, error) {
+	out, err := EvalAllE(t, options, expression)
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// EvalAll evaluates all the given expressions with a single terraform console session and returns the results in the
+// same order. This is much faster than calling Eval for each expression, as Terraform only loads the module, state, and
+// providers once. This will fail the test if there is an error.
+func EvalAll(t testing.TestingT, options *Options, expressions ...string) []interface{} {
+	out, err := EvalAllE(t, options, expressions...)
+	require.NoError(t, err)
+	return out
+}
+
+// EvalAllE evaluates all the given expressions with a single terraform console session and returns the results in the
+// same order. Note that when its input is not a terminal, terraform console only prints the result of the last
+// expression it reads, so the expressions are evaluated as the elements of a single list. This also means that the
+// expressions must not contain comments, and that if any expression fails to evaluate, the error is returned for all of
+// them.
+func EvalAllE(t testing.TestingT, options *Options, expressions ...string) ([]interface{}, error) {
+	if len(expressions) == 0 {
+		return []interface{}{}, nil
+	}
+
+	out, err := runTerraformCommandWithStdinAndGetStdoutE(t, options, consoleInput(expressions), consoleArgs(options)...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := parseConsoleOutput(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(expressions) {
+		return nil, fmt.Errorf("terraform console returned %d values for %d expressions", len(values), len(expressions))
+	}
+	return values, nil
+}
+
+// EvalStruct evaluates the given expression with terraform console and stores the result in the value pointed to by
+// v, using the JSON encoding of the result. This will fail the test if there is an error.
+func EvalStruct(t testing.TestingT, options *Options, expression string, v interface{}) {
+	require.NoError(t, EvalStructE(t, options, expression, v))
+}
+
+// EvalStructE evaluates the given expression with terraform console and stores the result in the value pointed to by
+// v, using the JSON encoding of the result.
+func EvalStructE(t testing.TestingT, options *Options, expression string, v interface{}) error {
+	out, err := runTerraformCommandWithStdinAndGetStdoutE(t, options, consoleInput([]string{expression}), consoleArgs(options)...)
+	if err != nil {
+		return err
+	}
+	jsonList, err := consoleOutputAsJSON(out)
+	if err != nil {
+		return err
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal([]byte(jsonList), &values); err != nil {
+		return err
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("terraform console returned %d values for 1 expression", len(values))
+	}
+	return json.Unmarshal(values[0], v)
+}
+
+func consoleArgs(options *Options) []string {
+	args := []string{"console"}
+	if options.EvalAgainstPlan {
+		args = append(args, "-plan")
+	}
+	return FormatArgs(options, args...)
+}
+
+// consoleInput returns the input for terraform console that evaluates all the given expressions as the elements of a
+// single list, encoded as JSON so that the result can be decoded without losing any type information. Terraform console
+// reads one expression per line, so line breaks within the expressions are replaced with spaces.
+func consoleInput(expressions []string) string {
+	elements := make([]string, 0, len(expressions))
+	for _, expression := range expressions {
+		elements = append(elements, fmt.Sprintf("(%s)", strings.NewReplacer("\r\n", " ", "\n", " ").Replace(expression)))
+	}
+	return fmt.Sprintf("jsonencode([%s])\n", strings.Join(elements, ", "))
+}
+
+// parseConsoleOutput decodes the output of terraform console for the input returned by consoleInput into Go values.
+func parseConsoleOutput(out string) ([]interface{}, error) {
+	jsonList, err := consoleOutputAsJSON(out)
+	if err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	if err := json.Unmarshal([]byte(jsonList), &values); err != nil {
+		return nil, err
+	}
+	parsed, err := parseValue(values)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.([]interface{}), nil
+}
+
+// consoleOutputAsJSON returns the JSON string that terraform console prints (as an HCL string literal) for the input
+// returned by consoleInput.
+func consoleOutputAsJSON(out string) (string, error) {
+	result := strings.TrimSpace(out)
+	switch result {
+	case "(known after apply)":
+		return "", EvalValueUnknown{}
+	case "(sensitive value)":
+		return "", EvalValueSensitive{}
+	}
+
+	if !strings.HasPrefix(result, `"`) {
+		// Before Terraform 0.15, terraform console printed strings without quotes.
+		return result, nil
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(result), "<terraform console>", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("unexpected output from terraform console: %s", result)
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+		return "", fmt.Errorf("unexpected output from terraform console: %s", result)
+	}
+	return value.AsString(), nil
+}
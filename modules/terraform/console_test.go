@@ -0,0 +1,81 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsoleInput(t *testing.T) {
+	t.Parallel()
+
+	input := consoleInput([]string{"local.a", "{\n  for k, v in var.m :\n  k => \"a  b\"\n}"})
+	assert.Equal(t, "jsonencode([(local.a), ({   for k, v in var.m :   k => \"a  b\" })])\n", input)
+}
+
+func TestParseConsoleOutput(t *testing.T) {
+	t.Parallel()
+
+	values, err := parseConsoleOutput(`"[{\"count\":3,\"ratio\":0.5,\"tags\":{\"Name\":\"$${foo}\"}},[\"a\",1],null,\"line\\nbreak\"]"` + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"count": 3, "ratio": 0.5, "tags": map[string]interface{}{"Name": "${foo}"}},
+		[]interface{}{"a", 1},
+		nil,
+		"line\nbreak",
+	}, values)
+
+	// Lists nested in maps may hold values of any type (e.g., a map(list(string)))
+	values, err = parseConsoleOutput(`[{"a":["x","y"],"b":[1,2.5],"c":[{"d":[true]}]}]`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"a": []interface{}{"x", "y"},
+			"b": []interface{}{1, 2.5},
+			"c": []interface{}{map[string]interface{}{"d": []interface{}{true}}},
+		},
+	}, values)
+
+	// Before Terraform 0.15, strings were printed without quotes
+	values, err = parseConsoleOutput(`["a",2]`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", 2}, values)
+
+	_, err = parseConsoleOutput("(known after apply)\n")
+	assert.Equal(t, EvalValueUnknown{}, err)
+	_, err = parseConsoleOutput("(sensitive value)\n")
+	assert.Equal(t, EvalValueSensitive{}, err)
+	_, err = parseConsoleOutput(`"not json"`)
+	assert.Error(t, err)
+}
+
+func TestEval(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-console", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir: testFolder,
+		Vars:         map[string]interface{}{"vpc_cidr": "10.1.0.0/16"},
+	}
+	Init(t, options)
+
+	assert.Equal(t, []interface{}{"10.1.1.0/24", "10.1.2.0/24"}, Eval(t, options, "local.private_subnets"))
+
+	values := EvalAll(t, options, "local.subnets_by_az", "length(var.azs)", `upper("a")`)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"us-east-1a": "10.1.1.0/24", "us-east-1b": "10.1.2.0/24"},
+		2,
+		"A",
+	}, values)
+
+	var subnets map[string]string
+	EvalStruct(t, options, "local.subnets_by_az", &subnets)
+	assert.Equal(t, "10.1.2.0/24", subnets["us-east-1b"])
+
+	_, err = EvalE(t, options, "local.does_not_exist")
+	assert.Error(t, err)
+}
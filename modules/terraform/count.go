@@ -37,8 +37,15 @@ func GetResourceCount(t testing.TestingT, cmdout string) *ResourceCount {
 	return cnt
 }
 
-// GetResourceCountE parses stdout/stderr of apply/plan/destroy commands and returns number of affected resources.
+// GetResourceCountE parses stdout/stderr of apply/plan/destroy commands and returns number of affected resources. Both
+// the human readable output and the machine readable output (i.e., when run with JSONOutput) are supported.
 func GetResourceCountE(t testing.TestingT, cmdout string) (*ResourceCount, error) {
+	if events, err := ParseUIEvents(cmdout); err == nil {
+		if summary := events.ChangeSummary(); summary != nil {
+			return &ResourceCount{Add: summary.Add, Change: summary.Change, Destroy: summary.Remove}, nil
+		}
+	}
+
 	cnt := ResourceCount{}
 
 	terraformCommandPatterns := []struct {
@@ -0,0 +1,194 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// Diagnostic is an error or warning reported by Terraform, with its severity, summary, detail, and the range in the
+// configuration it is about, as reported in the machine readable output of terraform validate -json and of the
+// commands run with JSONOutput.
+type Diagnostic = tfjson.Diagnostic
+
+// DiagnosticMatcher describes an expected diagnostic. All the fields that are set must match; fields that are left
+// empty match any diagnostic.
+type DiagnosticMatcher struct {
+	// The severity of the diagnostic. Defaults to error.
+	Severity tfjson.DiagnosticSeverity
+	// A substring of the summary of the diagnostic (e.g., "Invalid value for variable").
+	Summary string
+	// A substring of the detail of the diagnostic, which includes the error_message of validation blocks, preconditions,
+	// and postconditions.
+	Detail string
+	// The name of the input variable the diagnostic is about (e.g., "instance_type" for an invalid var.instance_type).
+	Variable string
+	// The file (as reported by Terraform, i.e., relative to the working directory) the diagnostic is about.
+	Filename string
+}
+
+// Matches returns true if the given diagnostic matches all the fields that are set on the matcher.
+func (matcher DiagnosticMatcher) Matches(diagnostic Diagnostic) bool {
+	severity := matcher.Severity
+	if severity == "" {
+		severity = tfjson.DiagnosticSeverityError
+	}
+	if diagnostic.Severity != severity {
+		return false
+	}
+	if !strings.Contains(diagnostic.Summary, matcher.Summary) || !strings.Contains(diagnostic.Detail, matcher.Detail) {
+		return false
+	}
+	if matcher.Filename != "" && (diagnostic.Range == nil || diagnostic.Range.Filename != matcher.Filename) {
+		return false
+	}
+	if matcher.Variable != "" && !diagnosticIsAboutVariable(diagnostic, matcher.Variable) {
+		return false
+	}
+	return true
+}
+
+func (matcher DiagnosticMatcher) String() string {
+	fields := []string{}
+	if matcher.Severity != "" {
+		fields = append(fields, fmt.Sprintf("Severity: %q", matcher.Severity))
+	}
+	if matcher.Summary != "" {
+		fields = append(fields, fmt.Sprintf("Summary: %q", matcher.Summary))
+	}
+	if matcher.Detail != "" {
+		fields = append(fields, fmt.Sprintf("Detail: %q", matcher.Detail))
+	}
+	if matcher.Variable != "" {
+		fields = append(fields, fmt.Sprintf("Variable: %q", matcher.Variable))
+	}
+	if matcher.Filename != "" {
+		fields = append(fields, fmt.Sprintf("Filename: %q", matcher.Filename))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(fields, ", "))
+}
+
+// diagnosticIsAboutVariable returns true if the diagnostic is about the value of the given input variable. Terraform
+// does not report the variable as a separate field, so this looks at where the variable shows up in the diagnostic: the
+// synthetic file name of values set with -var, the expression values of failed validation conditions, the variable
+// block the range points to, or a reference to the variable in the detail.
+func diagnosticIsAboutVariable(diagnostic Diagnostic, name string) bool {
+	if diagnostic.Range != nil && diagnostic.Range.Filename == fmt.Sprintf("<value for var.%s>", name) {
+		return true
+	}
+	if diagnostic.Snippet != nil {
+		for _, value := range diagnostic.Snippet.Values {
+			if value.Traversal == "var."+name || strings.HasPrefix(value.Traversal, "var."+name+".") || strings.HasPrefix(value.Traversal, "var."+name+"[") {
+				return true
+			}
+		}
+		if diagnostic.Snippet.Context != nil && *diagnostic.Snippet.Context == fmt.Sprintf("variable %q", name) {
+			return true
+		}
+	}
+	reference := regexp.MustCompile(fmt.Sprintf(`(\bvar\.%s\b|variable "%s")`, regexp.QuoteMeta(name), regexp.QuoteMeta(name)))
+	return reference.MatchString(diagnostic.Detail)
+}
+
+// FindDiagnostic returns the first diagnostic that matches the given matcher, or nil if there is none.
+func FindDiagnostic(diagnostics []Diagnostic, matcher DiagnosticMatcher) *Diagnostic {
+	for i := range diagnostics {
+		if matcher.Matches(diagnostics[i]) {
+			return &diagnostics[i]
+		}
+	}
+	return nil
+}
+
+// ValidateJSON calls terraform validate -json and returns the parsed result, including the diagnostics for an invalid
+// configuration. An invalid configuration is not considered an error; check Valid on the result instead. This will fail
+// the test if there is an error in the command.
+func ValidateJSON(t testing.TestingT, options *Options) *tfjson.ValidateOutput {
+	out, err := ValidateJSONE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// ValidateJSONE calls terraform validate -json and returns the parsed result, including the diagnostics for an invalid
+// configuration. An invalid configuration is not considered an error; check Valid on the result instead.
+func ValidateJSONE(t testing.TestingT, options *Options) (*tfjson.ValidateOutput, error) {
+	out, cmdErr := RunTerraformCommandAndGetStdoutE(t, options, FormatArgs(options, "validate", "-json")...)
+	result, err := ParseValidateJSON(out)
+	if err != nil {
+		if cmdErr != nil {
+			return nil, cmdErr
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParseValidateJSON parses the output of terraform validate -json.
+func ParseValidateJSON(output string) (*tfjson.ValidateOutput, error) {
+	// Skip anything that is printed before the JSON document, such as the log lines of terragrunt.
+	start := strings.Index(output, "{")
+	if start < 0 {
+		return nil, fmt.Errorf("terraform validate did not return JSON output: %s", output)
+	}
+	result := &tfjson.ValidateOutput{}
+	if err := json.Unmarshal([]byte(output[start:]), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExpectPlanFailure runs terraform plan with JSONOutput and checks that it fails with an error diagnostic for each of
+// the given matchers, returning all the diagnostics reported by the plan. This is typically used to table test the
+// variable validation blocks, preconditions, and postconditions of a module by running plan with each invalid input the
+// module is supposed to reject. Note that this does NOT run terraform init. This will fail the test if the plan
+// succeeds or if any matcher doesn't match.
+//
+// Example:
+//
+//	terraform.ExpectPlanFailure(t, options, terraform.DiagnosticMatcher{
+//		Summary:  "Invalid value for variable",
+//		Variable: "instance_type",
+//	})
+func ExpectPlanFailure(t testing.TestingT, options *Options, matchers ...DiagnosticMatcher) []Diagnostic {
+	diagnostics, err := ExpectPlanFailureE(t, options, matchers...)
+	require.NoError(t, err)
+	return diagnostics
+}
+
+// ExpectPlanFailureE runs terraform plan with JSONOutput and checks that it fails with an error diagnostic for each of
+// the given matchers, returning all the diagnostics reported by the plan. This returns an UnexpectedPlanSuccess error if
+// the plan succeeds, and a DiagnosticNotFound error if any matcher doesn't match. The options passed in are not
+// modified.
+func ExpectPlanFailureE(t testing.TestingT, options *Options, matchers ...DiagnosticMatcher) ([]Diagnostic, error) {
+	planOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	planOptions.JSONOutput = true
+
+	out, planErr := PlanE(t, planOptions)
+	if planErr == nil {
+		return nil, UnexpectedPlanSuccess{}
+	}
+	events, err := ParseUIEvents(out)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics := events.Diagnostics()
+	if len(events.Errors()) == 0 {
+		// The plan failed without reporting why in its machine readable output, e.g., because terraform is not installed.
+		return nil, planErr
+	}
+
+	for _, matcher := range matchers {
+		if FindDiagnostic(diagnostics, matcher) == nil {
+			return diagnostics, DiagnosticNotFound{Matcher: matcher, Diagnostics: diagnostics}
+		}
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,173 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testValidateJSONOutput = `{
+  "format_version": "1.0",
+  "valid": false,
+  "error_count": 1,
+  "warning_count": 1,
+  "diagnostics": [
+    {
+      "severity": "error",
+      "summary": "Invalid value for variable",
+      "detail": "Only t3 instance types are supported.\n\nThis was checked by the validation rule at main.tf:5,3-13.",
+      "range": {"filename": "<value for var.instance_type>", "start": {"line": 1, "column": 1, "byte": 0}, "end": {"line": 1, "column": 1, "byte": 0}}
+    },
+    {
+      "severity": "warning",
+      "summary": "Deprecated attribute",
+      "detail": "The attribute \"foo\" is deprecated.",
+      "range": {"filename": "main.tf", "start": {"line": 12, "column": 3, "byte": 200}, "end": {"line": 12, "column": 6, "byte": 203}}
+    }
+  ]
+}`
+
+func TestParseValidateJSON(t *testing.T) {
+	t.Parallel()
+
+	result, err := ParseValidateJSON("some log line\n" + testValidateJSONOutput)
+	require.NoError(t, err)
+
+	assert.False(t, result.Valid)
+	assert.Equal(t, 1, result.ErrorCount)
+	require.Len(t, result.Diagnostics, 2)
+	assert.Equal(t, tfjson.DiagnosticSeverityError, result.Diagnostics[0].Severity)
+	assert.Equal(t, "<value for var.instance_type>", result.Diagnostics[0].Range.Filename)
+	assert.Equal(t, 12, result.Diagnostics[1].Range.Start.Line)
+
+	_, err = ParseValidateJSON("Error: no JSON here")
+	assert.Error(t, err)
+}
+
+func TestDiagnosticMatcher(t *testing.T) {
+	t.Parallel()
+
+	context := `variable "instance_count"`
+	diagnostics := []Diagnostic{
+		{
+			Severity: tfjson.DiagnosticSeverityError,
+			Summary:  "Invalid value for variable",
+			Detail:   "Only t3 instance types are supported.",
+			Range:    &tfjson.Range{Filename: "<value for var.instance_type>"},
+		},
+		{
+			Severity: tfjson.DiagnosticSeverityError,
+			Summary:  "Module output value precondition failed",
+			Detail:   "The instance count must not exceed the max instance count.",
+			Range:    &tfjson.Range{Filename: "main.tf"},
+			Snippet: &tfjson.DiagnosticSnippet{Values: []tfjson.DiagnosticExpressionValue{
+				{Traversal: "var.max_instance_count", Statement: "is 3"},
+			}},
+		},
+		{
+			Severity: tfjson.DiagnosticSeverityError,
+			Summary:  "Invalid value for input variable",
+			Detail:   "a number is required.",
+			Range:    &tfjson.Range{Filename: "terraform.tfvars"},
+			Snippet:  &tfjson.DiagnosticSnippet{Context: &context},
+		},
+		{
+			Severity: tfjson.DiagnosticSeverityWarning,
+			Summary:  "Value for undeclared variable",
+			Detail:   `The root module does not declare a variable named "instance_types".`,
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		matcher  DiagnosticMatcher
+		expected int
+	}{
+		{"summary", DiagnosticMatcher{Summary: "precondition failed"}, 1},
+		{"detail", DiagnosticMatcher{Detail: "Only t3"}, 0},
+		{"variable from -var", DiagnosticMatcher{Variable: "instance_type"}, 0},
+		{"variable from expression values", DiagnosticMatcher{Variable: "max_instance_count"}, 1},
+		{"variable from snippet context", DiagnosticMatcher{Variable: "instance_count"}, 2},
+		{"filename", DiagnosticMatcher{Filename: "terraform.tfvars"}, 2},
+		{"warning", DiagnosticMatcher{Severity: tfjson.DiagnosticSeverityWarning, Summary: "undeclared"}, 3},
+		{"errors only by default", DiagnosticMatcher{Summary: "undeclared"}, -1},
+		{"all fields must match", DiagnosticMatcher{Summary: "Invalid value for variable", Variable: "instance_count"}, -1},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			found := FindDiagnostic(diagnostics, testCase.matcher)
+			if testCase.expected < 0 {
+				assert.Nil(t, found)
+				return
+			}
+			require.NotNil(t, found)
+			assert.Equal(t, diagnostics[testCase.expected], *found)
+		})
+	}
+}
+
+func TestDiagnosticNotFoundError(t *testing.T) {
+	t.Parallel()
+
+	err := DiagnosticNotFound{
+		Matcher:     DiagnosticMatcher{Summary: "Invalid value", Variable: "instance_type"},
+		Diagnostics: []Diagnostic{{Severity: tfjson.DiagnosticSeverityError, Summary: "Unsupported argument"}},
+	}
+	assert.Contains(t, err.Error(), `{Summary: "Invalid value", Variable: "instance_type"}`)
+	assert.Contains(t, err.Error(), "Error: Unsupported argument")
+}
+
+func TestExpectPlanFailure(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-variable-validation", t.Name())
+	require.NoError(t, err)
+	Init(t, &Options{TerraformDir: testFolder})
+
+	testCases := []struct {
+		name    string
+		vars    map[string]interface{}
+		matcher DiagnosticMatcher
+	}{
+		{
+			"invalid instance type",
+			map[string]interface{}{"instance_type": "m5.large"},
+			DiagnosticMatcher{Summary: "Invalid value for variable", Detail: "Only t3 instance types", Variable: "instance_type"},
+		},
+		{
+			"too many instances",
+			map[string]interface{}{"instance_count": 5},
+			DiagnosticMatcher{Detail: "must not exceed the max instance count", Variable: "max_instance_count"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			diagnostics := ExpectPlanFailure(t, &Options{TerraformDir: testFolder, Vars: testCase.vars}, testCase.matcher)
+			assert.NotEmpty(t, diagnostics)
+		})
+	}
+
+	_, err = ExpectPlanFailureE(t, &Options{TerraformDir: testFolder})
+	assert.Equal(t, UnexpectedPlanSuccess{}, err)
+}
+
+func TestValidateJSON(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-variable-validation", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{TerraformDir: testFolder}
+	Init(t, options)
+	result := ValidateJSON(t, options)
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Diagnostics)
+}
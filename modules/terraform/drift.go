@@ -0,0 +1,191 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AttributeDrift is a top level attribute of a resource whose real value no longer matches the value in the state.
+type AttributeDrift struct {
+	Name     string
+	Expected interface{} // The value recorded in the state
+	Actual   interface{} // The value of the real infrastructure object
+}
+
+func (drift AttributeDrift) String() string {
+	return fmt.Sprintf("%s: %v => %v", drift.Name, drift.Expected, drift.Actual)
+}
+
+// ResourceDrift describes how a single resource was changed outside of Terraform.
+type ResourceDrift struct {
+	Address string
+	Type    string
+	// ChangeActionUpdate if the resource was modified, or ChangeActionDelete if it no longer exists.
+	Action ChangeAction
+	// The attributes that drifted, sorted by name. Empty if the resource was deleted.
+	Attributes []AttributeDrift
+}
+
+// Attribute returns the drift of the attribute with the given name, or nil if that attribute did not drift.
+func (drift ResourceDrift) Attribute(name string) *AttributeDrift {
+	for i := range drift.Attributes {
+		if drift.Attributes[i].Name == name {
+			return &drift.Attributes[i]
+		}
+	}
+	return nil
+}
+
+func (drift ResourceDrift) String() string {
+	if drift.Action == ChangeActionDelete {
+		return fmt.Sprintf("%s (deleted outside of Terraform)", drift.Address)
+	}
+	attributes := make([]string, 0, len(drift.Attributes))
+	for _, attribute := range drift.Attributes {
+		attributes = append(attributes, attribute.String())
+	}
+	return fmt.Sprintf("%s (%s)", drift.Address, strings.Join(attributes, "; "))
+}
+
+// DriftReport is the drift between the state and the real infrastructure detected by a refresh-only plan.
+type DriftReport struct {
+	// The drifted resources, sorted by address.
+	Resources []ResourceDrift
+}
+
+// HasDrift returns true if any resource was changed outside of Terraform.
+func (report *DriftReport) HasDrift() bool {
+	return len(report.Resources) > 0
+}
+
+// Resource returns the drift of the resource with the given address, or nil if that resource did not drift.
+func (report *DriftReport) Resource(address string) *ResourceDrift {
+	for i := range report.Resources {
+		if report.Resources[i].Address == address {
+			return &report.Resources[i]
+		}
+	}
+	return nil
+}
+
+func (report *DriftReport) String() string {
+	lines := make([]string, 0, len(report.Resources))
+	for _, resource := range report.Resources {
+		lines = append(lines, resource.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AssertNoDrift checks that the report does not contain any drift, failing the test with the list of drifted resources
+// and attributes if it does.
+func AssertNoDrift(t testing.TestingT, report *DriftReport) bool {
+	return assert.Falsef(t, report.HasDrift(), "Detected drift in the following resources:\n%s", report)
+}
+
+// RequireNoDrift checks that the report does not contain any drift, failing and halting the test with the list of
+// drifted resources and attributes if it does.
+func RequireNoDrift(t testing.TestingT, report *DriftReport) {
+	require.Falsef(t, report.HasDrift(), "Detected drift in the following resources:\n%s", report)
+}
+
+// DetectDrift runs terraform plan -refresh-only -json against the module at options.TerraformDir and returns the
+// attributes of each resource that were changed outside of Terraform since the last apply. This is typically used to
+// test that a module reconciles out-of-band changes: modify a resource with the cloud provider's SDK, check the drift
+// with DetectDrift, then apply again and check that the drift is gone. This will fail the test if there is an error.
+//
+// Example:
+//
+//	terraform.InitAndApply(t, options)
+//	removeIngressRuleOutOfBand(t, securityGroupID)
+//	drift := terraform.DetectDrift(t, options)
+//	require.NotNil(t, drift.Resource("aws_security_group.this").Attribute("ingress"))
+//	terraform.Apply(t, options)
+//	terraform.AssertNoDrift(t, terraform.DetectDrift(t, options))
+func DetectDrift(t testing.TestingT, options *Options) *DriftReport {
+	report, err := DetectDriftE(t, options)
+	require.NoError(t, err)
+	return report
+}
+
+// DetectDriftE runs terraform plan -refresh-only -json against the module at options.TerraformDir and returns the
+// attributes of each resource that were changed outside of Terraform since the last apply. The options passed in are
+// not modified, and the state is not updated.
+func DetectDriftE(t testing.TestingT, options *Options) (*DriftReport, error) {
+	driftOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpFile, err := os.CreateTemp("", "terratest-drift-plan-file-")
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	driftOptions.PlanFilePath = tmpFile.Name()
+	driftOptions.JSONOutput = true
+	// The -lock flag is added by FormatArgs, according to options.Lock
+	if _, err := RunTerraformCommandE(t, driftOptions, FormatArgs(driftOptions, "plan", "-refresh-only", "-input=false")...); err != nil {
+		return nil, err
+	}
+
+	planJSON, err := ShowE(t, driftOptions)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDriftReportJSON(planJSON)
+}
+
+// ParseDriftReportJSON takes in the json string representation of a plan (as returned by terraform show -json) and
+// returns the drift Terraform detected while refreshing the state for that plan.
+func ParseDriftReportJSON(planJSON string) (*DriftReport, error) {
+	// terraform-json does not expose the resource_drift field of the plan representation, but its entries have the same
+	// structure as resource_changes.
+	var plan struct {
+		ResourceDrift []*tfjson.ResourceChange `json:"resource_drift"`
+	}
+	if err := json.Unmarshal([]byte(planJSON), &plan); err != nil {
+		return nil, err
+	}
+
+	report := &DriftReport{Resources: []ResourceDrift{}}
+	for _, change := range plan.ResourceDrift {
+		action := GetChangeAction(change)
+		if action == ChangeActionNoOp || change.Change == nil {
+			continue
+		}
+		report.Resources = append(report.Resources, ResourceDrift{
+			Address:    change.Address,
+			Type:       change.Type,
+			Action:     action,
+			Attributes: attributeDrift(change.Change.Before, change.Change.After),
+		})
+	}
+	sort.Slice(report.Resources, func(i, j int) bool { return report.Resources[i].Address < report.Resources[j].Address })
+	return report, nil
+}
+
+// attributeDrift returns the top level attributes that differ between the state (before) and the refreshed (after)
+// values of a resource, sorted by name. This returns an empty list if the resource was deleted.
+func attributeDrift(before interface{}, after interface{}) []AttributeDrift {
+	out := []AttributeDrift{}
+	if after == nil {
+		return out
+	}
+	beforeMap, _ := before.(map[string]interface{})
+	afterMap, _ := after.(map[string]interface{})
+	for _, name := range diffAttributes(before, after, nil) {
+		out = append(out, AttributeDrift{Name: name, Expected: beforeMap[name], Actual: afterMap[name]})
+	}
+	return out
+}
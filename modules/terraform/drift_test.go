@@ -0,0 +1,101 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRefreshOnlyPlanJSON = `{
+  "format_version": "1.1",
+  "terraform_version": "1.5.7",
+  "resource_drift": [
+    {
+      "address": "aws_security_group.this",
+      "mode": "managed",
+      "type": "aws_security_group",
+      "name": "this",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["update"],
+        "before": {"name": "web", "ingress": [{"from_port": 443}], "tags": {"Team": "infra"}},
+        "after": {"name": "web", "ingress": [{"from_port": 443}, {"from_port": 22}], "tags": {}}
+      }
+    },
+    {
+      "address": "aws_s3_bucket.logs",
+      "mode": "managed",
+      "type": "aws_s3_bucket",
+      "name": "logs",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["delete"],
+        "before": {"bucket": "logs"},
+        "after": null
+      }
+    }
+  ]
+}`
+
+func TestParseDriftReportJSON(t *testing.T) {
+	t.Parallel()
+
+	report, err := ParseDriftReportJSON(testRefreshOnlyPlanJSON)
+	require.NoError(t, err)
+	require.True(t, report.HasDrift())
+	require.Len(t, report.Resources, 2)
+
+	assert.Equal(t, ResourceDrift{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: ChangeActionDelete, Attributes: []AttributeDrift{}}, report.Resources[0])
+
+	securityGroup := report.Resource("aws_security_group.this")
+	require.NotNil(t, securityGroup)
+	assert.Equal(t, ChangeActionUpdate, securityGroup.Action)
+	assert.Len(t, securityGroup.Attributes, 2)
+	assert.Nil(t, securityGroup.Attribute("name"))
+	assert.Equal(t, map[string]interface{}{"Team": "infra"}, securityGroup.Attribute("tags").Expected)
+	assert.Len(t, securityGroup.Attribute("ingress").Actual, 2)
+
+	driftT := &mockT{}
+	AssertNoDrift(driftT, report)
+	assert.True(t, driftT.Failed)
+	require.Len(t, driftT.Messages, 1)
+	assert.Contains(t, driftT.Messages[0], "aws_s3_bucket.logs (deleted outside of Terraform)")
+	assert.Contains(t, driftT.Messages[0], "aws_security_group.this (ingress: ")
+	assert.Contains(t, driftT.Messages[0], "; tags: map[Team:infra] => map[]")
+
+	noDrift, err := ParseDriftReportJSON(`{"format_version": "1.1"}`)
+	require.NoError(t, err)
+	RequireNoDrift(t, noDrift)
+}
+
+func TestDetectDrift(t *testing.T) {
+	t.Parallel()
+
+	testFolder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testFolder, "main.tf"), []byte(`
+resource "local_file" "test" {
+  filename = "${path.module}/drift.txt"
+  content  = "managed by terraform"
+}
+`), 0644))
+
+	options := &Options{
+		TerraformDir: testFolder,
+	}
+	defer Destroy(t, options)
+
+	InitAndApply(t, options)
+	AssertNoDrift(t, DetectDrift(t, options))
+
+	// Remove the file outside of Terraform
+	require.NoError(t, os.Remove(filepath.Join(testFolder, "drift.txt")))
+	report := DetectDrift(t, options)
+	require.NotNil(t, report.Resource("local_file.test"))
+	assert.Equal(t, ChangeActionDelete, report.Resource("local_file.test").Action)
+
+	Apply(t, options)
+	AssertNoDrift(t, DetectDrift(t, options))
+}
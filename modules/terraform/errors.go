@@ -3,6 +3,10 @@ package terraform
 import (
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
 )
 
 // TgInvalidBinary occurs when a terragrunt function is called and the TerraformBinary is
@@ -101,3 +105,148 @@ type WorkspaceDoesNotExist string
 func (err WorkspaceDoesNotExist) Error() string {
 	return fmt.Sprintf("The workspace %q does not exist.", string(err))
 }
+
+// ResourceNotFound is returned when a resource address cannot be found in the terraform state or plan
+type ResourceNotFound string
+
+func (err ResourceNotFound) Error() string {
+	return fmt.Sprintf("resource %q not found", string(err))
+}
+
+// AttributeNotFound is returned when an attribute path cannot be resolved on a resource
+type AttributeNotFound struct {
+	Address   string
+	Attribute string
+}
+
+func (err AttributeNotFound) Error() string {
+	return fmt.Sprintf("attribute %q not found on resource %q", err.Attribute, err.Address)
+}
+
+// DiagnosticsError is returned when a Terraform command run with JSONOutput fails and reports error diagnostics in its
+// machine readable output.
+type DiagnosticsError struct {
+	Underlying  error
+	Diagnostics []tfjson.Diagnostic
+}
+
+func (err *DiagnosticsError) Error() string {
+	messages := []string{}
+	for _, diagnostic := range err.Diagnostics {
+		message := "Error: " + diagnostic.Summary
+		if diagnostic.Detail != "" {
+			message += "\n\n" + diagnostic.Detail
+		}
+		messages = append(messages, message)
+	}
+	return fmt.Sprintf("%v\n%s", err.Underlying, strings.Join(messages, "\n\n"))
+}
+
+func (err *DiagnosticsError) Unwrap() error {
+	return err.Underlying
+}
+
+// PluginCacheLockTimeoutError is returned when the plugin cache could not be locked within the timeout, e.g., because
+// another terraform init is stuck.
+type PluginCacheLockTimeoutError struct {
+	LockFile string
+	Timeout  time.Duration
+}
+
+func (err PluginCacheLockTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for the plugin cache lock %s", err.Timeout, err.LockFile)
+}
+
+// PluginCacheMirrorOverridden is returned when terraform init is run with a plugin cache that has a filesystem mirror,
+// while TF_CLI_CONFIG_FILE is set on the options (e.g., by RegistryServer.Configure), as the CLI config of the options
+// would keep Terraform from using the mirror.
+type PluginCacheMirrorOverridden struct {
+	MirrorDir     string
+	CLIConfigFile string
+}
+
+func (err PluginCacheMirrorOverridden) Error() string {
+	return fmt.Sprintf("the CLI config %s set through TF_CLI_CONFIG_FILE overrides the filesystem mirror %s of the plugin cache: set DisablePluginCache, or use a plugin cache without a mirror", err.CLIConfigFile, err.MirrorDir)
+}
+
+// TestsFailed is returned when terraform test reports failed or errored test files or run blocks.
+type TestsFailed []string
+
+func (err TestsFailed) Error() string {
+	return fmt.Sprintf("terraform test failed: %s", strings.Join(err, ", "))
+}
+
+// UnsupportedTerragruntCommand is returned when a function that runs a Terraform command terragrunt can't wrap is called
+// with terragrunt set as the TerraformBinary.
+type UnsupportedTerragruntCommand string
+
+func (command UnsupportedTerragruntCommand) Error() string {
+	return fmt.Sprintf("terraform %s is not supported with terragrunt as the TerraformBinary", string(command))
+}
+
+// UnexpectedPlanSuccess is returned when terraform plan was expected to fail, but succeeded.
+type UnexpectedPlanSuccess struct{}
+
+func (err UnexpectedPlanSuccess) Error() string {
+	return "expected terraform plan to fail, but it succeeded"
+}
+
+// DiagnosticNotFound is returned when none of the diagnostics reported by Terraform matches the expected diagnostic.
+type DiagnosticNotFound struct {
+	Matcher     DiagnosticMatcher
+	Diagnostics []tfjson.Diagnostic
+}
+
+func (err DiagnosticNotFound) Error() string {
+	return fmt.Sprintf("no diagnostic matches %s. Diagnostics:\n%s", err.Matcher, formatDiagnostics(err.Diagnostics))
+}
+
+// EvalValueUnknown is returned when an expression evaluated with terraform console depends on values that are only
+// known after apply.
+type EvalValueUnknown struct{}
+
+func (err EvalValueUnknown) Error() string {
+	return "the value of the expression is only known after apply"
+}
+
+// EvalValueSensitive is returned when an expression evaluated with terraform console is sensitive. Wrap the expression
+// in nonsensitive() to evaluate it anyway.
+type EvalValueSensitive struct{}
+
+func (err EvalValueSensitive) Error() string {
+	return "the value of the expression is sensitive, wrap it in nonsensitive() to evaluate it"
+}
+
+// BinaryVersionNotFound is returned when a binary cache does not contain the requested version of Terraform or
+// OpenTofu.
+type BinaryVersionNotFound struct {
+	Product  string
+	Version  string
+	CacheDir string
+}
+
+func (err BinaryVersionNotFound) Error() string {
+	return fmt.Sprintf("%s %s is not in the binary cache at %s", err.Product, err.Version, err.CacheDir)
+}
+
+// NoBinaryVersionMatches is returned when no release in a binary cache matches a version constraint.
+type NoBinaryVersionMatches struct {
+	Constraint string
+	CacheDir   string
+}
+
+func (err NoBinaryVersionMatches) Error() string {
+	return fmt.Sprintf("no release in the binary cache at %s matches '%s'", err.CacheDir, err.Constraint)
+}
+
+// BinaryChecksumMismatch is returned when the checksum of a release in a binary cache does not match the one in its
+// SHA256SUMS file.
+type BinaryChecksumMismatch struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (err BinaryChecksumMismatch) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", err.Path, err.Expected, err.Actual)
+}
@@ -36,6 +36,14 @@ var TerraformCommandsWithPlanFileSupport = []string{
 	"graph",
 }
 
+// TerraformCommandsWithJSONSupport is a list of all the Terraform commands that support the machine readable UI output
+// enabled with -json.
+var TerraformCommandsWithJSONSupport = []string{
+	"plan",
+	"apply",
+	"destroy",
+}
+
 // FormatArgs converts the inputs to a format palatable to terraform. This includes converting the given vars to the
 // format the Terraform CLI expects (-var key=value).
 func FormatArgs(options *Options, args ...string) []string {
@@ -49,18 +57,25 @@ func FormatArgs(options *Options, args ...string) []string {
 	}
 	lockSupported := collections.ListContains(TerraformCommandsWithLockSupport, commandType)
 	planFileSupported := collections.ListContains(TerraformCommandsWithPlanFileSupport, commandType)
+	// The machine readable UI output of multiple modules can't be told apart, so -json is not passed through run-all.
+	jsonSupported := collections.ListContains(TerraformCommandsWithJSONSupport, commandType) && args[0] != runAllCmd
 
 	// Include -var and -var-file flags unless we're running 'apply' with a plan file
 	includeVars := !(commandType == "apply" && len(options.PlanFilePath) > 0)
+	// When WriteVarsToFile is set, the vars are passed in through an auto var file instead (see writeAutoVarFile)
+	varsAsArgs := options.Vars
+	if options.WriteVarsToFile {
+		varsAsArgs = nil
+	}
 
 	terraformArgs = append(terraformArgs, args...)
 
 	if includeVars {
 		if options.SetVarsAfterVarFiles {
 			terraformArgs = append(terraformArgs, FormatTerraformArgs("-var-file", options.VarFiles)...)
-			terraformArgs = append(terraformArgs, FormatTerraformVarsAsArgs(options.Vars)...)
+			terraformArgs = append(terraformArgs, FormatTerraformVarsAsArgs(varsAsArgs)...)
 		} else {
-			terraformArgs = append(terraformArgs, FormatTerraformVarsAsArgs(options.Vars)...)
+			terraformArgs = append(terraformArgs, FormatTerraformVarsAsArgs(varsAsArgs)...)
 			terraformArgs = append(terraformArgs, FormatTerraformArgs("-var-file", options.VarFiles)...)
 		}
 	}
@@ -71,6 +86,10 @@ func FormatArgs(options *Options, args ...string) []string {
 		terraformArgs = append(terraformArgs, "-no-color")
 	}
 
+	if options.JSONOutput && jsonSupported {
+		terraformArgs = append(terraformArgs, "-json")
+	}
+
 	if lockSupported {
 		// If command supports locking, handle lock arguments
 		terraformArgs = append(terraformArgs, FormatTerraformLockAsArgs(options.Lock, options.LockTimeout)...)
@@ -279,6 +279,32 @@ func TestFormatArgsAppliesLockCorrectly(t *testing.T) {
 	}
 }
 
+func TestFormatArgsAppliesJSONOutputCorrectly(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		command  []string
+		expected []string
+	}{
+		{[]string{"plan"}, []string{"plan", "-json", "-lock=false", "-out=/some/plan/output"}},
+		{[]string{"apply", "-auto-approve"}, []string{"apply", "-auto-approve", "-json", "-lock=false", "/some/plan/output"}},
+		{[]string{"show"}, []string{"show", "/some/plan/output"}},
+		{[]string{"run-all", "plan"}, []string{"run-all", "plan", "-lock=false", "-out=/some/plan/output"}},
+	}
+
+	for _, testCase := range testCases {
+		result := FormatArgs(&Options{JSONOutput: true, PlanFilePath: "/some/plan/output"}, testCase.command...)
+		assert.Equal(t, testCase.expected, result)
+	}
+}
+
+func TestFormatArgsWriteVarsToFileOmitsVars(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{WriteVarsToFile: true, Vars: map[string]interface{}{"foo": "bar"}, VarFiles: []string{"test.tfvars"}}
+	assert.Equal(t, []string{"plan", "-var-file", "test.tfvars", "-lock=false"}, FormatArgs(options, "plan"))
+}
+
 func TestFormatSetVarsAfterVarFilesFormatsCorrectly(t *testing.T) {
 	t.Parallel()
 
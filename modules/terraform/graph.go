@@ -0,0 +1,307 @@
+package terraform
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// GraphType is the type of graph terraform graph outputs.
+type GraphType string
+
+const (
+	// GraphTypeDefault is the default graph of terraform graph, which since Terraform 1.7 is a simplified graph of the
+	// resources only.
+	GraphTypeDefault GraphType = ""
+	// GraphTypePlan is the full graph Terraform walks to create a plan, including variables, locals, outputs, and
+	// providers.
+	GraphTypePlan GraphType = "plan"
+	// GraphTypePlanDestroy is the graph Terraform walks to create a destroy plan.
+	GraphTypePlanDestroy GraphType = "plan-destroy"
+	// GraphTypeApply is the graph Terraform walks to apply a plan. Requires PlanFilePath to be set on the options.
+	GraphTypeApply GraphType = "apply"
+)
+
+// DependencyGraph is the dependency graph of a module as output by terraform graph. The nodes are keyed by address
+// (e.g., aws_iam_role.this, module.network.aws_vpc.main, var.name, or provider["registry.terraform.io/hashicorp/aws"]),
+// without the [root] prefix and the (expand) suffix Terraform adds. The close nodes of modules and providers are left out,
+// and other nodes keep their suffix (e.g., aws_iam_role.this (destroy) in a plan-destroy graph), as their edges point in
+// the opposite direction.
+type DependencyGraph struct {
+	// The addresses of all the nodes, sorted.
+	Nodes []string
+	// A map from the address of each node to the addresses of the nodes it directly depends on, sorted.
+	Edges map[string][]string
+}
+
+// Graph runs terraform graph with the given options (see GraphType on the options for the type of graph) and parses
+// the DOT output into a dependency graph. This will fail the test if there is an error.
+func Graph(t testing.TestingT, options *Options) *DependencyGraph {
+	graph, err := GraphE(t, options)
+	require.NoError(t, err)
+	return graph
+}
+
+// GraphE runs terraform graph with the given options (see GraphType on the options for the type of graph) and parses
+// the DOT output into a dependency graph.
+func GraphE(t testing.TestingT, options *Options) (*DependencyGraph, error) {
+	args := []string{"graph"}
+	if options.GraphType != GraphTypeDefault {
+		args = append(args, fmt.Sprintf("-type=%s", options.GraphType))
+	}
+	if options.GraphType == GraphTypeApply {
+		if options.PlanFilePath == "" {
+			return nil, PlanFilePathRequired
+		}
+		args = append(args, fmt.Sprintf("-plan=%s", options.PlanFilePath))
+	}
+
+	out, err := RunTerraformCommandAndGetStdoutE(t, options, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDependencyGraph(out)
+}
+
+var (
+	// Matches a quoted DOT identifier, which may contain escaped quotes (e.g., in provider addresses).
+	dotIDPattern   = `"((?:[^"\\]|\\.)*)"`
+	dotEdgeRegexp  = regexp.MustCompile(`^\s*` + dotIDPattern + `\s*->\s*` + dotIDPattern)
+	dotNodeRegexp  = regexp.MustCompile(`^\s*` + dotIDPattern + `\s*(\[|;|$)`)
+	ignoredNodeIDs = map[string]bool{"root": true}
+)
+
+// ParseDependencyGraph parses the DOT output of terraform graph into a dependency graph. An edge from A to B in the
+// output means that A depends on B.
+func ParseDependencyGraph(dot string) (*DependencyGraph, error) {
+	if !strings.Contains(dot, "digraph") {
+		return nil, fmt.Errorf("terraform graph did not return a DOT digraph: %s", dot)
+	}
+
+	nodes := map[string]bool{}
+	edges := map[string]map[string]bool{}
+
+	scanner := bufio.NewScanner(strings.NewReader(dot))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := dotEdgeRegexp.FindStringSubmatch(line); match != nil {
+			from, to := normalizeGraphNode(match[1]), normalizeGraphNode(match[2])
+			if ignoredGraphNode(from) || ignoredGraphNode(to) || from == to {
+				continue
+			}
+			nodes[from], nodes[to] = true, true
+			if edges[from] == nil {
+				edges[from] = map[string]bool{}
+			}
+			edges[from][to] = true
+			continue
+		}
+		if match := dotNodeRegexp.FindStringSubmatch(line); match != nil {
+			if node := normalizeGraphNode(match[1]); !ignoredGraphNode(node) {
+				nodes[node] = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	graph := &DependencyGraph{Nodes: []string{}, Edges: map[string][]string{}}
+	for node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+		dependencies := []string{}
+		for dependency := range edges[node] {
+			dependencies = append(dependencies, dependency)
+		}
+		sort.Strings(dependencies)
+		graph.Edges[node] = dependencies
+	}
+	sort.Strings(graph.Nodes)
+	return graph, nil
+}
+
+// normalizeGraphNode turns the ID of a node in the output of terraform graph into an address.
+func normalizeGraphNode(id string) string {
+	id = strings.ReplaceAll(id, `\"`, `"`)
+	id = strings.TrimPrefix(id, "[root] ")
+	return strings.TrimSuffix(id, " (expand)")
+}
+
+// ignoredGraphNode returns true for the nodes that are not about a single object in the configuration. The close nodes
+// of modules and providers depend on everything that uses them, so they would otherwise show up as dependency cycles.
+func ignoredGraphNode(node string) bool {
+	return ignoredNodeIDs[node] || strings.HasPrefix(node, "meta.") || strings.HasSuffix(node, " (close)")
+}
+
+// HasNode returns true if the graph contains a node with the given address.
+func (graph *DependencyGraph) HasNode(address string) bool {
+	_, hasNode := graph.Edges[address]
+	return hasNode
+}
+
+// HasEdge returns true if the node with address from directly depends on the node with address to.
+func (graph *DependencyGraph) HasEdge(from string, to string) bool {
+	for _, dependency := range graph.Edges[from] {
+		if dependency == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Dependencies returns the addresses of the nodes the given node directly depends on, sorted.
+func (graph *DependencyGraph) Dependencies(address string) []string {
+	return append([]string{}, graph.Edges[address]...)
+}
+
+// Dependents returns the addresses of the nodes that directly depend on the given node, sorted.
+func (graph *DependencyGraph) Dependents(address string) []string {
+	out := []string{}
+	for _, node := range graph.Nodes {
+		if graph.HasEdge(node, address) {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// TransitiveDependencies returns the addresses of all the nodes the given node depends on, directly or indirectly (e.g.,
+// through a local or a module output), sorted. If the address is a module, this returns the dependencies of all the
+// nodes within it, except for the nodes within the module itself.
+func (graph *DependencyGraph) TransitiveDependencies(address string) []string {
+	visited := map[string]bool{}
+	var visit func(node string)
+	visit = func(node string) {
+		for _, dependency := range graph.Edges[node] {
+			if !visited[dependency] {
+				visited[dependency] = true
+				visit(dependency)
+			}
+		}
+	}
+	for _, node := range graph.nodesWithin(address) {
+		visit(node)
+	}
+
+	out := []string{}
+	for node := range visited {
+		if !isWithinAddress(node, address) {
+			out = append(out, node)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// DependsOn returns true if from depends on to, directly or indirectly. Either address can also be a module (e.g.,
+// module.network), in which case it stands for all the nodes within that module.
+func (graph *DependencyGraph) DependsOn(from string, to string) bool {
+	for _, dependency := range graph.TransitiveDependencies(from) {
+		if isWithinAddress(dependency, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodesWithin returns the node with the given address, plus all the nodes within it if it is a module.
+func (graph *DependencyGraph) nodesWithin(address string) []string {
+	out := []string{}
+	for _, node := range graph.Nodes {
+		if isWithinAddress(node, address) {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+func isWithinAddress(node string, address string) bool {
+	return node == address || strings.HasPrefix(node, address+".")
+}
+
+// FindCycle returns the addresses of the nodes in a dependency cycle (with the first node repeated at the end), or nil
+// if the graph has no cycles. Note that Terraform itself refuses to plan modules with cycles, so this is mostly useful
+// for graphs of modules that are being refactored.
+func (graph *DependencyGraph) FindCycle() []string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := map[string]int{}
+	stack := []string{}
+
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		state[node] = inProgress
+		stack = append(stack, node)
+		for _, dependency := range graph.Edges[node] {
+			switch state[dependency] {
+			case inProgress:
+				for i, stackNode := range stack {
+					if stackNode == dependency {
+						return append(append([]string{}, stack[i:]...), dependency)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dependency); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[node] = done
+		return nil
+	}
+
+	for _, node := range graph.Nodes {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// AssertNoEdge checks that the node with address from does not directly depend on the node with address to.
+func AssertNoEdge(t testing.TestingT, graph *DependencyGraph, from string, to string) bool {
+	return assert.Falsef(t, graph.HasEdge(from, to), "Expected %s not to depend directly on %s, but it does", from, to)
+}
+
+// RequireNoEdge checks that the node with address from does not directly depend on the node with address to, and halts
+// the test if it does.
+func RequireNoEdge(t testing.TestingT, graph *DependencyGraph, from string, to string) {
+	require.Falsef(t, graph.HasEdge(from, to), "Expected %s not to depend directly on %s, but it does", from, to)
+}
+
+// AssertDependsOn checks that from depends on to, directly or indirectly. See DependsOn for more info.
+func AssertDependsOn(t testing.TestingT, graph *DependencyGraph, from string, to string) bool {
+	return assert.Truef(t, graph.DependsOn(from, to), "Expected %s to depend on %s, but it only depends on %v", from, to, graph.TransitiveDependencies(from))
+}
+
+// RequireDependsOn checks that from depends on to, directly or indirectly, and halts the test if it doesn't. See
+// DependsOn for more info.
+func RequireDependsOn(t testing.TestingT, graph *DependencyGraph, from string, to string) {
+	require.Truef(t, graph.DependsOn(from, to), "Expected %s to depend on %s, but it only depends on %v", from, to, graph.TransitiveDependencies(from))
+}
+
+// AssertNoCycle checks that the graph has no dependency cycles, failing the test with the nodes of the first cycle found
+// if it does.
+func AssertNoCycle(t testing.TestingT, graph *DependencyGraph) bool {
+	cycle := graph.FindCycle()
+	return assert.Nilf(t, cycle, "Found dependency cycle: %s", strings.Join(cycle, " -> "))
+}
+
+// RequireNoCycle checks that the graph has no dependency cycles, failing and halting the test with the nodes of the
+// first cycle found if it does.
+func RequireNoCycle(t testing.TestingT, graph *DependencyGraph) {
+	cycle := graph.FindCycle()
+	require.Nilf(t, cycle, "Found dependency cycle: %s", strings.Join(cycle, " -> "))
+}
@@ -0,0 +1,159 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The output of terraform graph -type=plan (and of terraform graph before Terraform 1.7)
+const testPlanGraphDOT = `digraph {
+	compound = "true"
+	newrank = "true"
+	subgraph "root" {
+		"[root] aws_iam_role.this (expand)" [label = "aws_iam_role.this", shape = "box"]
+		"[root] aws_iam_role_policy_attachment.this (expand)" [label = "aws_iam_role_policy_attachment.this", shape = "box"]
+		"[root] local.role_name (expand)" [label = "local.role_name", shape = "note"]
+		"[root] module.network.aws_vpc.main (expand)" [label = "module.network.aws_vpc.main", shape = "box"]
+		"[root] module.network.output.vpc_id (expand)" [label = "module.network.output.vpc_id", shape = "note"]
+		"[root] provider[\"registry.terraform.io/hashicorp/aws\"]" [label = "provider[\"registry.terraform.io/hashicorp/aws\"]", shape = "diamond"]
+		"[root] var.name" [label = "var.name", shape = "note"]
+		"[root] aws_iam_role.this (expand)" -> "[root] local.role_name (expand)"
+		"[root] aws_iam_role.this (expand)" -> "[root] provider[\"registry.terraform.io/hashicorp/aws\"]"
+		"[root] aws_iam_role_policy_attachment.this (expand)" -> "[root] aws_iam_role.this (expand)"
+		"[root] local.role_name (expand)" -> "[root] module.network.output.vpc_id (expand)"
+		"[root] local.role_name (expand)" -> "[root] var.name"
+		"[root] meta.count-boundary (EachMode fixup)" -> "[root] aws_iam_role_policy_attachment.this (expand)"
+		"[root] module.network (close)" -> "[root] module.network.output.vpc_id (expand)"
+		"[root] module.network.aws_vpc.main (expand)" -> "[root] provider[\"registry.terraform.io/hashicorp/aws\"]"
+		"[root] module.network.output.vpc_id (expand)" -> "[root] module.network.aws_vpc.main (expand)"
+		"[root] provider[\"registry.terraform.io/hashicorp/aws\"] (close)" -> "[root] aws_iam_role_policy_attachment.this (expand)"
+		"[root] root" -> "[root] meta.count-boundary (EachMode fixup)"
+	}
+}
+`
+
+// The simplified output of terraform graph since Terraform 1.7
+const testSimplifiedGraphDOT = `digraph G {
+  rankdir = "RL";
+  node [shape = rect, fontname = "sans-serif"];
+  "aws_iam_role.this" [label="aws_iam_role.this"];
+  "aws_iam_role_policy_attachment.this" [label="aws_iam_role_policy_attachment.this"];
+  subgraph "cluster_module.network" {
+    label = "module.network"
+    fontname = "sans-serif"
+    "module.network.aws_vpc.main" [label="aws_vpc.main"];
+  }
+  "aws_iam_role.this" -> "module.network.aws_vpc.main";
+  "aws_iam_role_policy_attachment.this" -> "aws_iam_role.this";
+}
+`
+
+func TestParseDependencyGraphPlanType(t *testing.T) {
+	t.Parallel()
+
+	graph, err := ParseDependencyGraph(testPlanGraphDOT)
+	require.NoError(t, err)
+
+	provider := `provider["registry.terraform.io/hashicorp/aws"]`
+	assert.Equal(t, []string{
+		"aws_iam_role.this",
+		"aws_iam_role_policy_attachment.this",
+		"local.role_name",
+		"module.network.aws_vpc.main",
+		"module.network.output.vpc_id",
+		provider,
+		"var.name",
+	}, graph.Nodes)
+
+	assert.Equal(t, []string{"local.role_name", provider}, graph.Dependencies("aws_iam_role.this"))
+	assert.Equal(t, []string{"aws_iam_role_policy_attachment.this"}, graph.Dependents("aws_iam_role.this"))
+	assert.True(t, graph.HasEdge("aws_iam_role_policy_attachment.this", "aws_iam_role.this"))
+	assert.False(t, graph.HasEdge("aws_iam_role_policy_attachment.this", "local.role_name"))
+	assert.True(t, graph.HasNode("var.name"))
+	assert.False(t, graph.HasNode("root"))
+
+	assert.Equal(t, []string{
+		"local.role_name",
+		"module.network.aws_vpc.main",
+		"module.network.output.vpc_id",
+		provider,
+		"var.name",
+	}, graph.TransitiveDependencies("aws_iam_role.this"))
+	assert.Equal(t, []string{provider}, graph.TransitiveDependencies("module.network"))
+
+	assert.True(t, graph.DependsOn("aws_iam_role_policy_attachment.this", "aws_iam_role.this"))
+	assert.True(t, graph.DependsOn("aws_iam_role_policy_attachment.this", "module.network"))
+	assert.False(t, graph.DependsOn("module.network", "aws_iam_role.this"))
+	assert.Nil(t, graph.FindCycle())
+
+	AssertNoEdge(t, graph, "aws_iam_role_policy_attachment.this", "module.network.aws_vpc.main")
+	AssertDependsOn(t, graph, "aws_iam_role_policy_attachment.this", "module.network.aws_vpc.main")
+	AssertNoCycle(t, graph)
+
+	mock := &mockT{}
+	AssertNoEdge(mock, graph, "aws_iam_role_policy_attachment.this", "aws_iam_role.this")
+	assert.True(t, mock.Failed)
+}
+
+func TestParseDependencyGraphSimplified(t *testing.T) {
+	t.Parallel()
+
+	graph, err := ParseDependencyGraph(testSimplifiedGraphDOT)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"aws_iam_role.this", "aws_iam_role_policy_attachment.this", "module.network.aws_vpc.main"}, graph.Nodes)
+	assert.Equal(t, []string{}, graph.Dependencies("module.network.aws_vpc.main"))
+	assert.True(t, graph.DependsOn("aws_iam_role_policy_attachment.this", "module.network"))
+}
+
+func TestDependencyGraphFindCycle(t *testing.T) {
+	t.Parallel()
+
+	graph, err := ParseDependencyGraph(`digraph {
+		"a.a" -> "b.b"
+		"b.b" -> "c.c"
+		"c.c" -> "b.b"
+	}`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"b.b", "c.c", "b.b"}, graph.FindCycle())
+
+	mock := &mockT{}
+	AssertNoCycle(mock, graph)
+	assert.True(t, mock.Failed)
+}
+
+func TestParseDependencyGraphInvalidOutput(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseDependencyGraph("Error: no configuration files")
+	assert.Error(t, err)
+}
+
+func TestGraphApplyTypeRequiresPlanFile(t *testing.T) {
+	t.Parallel()
+
+	_, err := GraphE(t, &Options{TerraformDir: ".", GraphType: GraphTypeApply})
+	assert.Equal(t, PlanFilePathRequired, err)
+}
+
+func TestGraph(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-basic-configuration", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir: testFolder,
+		Vars:         map[string]interface{}{"cnt": 1},
+		GraphType:    GraphTypePlan,
+	}
+	Init(t, options)
+	graph := Graph(t, options)
+
+	AssertDependsOn(t, graph, "null_resource.test", "var.cnt")
+	AssertNoCycle(t, graph)
+}
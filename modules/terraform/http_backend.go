@@ -0,0 +1,378 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// The HTTP methods Terraform's http backend uses by default to lock and unlock a state.
+const (
+	httpBackendLockMethod   = "LOCK"
+	httpBackendUnlockMethod = "UNLOCK"
+)
+
+// httpBackendStatePrefix is the path under which the states are served, followed by the name of the state.
+const httpBackendStatePrefix = "/state/"
+
+// HTTPBackendServer is an in-process implementation of the protocol of Terraform's http backend
+// (https://developer.hashicorp.com/terraform/language/settings/backends/http), which allows to test the backend config,
+// state locking, and state migration of a module without any cloud resources. Each state is identified by a name, so a
+// single server can hold the states of several tests. The states are stored in memory, or in a directory if the server
+// was started with RunHTTPBackendServerWithStateDir.
+//
+// Note that the http backend does not support workspaces other than default, so use a different state name instead
+// (e.g., one per workspace you would have used).
+//
+// Example:
+//
+//	server := terraform.RunHTTPBackendServer(t)
+//	defer server.Close()
+//
+//	options := &terraform.Options{
+//		TerraformDir:  "../test/fixtures/terraform-http-backend",
+//		BackendConfig: server.BackendConfig("my-test"),
+//	}
+//	terraform.InitAndApply(t, options)
+//	assert.Contains(t, server.State("my-test"), "my-output-value")
+type HTTPBackendServer struct {
+	// The URL of the server (e.g., http://127.0.0.1:54321).
+	URL string
+
+	server   *httptest.Server
+	stateDir string
+
+	mutex    sync.Mutex
+	states   map[string][]byte
+	locks    map[string]*HTTPBackendLockInfo
+	failures map[string][]int
+	requests []HTTPBackendRequest
+}
+
+// HTTPBackendLockInfo is the information Terraform sends about a lock when it locks a state, and that it shows when a
+// state it tries to lock is already locked.
+type HTTPBackendLockInfo struct {
+	ID        string    `json:"ID"`
+	Operation string    `json:"Operation"`
+	Info      string    `json:"Info"`
+	Who       string    `json:"Who"`
+	Version   string    `json:"Version"`
+	Created   time.Time `json:"Created"`
+	Path      string    `json:"Path"`
+}
+
+// HTTPBackendRequest is a request the server received, which can be used to check how Terraform used the backend (e.g.,
+// that it locked the state before writing it).
+type HTTPBackendRequest struct {
+	// The HTTP method (e.g., GET, POST, DELETE, LOCK, or UNLOCK).
+	Method string
+	// The name of the state.
+	State string
+	// The HTTP status code the server responded with.
+	StatusCode int
+}
+
+func (request HTTPBackendRequest) String() string {
+	return fmt.Sprintf("%s %s: %d", request.Method, request.State, request.StatusCode)
+}
+
+// RunHTTPBackendServer starts an HTTP backend server that stores the states in memory. Make sure to call the Close()
+// method on the server when you're done!
+func RunHTTPBackendServer(t testing.TestingT) *HTTPBackendServer {
+	server, err := RunHTTPBackendServerE(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// RunHTTPBackendServerE starts an HTTP backend server that stores the states in memory. Make sure to call the Close()
+// method on the server when you're done!
+func RunHTTPBackendServerE(t testing.TestingT) (*HTTPBackendServer, error) {
+	return runHTTPBackendServer(t, "")
+}
+
+// RunHTTPBackendServerWithStateDir starts an HTTP backend server that stores each state in a file called NAME.tfstate
+// in the given directory, so the states outlive the server (e.g., to check that a state can be read back by a new
+// server). Make sure to call the Close() method on the server when you're done!
+func RunHTTPBackendServerWithStateDir(t testing.TestingT, stateDir string) *HTTPBackendServer {
+	server, err := RunHTTPBackendServerWithStateDirE(t, stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// RunHTTPBackendServerWithStateDirE starts an HTTP backend server that stores each state in a file called
+// NAME.tfstate in the given directory. Make sure to call the Close() method on the server when you're done!
+func RunHTTPBackendServerWithStateDirE(t testing.TestingT, stateDir string) (*HTTPBackendServer, error) {
+	if err := os.MkdirAll(stateDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return runHTTPBackendServer(t, stateDir)
+}
+
+func runHTTPBackendServer(t testing.TestingT, stateDir string) (*HTTPBackendServer, error) {
+	backend := &HTTPBackendServer{
+		stateDir: stateDir,
+		states:   map[string][]byte{},
+		locks:    map[string]*HTTPBackendLockInfo{},
+		failures: map[string][]int{},
+	}
+	backend.server = httptest.NewServer(http.HandlerFunc(backend.handle))
+	backend.URL = backend.server.URL
+
+	logger.Logf(t, "Started HTTP backend server at %s", backend.URL)
+	return backend, nil
+}
+
+// Close shuts down the server.
+func (backend *HTTPBackendServer) Close() {
+	backend.server.Close()
+}
+
+// Address returns the URL of the state with the given name, to use as the address of the http backend.
+func (backend *HTTPBackendServer) Address(name string) string {
+	return backend.URL + httpBackendStatePrefix + name
+}
+
+// BackendConfig returns the backend config for the state with the given name, to use as the BackendConfig of the
+// terraform options of a module with an (empty) http backend block.
+func (backend *HTTPBackendServer) BackendConfig(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"address":        backend.Address(name),
+		"lock_address":   backend.Address(name),
+		"unlock_address": backend.Address(name),
+		"lock_method":    httpBackendLockMethod,
+		"unlock_method":  httpBackendUnlockMethod,
+	}
+}
+
+// State returns the contents of the state with the given name, or an empty string if there is no such state.
+func (backend *HTTPBackendServer) State(name string) string {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	state, _ := backend.readState(name)
+	return string(state)
+}
+
+// HasState returns true if there is a state with the given name.
+func (backend *HTTPBackendServer) HasState(name string) bool {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	_, hasState := backend.readState(name)
+	return hasState
+}
+
+// SetState sets the contents of the state with the given name (e.g., to test how a module handles an existing state).
+func (backend *HTTPBackendServer) SetState(t testing.TestingT, name string, state string) {
+	if err := backend.SetStateE(name, state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SetStateE sets the contents of the state with the given name.
+func (backend *HTTPBackendServer) SetStateE(name string, state string) error {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	return backend.writeState(name, []byte(state))
+}
+
+// LockState locks the state with the given name on behalf of someone else, so that Terraform fails to lock it (or
+// waits for up to the LockTimeout in the terraform options). Returns the info of the lock, whose ID can be used with
+// terraform force-unlock.
+func (backend *HTTPBackendServer) LockState(name string, who string) *HTTPBackendLockInfo {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	lock := &HTTPBackendLockInfo{
+		ID:        fmt.Sprintf("terratest-%d", time.Now().UnixNano()),
+		Operation: "OperationTypeTerratest",
+		Who:       who,
+		Created:   time.Now().UTC(),
+		Path:      name,
+	}
+	backend.locks[name] = lock
+	return lock
+}
+
+// UnlockState releases the lock on the state with the given name, if any, no matter who holds it.
+func (backend *HTTPBackendServer) UnlockState(name string) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	delete(backend.locks, name)
+}
+
+// Lock returns the info of the lock on the state with the given name, or nil if the state is not locked.
+func (backend *HTTPBackendServer) Lock(name string) *HTTPBackendLockInfo {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	return backend.locks[name]
+}
+
+// FailNextRequests makes the server respond to the next count requests with the given HTTP method (e.g., POST to fail
+// writing the state, or LOCK to fail locking it) with the given status code instead of handling them. Note that
+// Terraform retries the requests that fail with a 5xx status code (twice by default, see retry_max).
+func (backend *HTTPBackendServer) FailNextRequests(method string, count int, statusCode int) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	for i := 0; i < count; i++ {
+		backend.failures[method] = append(backend.failures[method], statusCode)
+	}
+}
+
+// Requests returns all the requests the server received so far, in order.
+func (backend *HTTPBackendServer) Requests() []HTTPBackendRequest {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	return append([]HTTPBackendRequest{}, backend.requests...)
+}
+
+func (backend *HTTPBackendServer) handle(w http.ResponseWriter, r *http.Request) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, httpBackendStatePrefix)
+	statusCode, body := backend.handleRequest(r, name)
+	backend.requests = append(backend.requests, HTTPBackendRequest{Method: r.Method, State: name, StatusCode: statusCode})
+
+	if body != nil {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// handleRequest handles a request for the state with the given name, and returns the status code and body of the
+// response. The caller must hold the mutex.
+func (backend *HTTPBackendServer) handleRequest(r *http.Request, name string) (int, []byte) {
+	if failures := backend.failures[r.Method]; len(failures) > 0 {
+		backend.failures[r.Method] = failures[1:]
+		return failures[0], []byte(http.StatusText(failures[0]))
+	}
+	if !strings.HasPrefix(r.URL.Path, httpBackendStatePrefix) || name == "" || strings.ContainsAny(name, `/\`) {
+		return http.StatusNotFound, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, []byte(err.Error())
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		state, hasState := backend.readState(name)
+		if !hasState {
+			return http.StatusNotFound, nil
+		}
+		return http.StatusOK, state
+
+	case http.MethodPost:
+		if lock := backend.locks[name]; lock != nil && lock.ID != r.URL.Query().Get("ID") {
+			return backend.lockedResponse(lock)
+		}
+		if err := backend.writeState(name, body); err != nil {
+			return http.StatusInternalServerError, []byte(err.Error())
+		}
+		return http.StatusOK, nil
+
+	case http.MethodDelete:
+		if lock := backend.locks[name]; lock != nil {
+			return backend.lockedResponse(lock)
+		}
+		if err := backend.deleteState(name); err != nil {
+			return http.StatusInternalServerError, []byte(err.Error())
+		}
+		return http.StatusOK, nil
+
+	case httpBackendLockMethod:
+		if lock := backend.locks[name]; lock != nil {
+			return backend.lockedResponse(lock)
+		}
+		lock := &HTTPBackendLockInfo{}
+		if err := json.Unmarshal(body, lock); err != nil {
+			return http.StatusBadRequest, []byte(err.Error())
+		}
+		backend.locks[name] = lock
+		return http.StatusOK, nil
+
+	case httpBackendUnlockMethod:
+		lock := backend.locks[name]
+		if lock == nil {
+			return http.StatusOK, nil
+		}
+		// terraform force-unlock sends no lock info, in which case the lock is released no matter who holds it
+		if len(body) > 0 {
+			requested := &HTTPBackendLockInfo{}
+			if err := json.Unmarshal(body, requested); err != nil {
+				return http.StatusBadRequest, []byte(err.Error())
+			}
+			if requested.ID != lock.ID {
+				return backend.lockedResponse(lock)
+			}
+		}
+		delete(backend.locks, name)
+		return http.StatusOK, nil
+	}
+
+	return http.StatusMethodNotAllowed, nil
+}
+
+// lockedResponse returns the response Terraform expects when a state is locked by someone else: the 423 Locked status
+// code with the info of the lock.
+func (backend *HTTPBackendServer) lockedResponse(lock *HTTPBackendLockInfo) (int, []byte) {
+	body, err := json.Marshal(lock)
+	if err != nil {
+		return http.StatusInternalServerError, []byte(err.Error())
+	}
+	return http.StatusLocked, body
+}
+
+func (backend *HTTPBackendServer) statePath(name string) string {
+	return filepath.Join(backend.stateDir, name+".tfstate")
+}
+
+func (backend *HTTPBackendServer) readState(name string) ([]byte, bool) {
+	if backend.stateDir == "" {
+		state, hasState := backend.states[name]
+		return state, hasState
+	}
+	state, err := os.ReadFile(backend.statePath(name))
+	return state, err == nil
+}
+
+func (backend *HTTPBackendServer) writeState(name string, state []byte) error {
+	if backend.stateDir == "" {
+		backend.states[name] = state
+		return nil
+	}
+	return os.WriteFile(backend.statePath(name), state, 0644)
+}
+
+func (backend *HTTPBackendServer) deleteState(name string) error {
+	if backend.stateDir == "" {
+		delete(backend.states, name)
+		return nil
+	}
+	if err := os.Remove(backend.statePath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
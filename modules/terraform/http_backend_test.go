@@ -0,0 +1,193 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doHTTPBackendRequest(t *testing.T, method string, url string, body string) (int, string) {
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response.StatusCode, string(responseBody)
+}
+
+func TestHTTPBackendServerProtocol(t *testing.T) {
+	t.Parallel()
+
+	server := RunHTTPBackendServer(t)
+	defer server.Close()
+	address := server.Address("test")
+
+	status, _ := doHTTPBackendRequest(t, http.MethodGet, address, "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = doHTTPBackendRequest(t, httpBackendLockMethod, address, `{"ID": "lock-1", "Who": "me"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "me", server.Lock("test").Who)
+
+	status, body := doHTTPBackendRequest(t, httpBackendLockMethod, address, `{"ID": "lock-2", "Who": "someone-else"}`)
+	assert.Equal(t, http.StatusLocked, status)
+	lock := HTTPBackendLockInfo{}
+	require.NoError(t, json.Unmarshal([]byte(body), &lock))
+	assert.Equal(t, "lock-1", lock.ID)
+
+	status, _ = doHTTPBackendRequest(t, http.MethodPost, address+"?ID=lock-2", `{"serial": 1}`)
+	assert.Equal(t, http.StatusLocked, status)
+	status, _ = doHTTPBackendRequest(t, http.MethodPost, address+"?ID=lock-1", `{"serial": 1}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, body = doHTTPBackendRequest(t, http.MethodGet, address, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"serial": 1}`, body)
+	assert.Equal(t, `{"serial": 1}`, server.State("test"))
+
+	status, _ = doHTTPBackendRequest(t, httpBackendUnlockMethod, address, `{"ID": "lock-2"}`)
+	assert.Equal(t, http.StatusLocked, status)
+	status, _ = doHTTPBackendRequest(t, httpBackendUnlockMethod, address, `{"ID": "lock-1"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, server.Lock("test"))
+
+	status, _ = doHTTPBackendRequest(t, http.MethodDelete, address, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, server.HasState("test"))
+
+	assert.Equal(t, []HTTPBackendRequest{
+		{Method: http.MethodGet, State: "test", StatusCode: http.StatusNotFound},
+		{Method: httpBackendLockMethod, State: "test", StatusCode: http.StatusOK},
+		{Method: httpBackendLockMethod, State: "test", StatusCode: http.StatusLocked},
+		{Method: http.MethodPost, State: "test", StatusCode: http.StatusLocked},
+		{Method: http.MethodPost, State: "test", StatusCode: http.StatusOK},
+		{Method: http.MethodGet, State: "test", StatusCode: http.StatusOK},
+		{Method: httpBackendUnlockMethod, State: "test", StatusCode: http.StatusLocked},
+		{Method: httpBackendUnlockMethod, State: "test", StatusCode: http.StatusOK},
+		{Method: http.MethodDelete, State: "test", StatusCode: http.StatusOK},
+	}, server.Requests())
+}
+
+func TestHTTPBackendServerLockContentionAndFailures(t *testing.T) {
+	t.Parallel()
+
+	server := RunHTTPBackendServer(t)
+	defer server.Close()
+	address := server.Address("test")
+
+	lock := server.LockState("test", "another-test")
+	status, _ := doHTTPBackendRequest(t, httpBackendLockMethod, address, `{"ID": "lock-1"}`)
+	assert.Equal(t, http.StatusLocked, status)
+
+	// An unlock without lock info, as sent by terraform force-unlock, releases the lock no matter who holds it
+	status, _ = doHTTPBackendRequest(t, httpBackendUnlockMethod, address, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, server.Lock("test"))
+	assert.NotEmpty(t, lock.ID)
+
+	server.FailNextRequests(http.MethodPost, 2, http.StatusServiceUnavailable)
+	for i := 0; i < 2; i++ {
+		status, _ = doHTTPBackendRequest(t, http.MethodPost, address, `{}`)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	}
+	assert.False(t, server.HasState("test"))
+	status, _ = doHTTPBackendRequest(t, http.MethodPost, address, `{}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, server.HasState("test"))
+}
+
+func TestHTTPBackendServerWithStateDir(t *testing.T) {
+	t.Parallel()
+
+	stateDir := t.TempDir()
+
+	server := RunHTTPBackendServerWithStateDir(t, stateDir)
+	server.SetState(t, "test", `{"serial": 3}`)
+	server.Close()
+
+	server = RunHTTPBackendServerWithStateDir(t, stateDir)
+	defer server.Close()
+	assert.FileExists(t, server.statePath("test"))
+	assert.Equal(t, `{"serial": 3}`, server.State("test"))
+}
+
+func TestInitAndApplyWithHTTPBackend(t *testing.T) {
+	t.Parallel()
+
+	server := RunHTTPBackendServer(t)
+	defer server.Close()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-http-backend", t.Name())
+	require.NoError(t, err)
+
+	data := fmt.Sprintf("data-for-test-%s", random.UniqueId())
+	options := &Options{
+		TerraformDir:  testFolder,
+		Vars:          map[string]interface{}{"foo": data},
+		BackendConfig: server.BackendConfig("test"),
+		Lock:          true,
+	}
+	InitAndApply(t, options)
+
+	assert.Contains(t, server.State("test"), data)
+	assert.Nil(t, server.Lock("test"))
+	assert.Contains(t, server.Requests(), HTTPBackendRequest{Method: httpBackendLockMethod, State: "test", StatusCode: http.StatusOK})
+}
+
+func TestApplyWithHTTPBackendLockedState(t *testing.T) {
+	t.Parallel()
+
+	server := RunHTTPBackendServer(t)
+	defer server.Close()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-http-backend", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir:  testFolder,
+		Vars:          map[string]interface{}{"foo": "bar"},
+		BackendConfig: server.BackendConfig("test"),
+		Lock:          true,
+		LockTimeout:   "1s",
+	}
+	Init(t, options)
+
+	server.LockState("test", "another-test")
+	_, err = ApplyE(t, options)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "another-test")
+	assert.False(t, server.HasState("test"))
+}
+
+func TestInitMigrateStateToHTTPBackend(t *testing.T) {
+	t.Parallel()
+
+	server := RunHTTPBackendServer(t)
+	defer server.Close()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-http-backend", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir:  testFolder,
+		Vars:          map[string]interface{}{"foo": "bar"},
+		BackendConfig: server.BackendConfig("before"),
+	}
+	InitAndApply(t, options)
+
+	options.BackendConfig = server.BackendConfig("after")
+	options.MigrateState = true
+	Init(t, options)
+
+	assert.Contains(t, server.State("after"), `"bar"`)
+}
@@ -34,5 +34,14 @@ func InitE(t testing.TestingT, options *Options) (string, error) {
 
 	args = append(args, FormatTerraformBackendConfigAsArgs(options.BackendConfig)...)
 	args = append(args, FormatTerraformPluginDirAsArgs(options.PluginDir)...)
-	return RunTerraformCommandE(t, options, args...)
+
+	cache, err := pluginCacheForOptions(options)
+	if err != nil {
+		return "", err
+	}
+	if cache == nil {
+		return RunTerraformCommandE(t, options, args...)
+	}
+
+	return initWithPluginCacheE(t, options, cache, args...)
 }
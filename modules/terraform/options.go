@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"context"
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
@@ -70,7 +71,37 @@ type Options struct {
 	Parallelism              int                    // Set the parallelism setting for Terraform
 	PlanFilePath             string                 // The path to output a plan file to (for the plan command) or read one from (for the apply command)
 	PluginDir                string                 // The path of downloaded plugins to pass to the terraform init command (-plugin-dir)
+	PluginCache              *PluginCache           `json:"-"` // The plugin cache to use for terraform init. Defaults to SharedPluginCache. See PluginCache for more info.
+	DisablePluginCache       bool                   // Do not use the shared plugin cache for terraform init
 	SetVarsAfterVarFiles     bool                   // Pass -var options after -var-file options to Terraform commands
+	TestFilters              []string               // The test files to run with terraform test (-filter). Runs all test files if empty.
+	TestDirectory            string                 // The directory with the test files for terraform test (-test-directory). Defaults to tests.
+	GraphType                GraphType              // The type of graph terraform graph outputs (-type). See GraphType for more info.
+	EvalAgainstPlan          bool                   // Evaluate expressions with terraform console against a plan (-plan) instead of the state
+
+	// If set, Vars are written to a temporary .auto.tfvars.json file in TerraformDir for the duration of each command,
+	// instead of being passed with -var. This supports null values and keeps sensitive values off the command line. Note
+	// that Terraform gives -var-file options precedence over .auto.tfvars.json files, so VarFiles override Vars in this
+	// mode, and SetVarsAfterVarFiles has no effect.
+	WriteVarsToFile bool
+
+	// If set, running Terraform commands are interrupted (SIGINT, then SIGKILL after shell.DefaultGracePeriod) when the
+	// context is cancelled. Note that once the context is cancelled, every later command using these options fails
+	// immediately, including a deferred Destroy, so prefer Timeout to bound individual commands.
+	Context context.Context `json:"-"`
+	// The maximum amount of time each individual Terraform command is allowed to run before it is interrupted. As the
+	// timeout applies per command, a deferred Destroy still gets to run after a stuck Apply times out.
+	Timeout time.Duration
+
+	// If set, plan, apply, and destroy are run with -json, so that their output is Terraform's machine readable UI
+	// output, which can be parsed with ParseUIEvents. The human readable message of each event is logged instead of the
+	// raw JSON, and errors include the error diagnostics reported by Terraform.
+	JSONOutput bool
+	// If set (together with JSONOutput), this function is called with each UI event as soon as Terraform emits it, e.g.
+	// to track the progress of a long running apply. The calls for the events of a command are made one at a time, even
+	// though the events come from both stdout and stderr, so the function does not need to be safe for concurrent calls
+	// unless the same options are used to run several commands at the same time.
+	UIEventHandler func(event UIEvent) `json:"-"`
 }
 
 // Clone makes a deep copy of most fields on the Options object and returns it.
@@ -1,7 +1,9 @@
 package terraform
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/assert"
@@ -41,3 +43,19 @@ func TestOptionsCloneDeepClonesVars(t *testing.T) {
 	assert.Equal(t, unique, original.Vars["unique"])
 	assert.Equal(t, unique, copied.Vars["original"])
 }
+
+func TestOptionsClonePreservesContextAndTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	original := Options{
+		Context: ctx,
+		Timeout: 5 * time.Minute,
+	}
+	copied, err := original.Clone()
+	require.NoError(t, err)
+	assert.Equal(t, ctx, copied.Context)
+	assert.Equal(t, 5*time.Minute, copied.Timeout)
+}
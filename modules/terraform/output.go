@@ -105,12 +105,7 @@ func parseMap(m map[string]interface{}) (map[string]interface{}, error) {
 			}
 			result[k] = nestedList
 		case float64:
-			testInt, err := strconv.ParseInt((fmt.Sprintf("%v", vt)), 10, 0)
-			if err == nil {
-				result[k] = int(testInt)
-			} else {
-				result[k] = vt
-			}
+			result[k] = parseNumber(vt)
 		default:
 			result[k] = vt
 		}
@@ -120,6 +115,47 @@ func parseMap(m map[string]interface{}) (map[string]interface{}, error) {
 	return result, nil
 }
 
+// parseNumber converts a number to an int if it has no fractional part, in the same way as parseMap.
+func parseNumber(n float64) interface{} {
+	testInt, err := strconv.ParseInt((fmt.Sprintf("%v", n)), 10, 0)
+	if err == nil {
+		return int(testInt)
+	}
+	return n
+}
+
+// parseValue parses the types of a value of any type in the same way as parseMap: maps and lists are parsed element by
+// element, and numbers without a fractional part are converted to ints. Unlike parseMap, lists nested in maps may hold
+// values of any type, not only maps.
+func parseValue(v interface{}) (interface{}, error) {
+	switch vt := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(vt))
+		for key, element := range vt {
+			parsed, err := parseValue(element)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = parsed
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(vt))
+		for _, element := range vt {
+			parsed, err := parseValue(element)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, parsed)
+		}
+		return result, nil
+	case float64:
+		return parseNumber(vt), nil
+	default:
+		return vt, nil
+	}
+}
+
 // OutputMapOfObjects calls terraform output for the given variable and returns its value as a map of lists/maps.
 // If the output value is not a map of lists/maps, then it fails the test.
 func OutputMapOfObjects(t testing.TestingT, options *Options, key string) map[string]interface{} {
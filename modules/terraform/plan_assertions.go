@@ -0,0 +1,299 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ChangeAction is the high level action Terraform plans to take on a resource. Unlike the raw tfjson.Actions list, a
+// replacement is represented as a single action instead of a combination of delete and create.
+type ChangeAction string
+
+const (
+	ChangeActionNoOp    ChangeAction = "no-op"
+	ChangeActionCreate  ChangeAction = "create"
+	ChangeActionRead    ChangeAction = "read"
+	ChangeActionUpdate  ChangeAction = "update"
+	ChangeActionDelete  ChangeAction = "delete"
+	ChangeActionReplace ChangeAction = "replace"
+)
+
+// GetChangeAction returns the high level action that Terraform plans to take for the given resource change.
+func GetChangeAction(change *tfjson.ResourceChange) ChangeAction {
+	if change.Change == nil {
+		return ChangeActionNoOp
+	}
+	actions := change.Change.Actions
+	switch {
+	case actions.Replace():
+		return ChangeActionReplace
+	case actions.Create():
+		return ChangeActionCreate
+	case actions.Read():
+		return ChangeActionRead
+	case actions.Update():
+		return ChangeActionUpdate
+	case actions.Delete():
+		return ChangeActionDelete
+	default:
+		return ChangeActionNoOp
+	}
+}
+
+// PlanResourceSelection is a set of resource changes from a plan, narrowed down by the filter methods (e.g., OfType,
+// WithAction), on which assertions can be made. Selections are immutable: every filter returns a new selection.
+//
+// Example:
+//
+//	plan := terraform.InitAndPlanAndShowWithStruct(t, options)
+//	plan.Resources().OfType("aws_s3_bucket").WithAction(terraform.ChangeActionCreate).HasCount(t, 2)
+//	plan.Resources().OfType("aws_s3_bucket").Attribute("versioning.0.enabled").Equals(t, true)
+type PlanResourceSelection struct {
+	changes []*tfjson.ResourceChange
+	filters []string
+}
+
+// Resources returns a selection of all the resource changes in the plan, sorted by address.
+func (plan *PlanStruct) Resources() *PlanResourceSelection {
+	changes := make([]*tfjson.ResourceChange, 0, len(plan.ResourceChangesMap))
+	for _, change := range plan.ResourceChangesMap {
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Address < changes[j].Address })
+	return &PlanResourceSelection{changes: changes}
+}
+
+// filter returns a new selection with only the resource changes that match the given function.
+func (selection *PlanResourceSelection) filter(description string, matches func(change *tfjson.ResourceChange) bool) *PlanResourceSelection {
+	out := &PlanResourceSelection{filters: append(append([]string{}, selection.filters...), description)}
+	for _, change := range selection.changes {
+		if matches(change) {
+			out.changes = append(out.changes, change)
+		}
+	}
+	return out
+}
+
+// OfType narrows the selection down to resources of the given type (e.g., aws_s3_bucket).
+func (selection *PlanResourceSelection) OfType(resourceType string) *PlanResourceSelection {
+	return selection.filter(fmt.Sprintf("of type %q", resourceType), func(change *tfjson.ResourceChange) bool {
+		return change.Type == resourceType
+	})
+}
+
+// WithAddress narrows the selection down to the resource with the given full address (e.g.,
+// module.foo.aws_s3_bucket.logs[0]).
+func (selection *PlanResourceSelection) WithAddress(address string) *PlanResourceSelection {
+	return selection.filter(fmt.Sprintf("with address %q", address), func(change *tfjson.ResourceChange) bool {
+		return change.Address == address
+	})
+}
+
+// InModule narrows the selection down to resources defined directly in the module with the given address (e.g.,
+// module.foo). Use an empty string to select resources in the root module.
+func (selection *PlanResourceSelection) InModule(moduleAddress string) *PlanResourceSelection {
+	return selection.filter(fmt.Sprintf("in module %q", moduleAddress), func(change *tfjson.ResourceChange) bool {
+		return change.ModuleAddress == moduleAddress
+	})
+}
+
+// WithAction narrows the selection down to resources that Terraform plans to take the given action on.
+func (selection *PlanResourceSelection) WithAction(action ChangeAction) *PlanResourceSelection {
+	return selection.filter(fmt.Sprintf("with action %q", action), func(change *tfjson.ResourceChange) bool {
+		return GetChangeAction(change) == action
+	})
+}
+
+// Changes returns the raw resource changes in the selection, sorted by address.
+func (selection *PlanResourceSelection) Changes() []*tfjson.ResourceChange {
+	return selection.changes
+}
+
+// Addresses returns the addresses of the resources in the selection, sorted.
+func (selection *PlanResourceSelection) Addresses() []string {
+	addresses := make([]string, 0, len(selection.changes))
+	for _, change := range selection.changes {
+		addresses = append(addresses, change.Address)
+	}
+	return addresses
+}
+
+// Count returns the number of resources in the selection.
+func (selection *PlanResourceSelection) Count() int {
+	return len(selection.changes)
+}
+
+// CountByAction returns the number of resources in the selection for each planned action.
+func (selection *PlanResourceSelection) CountByAction() map[ChangeAction]int {
+	out := map[ChangeAction]int{}
+	for _, change := range selection.changes {
+		out[GetChangeAction(change)]++
+	}
+	return out
+}
+
+// String returns a human readable description of the filters applied to this selection, for use in failure messages.
+func (selection *PlanResourceSelection) String() string {
+	if len(selection.filters) == 0 {
+		return "resources"
+	}
+	return "resources " + strings.Join(selection.filters, " ")
+}
+
+// HasCount asserts that the selection contains exactly the expected number of resources.
+func (selection *PlanResourceSelection) HasCount(t testing.TestingT, expected int) bool {
+	return assert.Equalf(t, expected, selection.Count(), "Unexpected number of %s. Found: %v", selection, selection.Addresses())
+}
+
+// Exists asserts that the selection contains at least one resource.
+func (selection *PlanResourceSelection) Exists(t testing.TestingT) bool {
+	return assert.NotEmptyf(t, selection.changes, "Expected plan to contain %s, but found none", selection)
+}
+
+// IsEmpty asserts that the selection does not contain any resources.
+func (selection *PlanResourceSelection) IsEmpty(t testing.TestingT) bool {
+	return assert.Emptyf(t, selection.Addresses(), "Expected plan to contain no %s", selection)
+}
+
+// Attribute selects the planned value (i.e., the value after apply) of the given attribute on every resource in the
+// selection. The attribute can be a path into nested blocks and lists, using a dot as the separator and numbers as list
+// indexes (e.g., versioning.0.enabled).
+func (selection *PlanResourceSelection) Attribute(attributePath string) *PlanAttributeSelection {
+	return &PlanAttributeSelection{resources: selection, path: attributePath}
+}
+
+// PlanAttributeSelection is an attribute of every resource in a PlanResourceSelection, on which assertions can be made.
+type PlanAttributeSelection struct {
+	resources *PlanResourceSelection
+	path      string
+}
+
+// Values returns a map that maps the address of each resource in the selection to the planned value of the attribute.
+// Resources where the attribute is not set or not known until after apply are omitted.
+func (attribute *PlanAttributeSelection) Values() map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, change := range attribute.resources.changes {
+		if value, err := attribute.plannedValue(change); err == nil {
+			out[change.Address] = value
+		}
+	}
+	return out
+}
+
+// plannedValue returns the value of the attribute in the planned (after) values of the given resource change.
+func (attribute *PlanAttributeSelection) plannedValue(change *tfjson.ResourceChange) (interface{}, error) {
+	after := map[string]interface{}{}
+	if change.Change != nil {
+		if typedAfter, isMap := change.Change.After.(map[string]interface{}); isMap {
+			after = typedAfter
+		}
+	}
+	return getAttributeByPath(after, change.Address, attribute.path)
+}
+
+// isKnownAfterApply returns true if Terraform reports that the value of the attribute on the given resource change
+// will only be known after apply.
+func (attribute *PlanAttributeSelection) isKnownAfterApply(change *tfjson.ResourceChange) bool {
+	if change.Change == nil {
+		return false
+	}
+	afterUnknown, isMap := change.Change.AfterUnknown.(map[string]interface{})
+	if !isMap {
+		return false
+	}
+	unknown, err := getAttributeByPath(afterUnknown, change.Address, attribute.path)
+	return err == nil && unknown == true
+}
+
+// Equals asserts that the planned value of the attribute equals the expected value on every resource in the
+// selection. Numbers in the plan are decoded as float64, so the comparison is done with assert.EqualValues to allow
+// passing in ints as the expected value. This also fails if the selection is empty.
+func (attribute *PlanAttributeSelection) Equals(t testing.TestingT, expected interface{}) bool {
+	if !attribute.resources.Exists(t) {
+		return false
+	}
+	ok := true
+	for _, change := range attribute.resources.changes {
+		actual, err := attribute.plannedValue(change)
+		if err != nil {
+			if attribute.isKnownAfterApply(change) {
+				err = fmt.Errorf("attribute %q of resource %q is not known until after apply", attribute.path, change.Address)
+			}
+			ok = assert.NoError(t, err) && ok
+			continue
+		}
+		ok = assert.EqualValuesf(t, expected, actual, "Unexpected planned value for attribute %q of resource %q", attribute.path, change.Address) && ok
+	}
+	return ok
+}
+
+// Exists asserts that the attribute is set (or will be known after apply) on every resource in the selection. This
+// also fails if the selection is empty.
+func (attribute *PlanAttributeSelection) Exists(t testing.TestingT) bool {
+	if !attribute.resources.Exists(t) {
+		return false
+	}
+	ok := true
+	for _, change := range attribute.resources.changes {
+		if attribute.isKnownAfterApply(change) {
+			continue
+		}
+		_, err := attribute.plannedValue(change)
+		ok = assert.NoError(t, err) && ok
+	}
+	return ok
+}
+
+// IsKnownAfterApply asserts that the value of the attribute will only be known after apply on every resource in the
+// selection (e.g., a generated ID). This also fails if the selection is empty.
+func (attribute *PlanAttributeSelection) IsKnownAfterApply(t testing.TestingT) bool {
+	if !attribute.resources.Exists(t) {
+		return false
+	}
+	ok := true
+	for _, change := range attribute.resources.changes {
+		ok = assert.Truef(t, attribute.isKnownAfterApply(change), "Expected attribute %q of resource %q to be known after apply", attribute.path, change.Address) && ok
+	}
+	return ok
+}
+
+// AssertPlanNoDestroys checks that the plan does not delete or replace any resources, failing the test if it does.
+func AssertPlanNoDestroys(t testing.TestingT, plan *PlanStruct) {
+	destroyed := planDestroyedAddresses(plan)
+	assert.Emptyf(t, destroyed, "Expected plan to not destroy any resources")
+}
+
+// RequirePlanNoDestroys checks that the plan does not delete or replace any resources, failing and halting the test if
+// it does.
+func RequirePlanNoDestroys(t testing.TestingT, plan *PlanStruct) {
+	destroyed := planDestroyedAddresses(plan)
+	require.Emptyf(t, destroyed, "Expected plan to not destroy any resources")
+}
+
+// AssertPlanNoReplaces checks that the plan does not replace any resources, failing the test if it does.
+func AssertPlanNoReplaces(t testing.TestingT, plan *PlanStruct) {
+	plan.Resources().WithAction(ChangeActionReplace).IsEmpty(t)
+}
+
+// RequirePlanNoReplaces checks that the plan does not replace any resources, failing and halting the test if it does.
+func RequirePlanNoReplaces(t testing.TestingT, plan *PlanStruct) {
+	if !plan.Resources().WithAction(ChangeActionReplace).IsEmpty(t) {
+		t.FailNow()
+	}
+}
+
+// planDestroyedAddresses returns the addresses of all resources that the plan deletes, either outright or as part of a
+// replacement.
+func planDestroyedAddresses(plan *PlanStruct) []string {
+	deleted := plan.Resources().WithAction(ChangeActionDelete).Addresses()
+	replaced := plan.Resources().WithAction(ChangeActionReplace).Addresses()
+	out := append(deleted, replaced...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,220 @@
+package terraform
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockT is used to test that assertion helpers fail the test under certain circumstances, and with which messages.
+type mockT struct {
+	Failed   bool
+	Messages []string
+}
+
+func (t *mockT) Fail() { t.Failed = true }
+
+func (t *mockT) FailNow() { t.Failed = true }
+
+func (t *mockT) Error(args ...interface{}) {
+	t.Failed = true
+	t.Messages = append(t.Messages, fmt.Sprint(args...))
+}
+
+func (t *mockT) Errorf(format string, args ...interface{}) {
+	t.Failed = true
+	t.Messages = append(t.Messages, fmt.Sprintf(format, args...))
+}
+
+func (t *mockT) Fatal(args ...interface{}) {
+	t.Failed = true
+	t.Messages = append(t.Messages, fmt.Sprint(args...))
+}
+
+func (t *mockT) Fatalf(format string, args ...interface{}) {
+	t.Failed = true
+	t.Messages = append(t.Messages, fmt.Sprintf(format, args...))
+}
+
+func (t *mockT) Name() string {
+	return "mockT"
+}
+
+const testPlanWithChangesJSON = `{
+  "format_version": "1.1",
+  "terraform_version": "1.5.7",
+  "resource_changes": [
+    {
+      "address": "aws_s3_bucket.new",
+      "mode": "managed",
+      "type": "aws_s3_bucket",
+      "name": "new",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after": {"bucket": "new-bucket", "versioning": [{"enabled": true}], "tags": {"Team": "infra"}},
+        "after_unknown": {"arn": true, "id": true, "versioning": [{}], "tags": {}}
+      }
+    },
+    {
+      "address": "module.logs.aws_s3_bucket.logs",
+      "module_address": "module.logs",
+      "mode": "managed",
+      "type": "aws_s3_bucket",
+      "name": "logs",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["update"],
+        "before": {"bucket": "logs", "versioning": [{"enabled": false}], "tags": {}},
+        "after": {"bucket": "logs", "versioning": [{"enabled": true}], "tags": {}},
+        "after_unknown": {}
+      }
+    },
+    {
+      "address": "aws_instance.web",
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "web",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"ami": "ami-1", "instance_type": "t3.micro"},
+        "after": {"ami": "ami-2", "instance_type": "t3.micro"},
+        "after_unknown": {"id": true}
+      }
+    },
+    {
+      "address": "aws_iam_role.old",
+      "mode": "managed",
+      "type": "aws_iam_role",
+      "name": "old",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["delete"],
+        "before": {"name": "old"},
+        "after": null,
+        "after_unknown": {}
+      }
+    },
+    {
+      "address": "aws_iam_role.same",
+      "mode": "managed",
+      "type": "aws_iam_role",
+      "name": "same",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["no-op"],
+        "before": {"name": "same"},
+        "after": {"name": "same"},
+        "after_unknown": {}
+      }
+    }
+  ]
+}`
+
+func TestPlanResourcesSelection(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlanJSON(testPlanWithChangesJSON)
+	require.NoError(t, err)
+
+	buckets := plan.Resources().OfType("aws_s3_bucket")
+	buckets.HasCount(t, 2)
+	assert.Equal(t, []string{"aws_s3_bucket.new", "module.logs.aws_s3_bucket.logs"}, buckets.Addresses())
+	buckets.WithAction(ChangeActionCreate).HasCount(t, 1)
+	buckets.InModule("module.logs").WithAction(ChangeActionUpdate).Exists(t)
+	buckets.Attribute("versioning.0.enabled").Equals(t, true)
+	buckets.WithAction(ChangeActionCreate).Attribute("tags.Team").Equals(t, "infra")
+	buckets.WithAction(ChangeActionCreate).Attribute("arn").IsKnownAfterApply(t)
+	buckets.WithAction(ChangeActionCreate).Attribute("arn").Exists(t)
+
+	assert.Equal(t, map[string]interface{}{"aws_instance.web": "ami-2"}, plan.Resources().OfType("aws_instance").Attribute("ami").Values())
+	assert.Equal(t, map[ChangeAction]int{
+		ChangeActionCreate:  1,
+		ChangeActionUpdate:  1,
+		ChangeActionReplace: 1,
+		ChangeActionDelete:  1,
+		ChangeActionNoOp:    1,
+	}, plan.Resources().CountByAction())
+}
+
+func TestPlanResourcesSelectionFailures(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlanJSON(testPlanWithChangesJSON)
+	require.NoError(t, err)
+
+	countT := &mockT{}
+	plan.Resources().OfType("aws_s3_bucket").WithAction(ChangeActionDelete).HasCount(countT, 1)
+	assert.True(t, countT.Failed)
+	require.Len(t, countT.Messages, 1)
+	assert.Contains(t, countT.Messages[0], `resources of type "aws_s3_bucket" with action "delete"`)
+
+	emptyT := &mockT{}
+	plan.Resources().OfType("aws_vpc").Attribute("cidr_block").Equals(emptyT, "10.0.0.0/16")
+	assert.True(t, emptyT.Failed)
+
+	valueT := &mockT{}
+	plan.Resources().OfType("aws_instance").Attribute("ami").Equals(valueT, "ami-1")
+	assert.True(t, valueT.Failed)
+	require.Len(t, valueT.Messages, 1)
+	assert.Contains(t, valueT.Messages[0], `attribute "ami" of resource "aws_instance.web"`)
+	assert.Contains(t, valueT.Messages[0], "Diff:")
+
+	unknownT := &mockT{}
+	plan.Resources().WithAddress("aws_s3_bucket.new").Attribute("id").Equals(unknownT, "new-bucket")
+	assert.True(t, unknownT.Failed)
+	require.Len(t, unknownT.Messages, 1)
+	assert.Contains(t, unknownT.Messages[0], "not known until after apply")
+}
+
+func TestPlanNoDestroysAndNoReplaces(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlanJSON(testPlanWithChangesJSON)
+	require.NoError(t, err)
+
+	destroysT := &mockT{}
+	AssertPlanNoDestroys(destroysT, plan)
+	assert.True(t, destroysT.Failed)
+	require.Len(t, destroysT.Messages, 1)
+	assert.Contains(t, destroysT.Messages[0], "aws_iam_role.old")
+	assert.Contains(t, destroysT.Messages[0], "aws_instance.web")
+
+	replacesT := &mockT{}
+	AssertPlanNoReplaces(replacesT, plan)
+	assert.True(t, replacesT.Failed)
+	require.Len(t, replacesT.Messages, 1)
+	assert.Contains(t, replacesT.Messages[0], "aws_instance.web")
+	assert.NotContains(t, replacesT.Messages[0], "aws_iam_role.old")
+
+	noChangesPlan, err := ParsePlanJSON(`{"format_version": "1.1"}`)
+	require.NoError(t, err)
+	RequirePlanNoDestroys(t, noChangesPlan)
+	RequirePlanNoReplaces(t, noChangesPlan)
+}
+
+func TestPlanResourcesWithInitAndPlanAndShowWithStruct(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-basic-configuration", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir: testFolder,
+		PlanFilePath: filepath.Join(testFolder, "plan.out"),
+		Vars: map[string]interface{}{
+			"cnt": 3,
+		},
+	}
+
+	plan := InitAndPlanAndShowWithStruct(t, options)
+	plan.Resources().OfType("null_resource").WithAction(ChangeActionCreate).HasCount(t, 3)
+	plan.Resources().OfType("null_resource").Attribute("id").IsKnownAfterApply(t)
+	RequirePlanNoDestroys(t, plan)
+}
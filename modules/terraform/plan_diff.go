@@ -0,0 +1,314 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/collections"
+	"github.com/gruntwork-io/terratest/modules/git"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ResourceChangeReport describes the change to a single resource between two versions of a module.
+type ResourceChangeReport struct {
+	Address string
+	Action  ChangeAction
+
+	// The names of the top level attributes whose value differs between the two versions, sorted. Attributes whose new
+	// value will only be known after apply are always considered changed.
+	ChangedAttributes []string
+}
+
+func (change ResourceChangeReport) String() string {
+	if len(change.ChangedAttributes) == 0 {
+		return fmt.Sprintf("%s (%s)", change.Address, change.Action)
+	}
+	return fmt.Sprintf("%s (%s: %s)", change.Address, change.Action, strings.Join(change.ChangedAttributes, ", "))
+}
+
+// PlanChangeReport is a structured report of all the resources that change between two versions of a module. Resources
+// that do not change are not included.
+type PlanChangeReport struct {
+	// The changes, sorted by resource address.
+	Changes []ResourceChangeReport
+}
+
+// WithAction returns the changes in the report that have the given action.
+func (report *PlanChangeReport) WithAction(action ChangeAction) []ResourceChangeReport {
+	out := []ResourceChangeReport{}
+	for _, change := range report.Changes {
+		if change.Action == action {
+			out = append(out, change)
+		}
+	}
+	return out
+}
+
+// UpgradeAllowList lists the destructive changes that are acceptable when upgrading a module. Creates and in-place
+// updates are always allowed.
+type UpgradeAllowList struct {
+	Replace []string // The addresses of the resources that may be replaced.
+	Delete  []string // The addresses of the resources that may be deleted.
+}
+
+// Disallowed returns the changes in the report that replace or delete a resource that is not on the given allow list.
+func (report *PlanChangeReport) Disallowed(allowList UpgradeAllowList) []ResourceChangeReport {
+	out := []ResourceChangeReport{}
+	for _, change := range report.Changes {
+		switch change.Action {
+		case ChangeActionReplace:
+			if !collections.ListContains(allowList.Replace, change.Address) {
+				out = append(out, change)
+			}
+		case ChangeActionDelete:
+			if !collections.ListContains(allowList.Delete, change.Address) {
+				out = append(out, change)
+			}
+		}
+	}
+	return out
+}
+
+// AssertPlanChangeReportAllowed checks that the report does not replace or delete any resource that is not on the
+// given allow list, failing the test if it does.
+func AssertPlanChangeReportAllowed(t testing.TestingT, report *PlanChangeReport, allowList UpgradeAllowList) {
+	assert.Emptyf(t, report.Disallowed(allowList), "Found destructive changes that are not on the allow list")
+}
+
+// RequirePlanChangeReportAllowed checks that the report does not replace or delete any resource that is not on the
+// given allow list, failing and halting the test if it does.
+func RequirePlanChangeReportAllowed(t testing.TestingT, report *PlanChangeReport, allowList UpgradeAllowList) {
+	require.Emptyf(t, report.Disallowed(allowList), "Found destructive changes that are not on the allow list")
+}
+
+// GetPlanChangeReport builds a change report from the resource changes in the given plan. This is typically used on a
+// plan of a new version of a module against the state of an older version, so that the report describes the upgrade.
+func GetPlanChangeReport(plan *PlanStruct) *PlanChangeReport {
+	report := &PlanChangeReport{Changes: []ResourceChangeReport{}}
+	for _, change := range plan.Resources().Changes() {
+		action := GetChangeAction(change)
+		if action == ChangeActionNoOp || action == ChangeActionRead {
+			continue
+		}
+		var before, after, afterUnknown interface{}
+		if change.Change != nil {
+			before, after, afterUnknown = change.Change.Before, change.Change.After, change.Change.AfterUnknown
+		}
+		report.Changes = append(report.Changes, ResourceChangeReport{
+			Address:           change.Address,
+			Action:            action,
+			ChangedAttributes: diffAttributes(before, after, afterUnknown),
+		})
+	}
+	return report
+}
+
+// DiffPlans compares the planned values of two plan snapshots (e.g., a plan of the old and a plan of the new version of
+// a module, both against empty state) and returns a report of the resources that are added, removed, or have different
+// planned values. As this compares the snapshots statically, resources are reported as created, updated, or deleted,
+// but never as replaced. To find out which resources would be replaced by an upgrade, use UpgradeTest instead.
+func DiffPlans(oldPlan *PlanStruct, newPlan *PlanStruct) *PlanChangeReport {
+	report := &PlanChangeReport{Changes: []ResourceChangeReport{}}
+
+	for address, newResource := range newPlan.ResourcePlannedValuesMap {
+		oldResource, existed := oldPlan.ResourcePlannedValuesMap[address]
+		if !existed {
+			report.Changes = append(report.Changes, ResourceChangeReport{
+				Address:           address,
+				Action:            ChangeActionCreate,
+				ChangedAttributes: diffAttributes(nil, newResource.AttributeValues, nil),
+			})
+			continue
+		}
+		changed := diffAttributes(oldResource.AttributeValues, newResource.AttributeValues, nil)
+		if len(changed) > 0 {
+			report.Changes = append(report.Changes, ResourceChangeReport{Address: address, Action: ChangeActionUpdate, ChangedAttributes: changed})
+		}
+	}
+	for address, oldResource := range oldPlan.ResourcePlannedValuesMap {
+		if _, exists := newPlan.ResourcePlannedValuesMap[address]; !exists {
+			report.Changes = append(report.Changes, ResourceChangeReport{
+				Address:           address,
+				Action:            ChangeActionDelete,
+				ChangedAttributes: diffAttributes(oldResource.AttributeValues, nil, nil),
+			})
+		}
+	}
+
+	sort.Slice(report.Changes, func(i, j int) bool { return report.Changes[i].Address < report.Changes[j].Address })
+	return report
+}
+
+// diffAttributes returns the sorted names of the top level attributes that differ between before and after, or that
+// are marked as unknown in afterUnknown.
+func diffAttributes(before interface{}, after interface{}, afterUnknown interface{}) []string {
+	beforeMap, _ := before.(map[string]interface{})
+	afterMap, _ := after.(map[string]interface{})
+	unknownMap, _ := afterUnknown.(map[string]interface{})
+
+	changed := map[string]bool{}
+	for key, afterVal := range afterMap {
+		if !reflect.DeepEqual(beforeMap[key], afterVal) {
+			changed[key] = true
+		}
+	}
+	for key, beforeVal := range beforeMap {
+		if _, hasKey := afterMap[key]; !hasKey && beforeVal != nil {
+			changed[key] = true
+		}
+	}
+	for key, unknown := range unknownMap {
+		if unknown == true {
+			changed[key] = true
+		}
+	}
+
+	out := make([]string, 0, len(changed))
+	for key := range changed {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// UpgradeTest runs terraform init and apply on the module in options.TerraformDir as of oldRef in its git repo, then
+// runs terraform init, plan, and show on the module as of newRef against the resulting state, and returns a report of
+// the changes the upgrade would make. Both refs are resolved to commits up front and checked out in a temporary git
+// worktree, so the checkout that contains options.TerraformDir is not touched, and the options passed in are not
+// modified. The resources created by apply are destroyed (with the module as of newRef) and the worktree is removed
+// before this returns, so the state must not be needed afterwards. This will fail the test if there is an error.
+//
+// Example:
+//
+//	report := terraform.UpgradeTest(t, options, "v1.0.0", "v2.0.0")
+//	terraform.AssertPlanChangeReportAllowed(t, report, terraform.UpgradeAllowList{
+//		Replace: []string{"aws_launch_template.this"},
+//	})
+func UpgradeTest(t testing.TestingT, options *Options, oldRef string, newRef string) *PlanChangeReport {
+	report, err := UpgradeTestE(t, options, oldRef, newRef)
+	require.NoError(t, err)
+	return report
+}
+
+// UpgradeTestE runs terraform init and apply on the module in options.TerraformDir as of oldRef in its git repo, then
+// runs terraform init, plan, and show on the module as of newRef against the resulting state, and returns a report of
+// the changes the upgrade would make. See UpgradeTest for more info.
+func UpgradeTestE(t testing.TestingT, options *Options, oldRef string, newRef string) (*PlanChangeReport, error) {
+	plan, err := upgradePlanE(t, options, oldRef, newRef)
+	if err != nil {
+		return nil, err
+	}
+	return GetPlanChangeReport(plan), nil
+}
+
+// upgradePlanE applies the module as of oldRef, and returns the plan of the module as of newRef against the resulting
+// state. The refs are checked out in a temporary git worktree, which is removed, after destroying the resources, before
+// this returns.
+func upgradePlanE(t testing.TestingT, options *Options, oldRef string, newRef string) (plan *PlanStruct, err error) {
+	repoRoot, err := git.GetRepoRootForDirE(t, options.TerraformDir)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve both refs before checking out anything, so that relative refs such as HEAD~1 are not affected by the checkouts
+	oldCommit, err := git.ResolveRefForDirE(t, repoRoot, oldRef)
+	if err != nil {
+		return nil, err
+	}
+	newCommit, err := git.ResolveRefForDirE(t, repoRoot, newRef)
+	if err != nil {
+		return nil, err
+	}
+	moduleDir, err := pathRelativeToRepoRoot(repoRoot, options.TerraformDir)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "terratest-upgrade-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	worktreeDir := filepath.Join(tmpDir, filepath.Base(repoRoot))
+	if err := git.AddWorktreeForDirE(t, repoRoot, worktreeDir, oldCommit); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if removeErr := git.RemoveWorktreeForDirE(t, repoRoot, worktreeDir); removeErr != nil {
+			options.Logger.Logf(t, "Failed to remove worktree %s: %v", worktreeDir, removeErr)
+		}
+	}()
+
+	upgradeOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	upgradeOptions.TerraformDir = filepath.Join(worktreeDir, moduleDir)
+
+	// The state lives in the worktree, so the resources must be destroyed before it is removed
+	defer func() {
+		if _, destroyErr := DestroyE(t, upgradeOptions); destroyErr != nil {
+			if err == nil {
+				err = destroyErr
+			} else {
+				options.Logger.Logf(t, "Failed to destroy the resources of the upgrade test: %v", destroyErr)
+			}
+		}
+	}()
+
+	if _, err := InitAndApplyE(t, upgradeOptions); err != nil {
+		return nil, err
+	}
+	if err := git.CheckoutRefForDirE(t, worktreeDir, newCommit); err != nil {
+		return nil, err
+	}
+	return initAndPlanAndShowWithStructTempPlanFileE(t, upgradeOptions)
+}
+
+// pathRelativeToRepoRoot returns the path of dir relative to the root of the git repo it resides in.
+func pathRelativeToRepoRoot(repoRoot string, dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	// git returns the root of the repo with symlinks resolved (e.g., /private/var on macOS)
+	realDir, err := filepath.EvalSymlinks(absDir)
+	if err != nil {
+		return "", err
+	}
+	realRepoRoot, err := filepath.EvalSymlinks(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(realRepoRoot, realDir)
+}
+
+// initAndPlanAndShowWithStructTempPlanFileE runs InitAndPlanAndShowWithStructE, allocating a temporary plan file if
+// PlanFilePath is not set on the options. The options passed in are not modified.
+func initAndPlanAndShowWithStructTempPlanFileE(t testing.TestingT, options *Options) (*PlanStruct, error) {
+	if options.PlanFilePath != "" {
+		return InitAndPlanAndShowWithStructE(t, options)
+	}
+
+	planOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpFile, err := os.CreateTemp("", "terratest-plan-file-")
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	planOptions.PlanFilePath = tmpFile.Name()
+	return InitAndPlanAndShowWithStructE(t, planOptions)
+}
@@ -0,0 +1,133 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/internal/testgit"
+)
+
+func TestGetPlanChangeReport(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlanJSON(testPlanWithChangesJSON)
+	require.NoError(t, err)
+
+	report := GetPlanChangeReport(plan)
+	assert.Equal(t, []ResourceChangeReport{
+		{Address: "aws_iam_role.old", Action: ChangeActionDelete, ChangedAttributes: []string{"name"}},
+		{Address: "aws_instance.web", Action: ChangeActionReplace, ChangedAttributes: []string{"ami", "id"}},
+		{Address: "aws_s3_bucket.new", Action: ChangeActionCreate, ChangedAttributes: []string{"arn", "bucket", "id", "tags", "versioning"}},
+		{Address: "module.logs.aws_s3_bucket.logs", Action: ChangeActionUpdate, ChangedAttributes: []string{"versioning"}},
+	}, report.Changes)
+
+	assert.Len(t, report.WithAction(ChangeActionReplace), 1)
+	assert.Equal(t, []ResourceChangeReport{report.Changes[0], report.Changes[1]}, report.Disallowed(UpgradeAllowList{}))
+	assert.Empty(t, report.Disallowed(UpgradeAllowList{
+		Replace: []string{"aws_instance.web"},
+		Delete:  []string{"aws_iam_role.old"},
+	}))
+
+	disallowedT := &mockT{}
+	AssertPlanChangeReportAllowed(disallowedT, report, UpgradeAllowList{Delete: []string{"aws_iam_role.old"}})
+	assert.True(t, disallowedT.Failed)
+	require.Len(t, disallowedT.Messages, 1)
+	assert.Contains(t, disallowedT.Messages[0], "aws_instance.web (replace: ami, id)")
+}
+
+func TestDiffPlans(t *testing.T) {
+	t.Parallel()
+
+	oldPlan, err := ParsePlanJSON(`{
+  "format_version": "1.1",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "null_resource.kept", "type": "null_resource", "name": "kept", "values": {"triggers": {"v": "1"}}},
+        {"address": "null_resource.same", "type": "null_resource", "name": "same", "values": {"triggers": null}},
+        {"address": "null_resource.removed", "type": "null_resource", "name": "removed", "values": {"triggers": null}}
+      ]
+    }
+  }
+}`)
+	require.NoError(t, err)
+	newPlan, err := ParsePlanJSON(`{
+  "format_version": "1.1",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "null_resource.kept", "type": "null_resource", "name": "kept", "values": {"triggers": {"v": "2"}}},
+        {"address": "null_resource.same", "type": "null_resource", "name": "same", "values": {"triggers": null}},
+        {"address": "null_resource.added", "type": "null_resource", "name": "added", "values": {"triggers": {"v": "1"}}}
+      ]
+    }
+  }
+}`)
+	require.NoError(t, err)
+
+	report := DiffPlans(oldPlan, newPlan)
+	assert.Equal(t, []ResourceChangeReport{
+		{Address: "null_resource.added", Action: ChangeActionCreate, ChangedAttributes: []string{"triggers"}},
+		{Address: "null_resource.kept", Action: ChangeActionUpdate, ChangedAttributes: []string{"triggers"}},
+		{Address: "null_resource.removed", Action: ChangeActionDelete, ChangedAttributes: []string{}},
+	}, report.Changes)
+}
+
+func TestUpgradeTest(t *testing.T) {
+	t.Parallel()
+
+	repoDir := t.TempDir()
+	mainFile := filepath.Join(repoDir, "main.tf")
+	testgit.Run(t, repoDir, "init", "--quiet")
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, ".gitignore"), []byte(".terraform*\n*.tfstate*\n"), 0644))
+	require.NoError(t, os.WriteFile(mainFile, []byte(`
+resource "null_resource" "replaced" {
+  triggers = { version = "1" }
+}
+
+resource "null_resource" "kept" {}
+`), 0644))
+	testgit.Run(t, repoDir, "add", ".")
+	testgit.Run(t, repoDir, "commit", "--quiet", "-m", "v1")
+	testgit.Run(t, repoDir, "tag", "v1")
+	require.NoError(t, os.WriteFile(mainFile, []byte(`
+resource "null_resource" "replaced" {
+  triggers = { version = "2" }
+}
+
+resource "null_resource" "kept" {}
+
+resource "null_resource" "added" {}
+`), 0644))
+	testgit.Run(t, repoDir, "commit", "--quiet", "-am", "v2")
+	testgit.Run(t, repoDir, "tag", "v2")
+
+	head := testgit.Run(t, repoDir, "rev-parse", "HEAD")
+	options := &Options{
+		TerraformDir: repoDir,
+	}
+
+	report := UpgradeTest(t, options, "v1", "v2")
+	assert.Equal(t, []string{"null_resource.added"}, changeReportAddresses(report.WithAction(ChangeActionCreate)))
+	assert.Equal(t, []string{"null_resource.replaced"}, changeReportAddresses(report.WithAction(ChangeActionReplace)))
+	RequirePlanChangeReportAllowed(t, report, UpgradeAllowList{Replace: []string{"null_resource.replaced"}})
+
+	// The upgrade ran in a separate worktree, so the checkout of the repo is untouched
+	assert.Equal(t, repoDir, options.TerraformDir)
+	assert.Equal(t, head, testgit.Run(t, repoDir, "rev-parse", "HEAD"))
+	assert.Equal(t, "", testgit.Run(t, repoDir, "status", "--porcelain", "--ignored"))
+	assert.Len(t, strings.Split(testgit.Run(t, repoDir, "worktree", "list"), "\n"), 1)
+}
+
+func changeReportAddresses(changes []ResourceChangeReport) []string {
+	out := []string{}
+	for _, change := range changes {
+		out = append(out, change.Address)
+	}
+	return out
+}
@@ -25,6 +25,14 @@ type PlanStruct struct {
 	// A map that maps full resource addresses (e.g., module.foo.null_resource.test) to the planned actions terraform
 	// will take on that resource.
 	ResourceChangesMap map[string]*tfjson.ResourceChange
+
+	// A map that maps full resource addresses to the address the resource had in the prior state, for resources that
+	// are moved (e.g., with a moved block). Requires Terraform 1.1 or later.
+	ResourcePreviousAddressMap map[string]string
+
+	// A map that maps full resource addresses to the ID the resource is imported with, for resources that are imported
+	// with an import block. Requires Terraform 1.5 or later.
+	ResourceImportIDMap map[string]string
 }
 
 // ParsePlanJSON takes in the json string representation of the terraform plan and returns a go struct representation
@@ -38,9 +46,46 @@ func ParsePlanJSON(jsonStr string) (*PlanStruct, error) {
 
 	plan.ResourcePlannedValuesMap = parsePlannedValues(plan)
 	plan.ResourceChangesMap = parseResourceChanges(plan)
+
+	var err error
+	plan.ResourcePreviousAddressMap, plan.ResourceImportIDMap, err = parseMovesAndImports(jsonStr)
+	if err != nil {
+		return nil, err
+	}
 	return plan, nil
 }
 
+// parseMovesAndImports returns the previous addresses of the moved resources and the import IDs of the imported
+// resources in the plan. These fields are not exposed by terraform-json, so they are parsed separately from the raw json.
+func parseMovesAndImports(jsonStr string) (map[string]string, map[string]string, error) {
+	var plan struct {
+		ResourceChanges []struct {
+			Address         string `json:"address"`
+			PreviousAddress string `json:"previous_address"`
+			Change          struct {
+				Importing *struct {
+					ID string `json:"id"`
+				} `json:"importing"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &plan); err != nil {
+		return nil, nil, err
+	}
+
+	moves := map[string]string{}
+	imports := map[string]string{}
+	for _, change := range plan.ResourceChanges {
+		if change.PreviousAddress != "" && change.PreviousAddress != change.Address {
+			moves[change.Address] = change.PreviousAddress
+		}
+		if change.Change.Importing != nil {
+			imports[change.Address] = change.Change.Importing.ID
+		}
+	}
+	return moves, imports, nil
+}
+
 // parseResourceChanges takes a plan and returns a map that maps resource addresses to the planned changes for that
 // resource. If there are no changes, this returns an empty map instead of erroring.
 func parseResourceChanges(plan *PlanStruct) map[string]*tfjson.ResourceChange {
@@ -0,0 +1,468 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	// PluginCacheDirEnvVar can be set to use a persistent directory (e.g., one that is cached between CI runs) as the
+	// shared plugin cache, instead of a new temp dir for each test binary.
+	PluginCacheDirEnvVar = "TERRATEST_PLUGIN_CACHE_DIR"
+	// ProviderMirrorDirEnvVar can be set to the path of a pre-seeded filesystem mirror (e.g., one created with
+	// ProvidersMirror) that the shared plugin cache installs providers from.
+	ProviderMirrorDirEnvVar = "TERRATEST_PROVIDER_MIRROR_DIR"
+
+	pluginCacheLockFileName   = ".terratest.lock"
+	cliConfigFileName         = ".terratest.tfrc"
+	readOnlyCLIConfigFileName = ".terratest-read-only.tfrc"
+)
+
+// PluginCacheLockTimeout is the maximum amount of time to wait for another terraform init to release the plugin cache.
+var PluginCacheLockTimeout = 10 * time.Minute
+
+// PluginCacheStaleLockAge is the age after which a lock file left behind by a crashed test binary is removed. The lock
+// file is refreshed while it is held, so this must be shorter than PluginCacheLockTimeout, but need not be longer than
+// terraform init takes.
+var PluginCacheStaleLockAge = 2 * time.Minute
+
+// PluginCache is a Terraform provider plugin cache (TF_PLUGIN_CACHE_DIR) shared by all the terraform init calls that
+// use it, optionally backed by a local filesystem mirror so that providers can be installed without network access.
+// Terraform does not support concurrent writes to the plugin cache, so init first runs without writing to it: the cache
+// (and mirror) are used as read-only filesystem mirrors, which works without any locking if they already hold all the
+// providers the module needs. Only if they don't is init run again with the cache as TF_PLUGIN_CACHE_DIR, to add the
+// missing providers to it. Those init calls are serialized, both within the test binary and across test binaries (e.g.,
+// when running go test ./...). Note that as a result, init uses a provider version that is in the cache if it meets the
+// version constraints, even if a newer version has been released since.
+//
+// Checksums in .terraform.lock.hcl keep being verified as usual. Note that since Terraform 1.4, providers are only
+// linked from the cache if the lock file already contains their checksums, so committing lock files for your test
+// fixtures gets you the most out of the cache.
+type PluginCache struct {
+	// The directory that is used as TF_PLUGIN_CACHE_DIR.
+	Dir string
+	// The directory of a local filesystem mirror (in the layout created by `terraform providers mirror`) to install
+	// providers from. Providers that are not in the mirror are installed from their origin registry. Optional.
+	MirrorDir string
+
+	mutex sync.Mutex
+}
+
+var (
+	sharedPluginCache     *PluginCache
+	sharedPluginCacheErr  error
+	sharedPluginCacheOnce sync.Once
+)
+
+// SharedPluginCache returns the plugin cache shared by all tests in this test binary, creating it on first use. This
+// is the cache init uses, unless configured otherwise on the options. This will fail the test if there is an error.
+func SharedPluginCache(t testing.TestingT) *PluginCache {
+	cache, err := SharedPluginCacheE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+// SharedPluginCacheE returns the plugin cache shared by all tests in this test binary, creating it on first use. The
+// cache is stored in the directory set in the TERRATEST_PLUGIN_CACHE_DIR env var, or in a new temp dir if it is not
+// set, and uses the mirror set in the TERRATEST_PROVIDER_MIRROR_DIR env var, if any.
+func SharedPluginCacheE() (*PluginCache, error) {
+	sharedPluginCacheOnce.Do(func() {
+		dir := os.Getenv(PluginCacheDirEnvVar)
+		if dir == "" {
+			dir, sharedPluginCacheErr = os.MkdirTemp("", "terratest-plugin-cache-")
+			if sharedPluginCacheErr != nil {
+				return
+			}
+		}
+		sharedPluginCache, sharedPluginCacheErr = NewPluginCacheE(dir, os.Getenv(ProviderMirrorDirEnvVar))
+	})
+	return sharedPluginCache, sharedPluginCacheErr
+}
+
+// NewPluginCache creates a plugin cache in the given directory, optionally backed by the filesystem mirror in
+// mirrorDir. This will fail the test if there is an error.
+func NewPluginCache(t testing.TestingT, dir string, mirrorDir string) *PluginCache {
+	cache, err := NewPluginCacheE(dir, mirrorDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+// NewPluginCacheE creates a plugin cache in the given directory, optionally backed by the filesystem mirror in
+// mirrorDir.
+func NewPluginCacheE(dir string, mirrorDir string) (*PluginCache, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, err
+	}
+	cache := &PluginCache{Dir: absDir}
+
+	if mirrorDir != "" {
+		if cache.MirrorDir, err = filepath.Abs(mirrorDir); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(cache.MirrorDir); err != nil {
+			return nil, err
+		}
+	}
+	return cache, nil
+}
+
+// EnvVars returns the environment variables that make Terraform use this plugin cache (and mirror, if configured).
+func (cache *PluginCache) EnvVars() (map[string]string, error) {
+	envVars := map[string]string{"TF_PLUGIN_CACHE_DIR": cache.Dir}
+	if cache.MirrorDir == "" {
+		return envVars, nil
+	}
+
+	cliConfig, err := cache.cliConfig()
+	if err != nil {
+		return nil, err
+	}
+	cliConfigPath := filepath.Join(cache.Dir, cliConfigFileName)
+	if err := os.WriteFile(cliConfigPath, []byte(cliConfig), 0644); err != nil {
+		return nil, err
+	}
+	envVars["TF_CLI_CONFIG_FILE"] = cliConfigPath
+	return envVars, nil
+}
+
+// readOnlyEnvVars returns the environment variables that make Terraform install providers from this plugin cache (and
+// mirror, if configured) only, without writing to the cache.
+func (cache *PluginCache) readOnlyEnvVars() (map[string]string, error) {
+	var sb strings.Builder
+	sb.WriteString("provider_installation {\n")
+	for _, mirrorDir := range []string{cache.Dir, cache.MirrorDir} {
+		if mirrorDir != "" {
+			fmt.Fprintf(&sb, "  filesystem_mirror {\n    path = %q\n  }\n", filepath.ToSlash(mirrorDir))
+		}
+	}
+	sb.WriteString("}\n")
+
+	// Init calls that don't hold the lock read this file concurrently, so replace it atomically
+	cliConfigPath := filepath.Join(cache.Dir, readOnlyCLIConfigFileName)
+	tmpFile, err := os.CreateTemp(cache.Dir, readOnlyCLIConfigFileName+".*")
+	if err != nil {
+		return nil, err
+	}
+	_, writeErr := tmpFile.WriteString(sb.String())
+	closeErr := tmpFile.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tmpFile.Name(), cliConfigPath)
+	}
+	if writeErr != nil {
+		os.Remove(tmpFile.Name())
+		return nil, writeErr
+	}
+	return map[string]string{"TF_CLI_CONFIG_FILE": cliConfigPath}, nil
+}
+
+// cliConfig returns a Terraform CLI configuration that installs the providers in the mirror from the mirror, and all
+// other providers from their origin registry.
+func (cache *PluginCache) cliConfig() (string, error) {
+	mirrored, err := mirroredProviders(cache.MirrorDir)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("provider_installation {\n")
+	fmt.Fprintf(&sb, "  filesystem_mirror {\n    path = %q\n  }\n", filepath.ToSlash(cache.MirrorDir))
+	sb.WriteString("  direct {\n")
+	if len(mirrored) > 0 {
+		fmt.Fprintf(&sb, "    exclude = [%s]\n", quoteAndJoin(mirrored))
+	}
+	sb.WriteString("  }\n}\n")
+	return sb.String(), nil
+}
+
+// mirroredProviders returns the source addresses (HOSTNAME/NAMESPACE/TYPE) of the providers in the given filesystem
+// mirror, sorted.
+func mirroredProviders(mirrorDir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(mirrorDir, "*", "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	out := []string{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			continue
+		}
+		relPath, err := filepath.Rel(mirrorDir, match)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, filepath.ToSlash(relPath))
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func quoteAndJoin(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, fmt.Sprintf("%q", value))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Lock waits until no other terraform init is using the plugin cache and acquires it, both for this test binary and
+// for any other test binary using the same cache directory. The returned function releases the lock.
+func (cache *PluginCache) Lock() (func(), error) {
+	cache.mutex.Lock()
+
+	lockPath := filepath.Join(cache.Dir, pluginCacheLockFileName)
+	// The lock file holds the PID of this process plus a random token, which tells this lock apart from other locks
+	// taken later by the same process
+	owner := fmt.Sprintf("%d %s", os.Getpid(), random.UniqueId())
+	deadline := time.Now().Add(PluginCacheLockTimeout)
+	for {
+		lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, writeErr := lockFile.WriteString(owner)
+			closeErr := lockFile.Close()
+			if writeErr == nil {
+				writeErr = closeErr
+			}
+			if writeErr != nil {
+				os.Remove(lockPath)
+				cache.mutex.Unlock()
+				return nil, writeErr
+			}
+			stopRefreshing := refreshLockFile(lockPath)
+			return func() {
+				stopRefreshing()
+				os.Remove(lockPath)
+				cache.mutex.Unlock()
+			}, nil
+		}
+		if !os.IsExist(err) {
+			cache.mutex.Unlock()
+			return nil, err
+		}
+
+		if removeStaleLockFile(lockPath) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			cache.mutex.Unlock()
+			return nil, PluginCacheLockTimeoutError{LockFile: lockPath, Timeout: PluginCacheLockTimeout}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// refreshLockFile updates the modification time of the given lock file periodically, so that other test binaries can
+// tell it apart from a lock file left behind by a crashed test binary, until the returned function is called.
+func refreshLockFile(lockPath string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		interval := PluginCacheStaleLockAge / 4
+		if interval < time.Second {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				os.Chtimes(lockPath, now, now)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// removeStaleLockFile removes the given lock file if it has not been refreshed for PluginCacheStaleLockAge, and returns
+// true if it did. As several test binaries may find the same stale lock file, it is first renamed to a unique path, so
+// that only one of them removes it, and put back if it turns out to be a lock file that was taken in the meantime.
+func removeStaleLockFile(lockPath string) bool {
+	owner, stale := readStaleLockFile(lockPath)
+	if !stale {
+		return false
+	}
+
+	stalePath := fmt.Sprintf("%s.stale-%d-%s", lockPath, os.Getpid(), random.UniqueId())
+	if err := os.Rename(lockPath, stalePath); err != nil {
+		// Another test binary got to it first
+		return false
+	}
+	if renamedOwner, stillStale := readStaleLockFile(stalePath); !stillStale || renamedOwner != owner {
+		// The lock file was replaced or refreshed after we checked it, so put it back, unless yet another lock was taken
+		os.Link(stalePath, lockPath)
+		os.Remove(stalePath)
+		return false
+	}
+	os.Remove(stalePath)
+	return true
+}
+
+// readStaleLockFile returns the contents of the given lock file, and whether it has not been refreshed for
+// PluginCacheStaleLockAge.
+func readStaleLockFile(lockPath string) (string, bool) {
+	info, err := os.Stat(lockPath)
+	if err != nil || time.Since(info.ModTime()) <= PluginCacheStaleLockAge {
+		return "", false
+	}
+	contents, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "", false
+	}
+	return string(contents), true
+}
+
+// pluginCacheForOptions returns the plugin cache that terraform init should use for the given options, or nil if it
+// should not use one. The shared plugin cache is used by default, unless it is disabled, a -plugin-dir is used (in which
+// case Terraform never installs providers from anywhere else), the user configured a plugin cache of their own through
+// TF_PLUGIN_CACHE_DIR, or terragrunt is used (which runs init for multiple modules concurrently).
+func pluginCacheForOptions(options *Options) (*PluginCache, error) {
+	if options.PluginCache != nil {
+		return options.PluginCache, nil
+	}
+	if options.DisablePluginCache || options.PluginDir != "" || options.TerraformBinary == "terragrunt" {
+		return nil, nil
+	}
+	if _, hasCacheDir := options.EnvVars["TF_PLUGIN_CACHE_DIR"]; hasCacheDir {
+		return nil, nil
+	}
+	if _, hasCacheDir := os.LookupEnv("TF_PLUGIN_CACHE_DIR"); hasCacheDir {
+		return nil, nil
+	}
+	return SharedPluginCacheE()
+}
+
+// initWithPluginCacheE runs terraform init with the given args using the given plugin cache. Init is first run with
+// the cache as a read-only mirror, without locking it. If that fails (most likely because the cache does not hold all
+// the providers the module needs yet), init is run again while holding the lock, with the cache as TF_PLUGIN_CACHE_DIR,
+// so that the missing providers are added to it. A TF_CLI_CONFIG_FILE set on the options can't be combined with the
+// mirror of the cache, as it would override the mirror, which is an error.
+func initWithPluginCacheE(t testing.TestingT, options *Options, cache *PluginCache, args ...string) (string, error) {
+	if cliConfigFile, isSet := options.EnvVars["TF_CLI_CONFIG_FILE"]; isSet && cache.MirrorDir != "" {
+		return "", PluginCacheMirrorOverridden{MirrorDir: cache.MirrorDir, CLIConfigFile: cliConfigFile}
+	}
+
+	// The read-only mirror is configured through TF_CLI_CONFIG_FILE, which would override the CLI config of the user
+	if !hasCLIConfigFile(options) {
+		readOnlyOptions, err := withReadOnlyPluginCache(options, cache)
+		if err != nil {
+			return "", err
+		}
+		out, err := RunTerraformCommandE(t, readOnlyOptions, args...)
+		if err == nil || (options.Context != nil && options.Context.Err() != nil) {
+			return out, err
+		}
+		options.Logger.Logf(t, "terraform init could not install all providers from the plugin cache %s, running it again to add them to the cache", cache.Dir)
+	}
+
+	unlock, err := cache.Lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	cacheOptions, err := withPluginCache(options, cache)
+	if err != nil {
+		return "", err
+	}
+	return RunTerraformCommandE(t, cacheOptions, args...)
+}
+
+// hasCLIConfigFile returns true if the user configured a Terraform CLI config file through TF_CLI_CONFIG_FILE.
+func hasCLIConfigFile(options *Options) bool {
+	if _, isSet := options.EnvVars["TF_CLI_CONFIG_FILE"]; isSet {
+		return true
+	}
+	_, isSet := os.LookupEnv("TF_CLI_CONFIG_FILE")
+	return isSet
+}
+
+// withReadOnlyPluginCache returns a copy of the options that makes Terraform install providers from the given plugin
+// cache (and its mirror) only. Failed attempts are not retried, as they are followed by an init that fills the cache.
+func withReadOnlyPluginCache(options *Options, cache *PluginCache) (*Options, error) {
+	readOnlyOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	envVars, err := cache.readOnlyEnvVars()
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range envVars {
+		readOnlyOptions.EnvVars[key] = val
+	}
+	readOnlyOptions.RetryableTerraformErrors = nil
+	readOnlyOptions.MaxRetries = 0
+	return readOnlyOptions, nil
+}
+
+// withPluginCache returns a copy of the options that makes Terraform use the given plugin cache. Env vars that are
+// already set on the options take precedence.
+func withPluginCache(options *Options, cache *PluginCache) (*Options, error) {
+	cacheOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	envVars, err := cache.EnvVars()
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range envVars {
+		if _, isSet := cacheOptions.EnvVars[key]; !isSet {
+			cacheOptions.EnvVars[key] = val
+		}
+	}
+	return cacheOptions, nil
+}
+
+// ProvidersMirror calls terraform providers mirror to download the providers required by the module in
+// options.TerraformDir to the given filesystem mirror directory, for the given platforms (e.g., linux_amd64), or the
+// current platform if none are given. The mirror can then be used offline through PluginCache.MirrorDir. This will fail
+// the test if there is an error.
+func ProvidersMirror(t testing.TestingT, options *Options, mirrorDir string, platforms ...string) string {
+	out, err := ProvidersMirrorE(t, options, mirrorDir, platforms...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// ProvidersMirrorE calls terraform providers mirror to download the providers required by the module in
+// options.TerraformDir to the given filesystem mirror directory, for the given platforms (e.g., linux_amd64), or the
+// current platform if none are given.
+func ProvidersMirrorE(t testing.TestingT, options *Options, mirrorDir string, platforms ...string) (string, error) {
+	args := []string{"providers", "mirror"}
+	for _, platform := range platforms {
+		args = append(args, "-platform="+platform)
+	}
+	args = append(args, mirrorDir)
+	return RunTerraformCommandE(t, options, args...)
+}
@@ -0,0 +1,241 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedFakeProviderMirror creates a filesystem mirror with an (unpacked) fake provider example.com/test/fake, so that
+// terraform init can install it without network access.
+func seedFakeProviderMirror(t *testing.T) string {
+	mirrorDir := t.TempDir()
+	platformDir := filepath.Join(mirrorDir, "example.com", "test", "fake", "1.0.0", fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH))
+	require.NoError(t, os.MkdirAll(platformDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(platformDir, "terraform-provider-fake_v1.0.0"), []byte("#!/bin/sh\nexit 1\n"), 0755))
+	return mirrorDir
+}
+
+func TestPluginCacheEnvVars(t *testing.T) {
+	t.Parallel()
+
+	cache := NewPluginCache(t, t.TempDir(), "")
+	envVars, err := cache.EnvVars()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"TF_PLUGIN_CACHE_DIR": cache.Dir}, envVars)
+
+	mirrorDir := seedFakeProviderMirror(t)
+	mirrorCache := NewPluginCache(t, t.TempDir(), mirrorDir)
+	envVars, err = mirrorCache.EnvVars()
+	require.NoError(t, err)
+	require.Contains(t, envVars, "TF_CLI_CONFIG_FILE")
+
+	cliConfig, err := os.ReadFile(envVars["TF_CLI_CONFIG_FILE"])
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`provider_installation {
+  filesystem_mirror {
+    path = %q
+  }
+  direct {
+    exclude = ["example.com/test/fake"]
+  }
+}
+`, filepath.ToSlash(mirrorDir)), string(cliConfig))
+
+	_, err = NewPluginCacheE(t.TempDir(), filepath.Join(mirrorDir, "does-not-exist"))
+	assert.Error(t, err)
+}
+
+func TestPluginCacheLock(t *testing.T) {
+	t.Parallel()
+
+	cache := NewPluginCache(t, t.TempDir(), "")
+	// A second cache in the same dir stands in for another test binary.
+	otherCache := NewPluginCache(t, cache.Dir, "")
+
+	holders := 0
+	maxHolders := 0
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		for _, c := range []*PluginCache{cache, otherCache} {
+			wg.Add(1)
+			go func(c *PluginCache) {
+				defer wg.Done()
+				unlock, err := c.Lock()
+				if !assert.NoError(t, err) {
+					return
+				}
+				mutex.Lock()
+				holders++
+				if holders > maxHolders {
+					maxHolders = holders
+				}
+				mutex.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mutex.Lock()
+				holders--
+				mutex.Unlock()
+				unlock()
+			}(c)
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, 1, maxHolders)
+	assert.NoFileExists(t, filepath.Join(cache.Dir, pluginCacheLockFileName))
+}
+
+func TestPluginCacheLockRemovesStaleLock(t *testing.T) {
+	t.Parallel()
+
+	cache := NewPluginCache(t, t.TempDir(), "")
+	lockPath := filepath.Join(cache.Dir, pluginCacheLockFileName)
+	require.NoError(t, os.WriteFile(lockPath, []byte("12345"), 0644))
+	staleTime := time.Now().Add(-2 * PluginCacheStaleLockAge)
+	require.NoError(t, os.Chtimes(lockPath, staleTime, staleTime))
+
+	unlock, err := cache.Lock()
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestRemoveStaleLockFile(t *testing.T) {
+	t.Parallel()
+
+	// A crashed test binary must not make the others time out
+	assert.Less(t, PluginCacheStaleLockAge, PluginCacheLockTimeout)
+
+	lockPath := filepath.Join(t.TempDir(), pluginCacheLockFileName)
+	require.NoError(t, os.WriteFile(lockPath, []byte("12345 abcdef"), 0644))
+	assert.False(t, removeStaleLockFile(lockPath))
+	assert.FileExists(t, lockPath)
+
+	staleTime := time.Now().Add(-2 * PluginCacheStaleLockAge)
+	require.NoError(t, os.Chtimes(lockPath, staleTime, staleTime))
+	assert.True(t, removeStaleLockFile(lockPath))
+	assert.NoFileExists(t, lockPath)
+
+	// Nothing is left behind by the rename
+	entries, err := os.ReadDir(filepath.Dir(lockPath))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestPluginCacheForOptions(t *testing.T) {
+	t.Parallel()
+
+	cache := NewPluginCache(t, t.TempDir(), "")
+	shared, err := SharedPluginCacheE()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		options  *Options
+		expected *PluginCache
+	}{
+		{"Default", &Options{}, shared},
+		{"Explicit", &Options{PluginCache: cache, PluginDir: "/some/plugin/dir"}, cache},
+		{"Disabled", &Options{DisablePluginCache: true}, nil},
+		{"PluginDir", &Options{PluginDir: "/some/plugin/dir"}, nil},
+		{"Terragrunt", &Options{TerraformBinary: "terragrunt"}, nil},
+		{"UserCacheDir", &Options{EnvVars: map[string]string{"TF_PLUGIN_CACHE_DIR": "/some/cache/dir"}}, nil},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := pluginCacheForOptions(testCase.options)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, actual)
+		})
+	}
+}
+
+func TestInitWithPreSeededMirror(t *testing.T) {
+	t.Parallel()
+
+	testFolder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testFolder, "main.tf"), []byte(`
+terraform {
+  required_providers {
+    fake = {
+      source  = "example.com/test/fake"
+      version = "1.0.0"
+    }
+  }
+}
+`), 0644))
+
+	options := &Options{
+		TerraformDir: testFolder,
+		PluginCache:  NewPluginCache(t, t.TempDir(), seedFakeProviderMirror(t)),
+	}
+
+	Init(t, options)
+	assert.FileExists(t, filepath.Join(testFolder, ".terraform.lock.hcl"))
+
+	// A second init verifies the provider against the checksums recorded in the lock file.
+	Init(t, options)
+}
+
+func TestInitWithPluginCacheLocksOnlyToFillTheCache(t *testing.T) {
+	t.Parallel()
+
+	if _, isSet := os.LookupEnv("TF_CLI_CONFIG_FILE"); isSet {
+		t.Skip("TF_CLI_CONFIG_FILE is set, so init never uses the plugin cache as a read-only mirror")
+	}
+
+	cache := NewPluginCache(t, t.TempDir(), "")
+	logPath := filepath.Join(t.TempDir(), "calls.log")
+
+	// Stands in for terraform init, which fails to install the providers until they have been added to the cache
+	fakeTerraform := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(fakeTerraform, []byte(`#!/bin/sh
+locked=false
+[ -e "$CACHE_DIR/.terratest.lock" ] && locked=true
+echo "cache=${TF_PLUGIN_CACHE_DIR:-none} locked=$locked" >> "$CALLS_LOG"
+[ -n "$TF_PLUGIN_CACHE_DIR" ] && touch "$TF_PLUGIN_CACHE_DIR/provider"
+[ -e "$CACHE_DIR/provider" ]
+`), 0755))
+
+	options := &Options{
+		TerraformBinary: fakeTerraform,
+		TerraformDir:    t.TempDir(),
+		PluginCache:     cache,
+		EnvVars:         map[string]string{"CACHE_DIR": cache.Dir, "CALLS_LOG": logPath},
+	}
+
+	Init(t, options)
+	Init(t, options)
+
+	calls, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("cache=none locked=false\ncache=%s locked=true\ncache=none locked=false\n", cache.Dir), string(calls))
+
+	cliConfig, err := os.ReadFile(filepath.Join(cache.Dir, readOnlyCLIConfigFileName))
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("provider_installation {\n  filesystem_mirror {\n    path = %q\n  }\n}\n", filepath.ToSlash(cache.Dir)), string(cliConfig))
+}
+
+func TestInitWithPluginCacheMirrorAndCLIConfigFile(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{
+		TerraformDir: t.TempDir(),
+		PluginCache:  NewPluginCache(t, t.TempDir(), seedFakeProviderMirror(t)),
+		EnvVars:      map[string]string{"TF_CLI_CONFIG_FILE": "/some/registry.tfrc"},
+	}
+
+	_, err := InitE(t, options)
+	assert.Equal(t, PluginCacheMirrorOverridden{MirrorDir: options.PluginCache.MirrorDir, CLIConfigFile: "/some/registry.tfrc"}, err)
+}
@@ -0,0 +1,377 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ProviderSchemaSet is the schemas of all the providers a module uses, as returned by terraform providers schema -json,
+// with lookups by resource type. Attributes are looked up by path: the names of the nested blocks and nested attributes
+// leading to the attribute, separated by dots (e.g., ingress.from_port).
+type ProviderSchemaSet struct {
+	*tfjson.ProviderSchemas
+}
+
+// ProviderSchemas runs terraform providers schema -json for the module at options.TerraformDir, which must be
+// initialized, and returns the schemas of all the providers it uses. This will fail the test if there is an error.
+func ProviderSchemas(t testing.TestingT, options *Options) *ProviderSchemaSet {
+	schemas, err := ProviderSchemasE(t, options)
+	require.NoError(t, err)
+	return schemas
+}
+
+// ProviderSchemasE runs terraform providers schema -json for the module at options.TerraformDir, which must be
+// initialized, and returns the schemas of all the providers it uses.
+func ProviderSchemasE(t testing.TestingT, options *Options) (*ProviderSchemaSet, error) {
+	out, err := RunTerraformCommandAndGetStdoutE(t, options, "providers", "schema", "-json")
+	if err != nil {
+		return nil, err
+	}
+	return ParseProviderSchemasJSON(out)
+}
+
+// ParseProviderSchemasJSON parses the output of terraform providers schema -json.
+func ParseProviderSchemasJSON(schemasJSON string) (*ProviderSchemaSet, error) {
+	schemas := &tfjson.ProviderSchemas{}
+	if err := json.Unmarshal([]byte(schemasJSON), schemas); err != nil {
+		return nil, err
+	}
+	return &ProviderSchemaSet{ProviderSchemas: schemas}, nil
+}
+
+// ResourceSchema returns the schema of the given resource type (e.g., aws_instance), or nil if none of the providers
+// has that resource type.
+func (schemas *ProviderSchemaSet) ResourceSchema(resourceType string) *tfjson.Schema {
+	for _, provider := range schemas.Schemas {
+		if schema, hasSchema := provider.ResourceSchemas[resourceType]; hasSchema {
+			return schema
+		}
+	}
+	return nil
+}
+
+// DataSourceSchema returns the schema of the given data source type (e.g., aws_ami), or nil if none of the providers
+// has that data source type.
+func (schemas *ProviderSchemaSet) DataSourceSchema(dataSourceType string) *tfjson.Schema {
+	for _, provider := range schemas.Schemas {
+		if schema, hasSchema := provider.DataSourceSchemas[dataSourceType]; hasSchema {
+			return schema
+		}
+	}
+	return nil
+}
+
+// IsResourceDeprecated returns true if the given resource type exists and is deprecated.
+func (schemas *ProviderSchemaSet) IsResourceDeprecated(resourceType string) bool {
+	schema := schemas.ResourceSchema(resourceType)
+	return schema != nil && schema.Block != nil && schema.Block.Deprecated
+}
+
+// Attribute returns the schema of the attribute at the given path in the given resource type, or nil if there is no
+// such attribute (e.g., because it was removed, or because the path points to a nested block).
+func (schemas *ProviderSchemaSet) Attribute(resourceType string, path string) *tfjson.SchemaAttribute {
+	schema := schemas.ResourceSchema(resourceType)
+	if schema == nil {
+		return nil
+	}
+	attribute, _ := lookupSchemaPath(schema.Block, strings.Split(path, "."))
+	return attribute
+}
+
+// IsAttributeDeprecated returns true if the attribute (or nested block) at the given path in the given resource type
+// exists and is deprecated.
+func (schemas *ProviderSchemaSet) IsAttributeDeprecated(resourceType string, path string) bool {
+	schema := schemas.ResourceSchema(resourceType)
+	if schema == nil {
+		return false
+	}
+	attribute, block := lookupSchemaPath(schema.Block, strings.Split(path, "."))
+	return (attribute != nil && attribute.Deprecated) || (block != nil && block.Deprecated)
+}
+
+// IsAttributeSensitive returns true if the attribute at the given path in the given resource type exists and is
+// sensitive.
+func (schemas *ProviderSchemaSet) IsAttributeSensitive(resourceType string, path string) bool {
+	attribute := schemas.Attribute(resourceType, path)
+	return attribute != nil && attribute.Sensitive
+}
+
+// IsAttributeRequired returns true if the attribute at the given path in the given resource type exists and is
+// required.
+func (schemas *ProviderSchemaSet) IsAttributeRequired(resourceType string, path string) bool {
+	attribute := schemas.Attribute(resourceType, path)
+	return attribute != nil && attribute.Required
+}
+
+// DeprecatedAttributes returns the paths of all the deprecated attributes and nested blocks of the given resource type,
+// sorted.
+func (schemas *ProviderSchemaSet) DeprecatedAttributes(resourceType string) []string {
+	return schemas.filterAttributes(resourceType, func(attribute *tfjson.SchemaAttribute, block *tfjson.SchemaBlock) bool {
+		return (attribute != nil && attribute.Deprecated) || (block != nil && block.Deprecated)
+	})
+}
+
+// SensitiveAttributes returns the paths of all the sensitive attributes of the given resource type, sorted.
+func (schemas *ProviderSchemaSet) SensitiveAttributes(resourceType string) []string {
+	return schemas.filterAttributes(resourceType, func(attribute *tfjson.SchemaAttribute, _ *tfjson.SchemaBlock) bool {
+		return attribute != nil && attribute.Sensitive
+	})
+}
+
+// RequiredAttributes returns the paths of all the required attributes of the given resource type, sorted. This
+// includes required attributes of nested blocks, which only have to be set if the nested block is used.
+func (schemas *ProviderSchemaSet) RequiredAttributes(resourceType string) []string {
+	return schemas.filterAttributes(resourceType, func(attribute *tfjson.SchemaAttribute, _ *tfjson.SchemaBlock) bool {
+		return attribute != nil && attribute.Required
+	})
+}
+
+// filterAttributes returns the paths of all the attributes and nested blocks of the given resource type that match the
+// given filter, sorted.
+func (schemas *ProviderSchemaSet) filterAttributes(resourceType string, filter func(attribute *tfjson.SchemaAttribute, block *tfjson.SchemaBlock) bool) []string {
+	out := []string{}
+	schema := schemas.ResourceSchema(resourceType)
+	if schema == nil {
+		return out
+	}
+	walkSchemaBlock(schema.Block, "", func(path string, attribute *tfjson.SchemaAttribute, block *tfjson.SchemaBlock) {
+		if filter(attribute, block) {
+			out = append(out, path)
+		}
+	})
+	sort.Strings(out)
+	return out
+}
+
+// walkSchemaBlock calls fn for every attribute and nested block in the given block, recursively.
+func walkSchemaBlock(block *tfjson.SchemaBlock, prefix string, fn func(path string, attribute *tfjson.SchemaAttribute, block *tfjson.SchemaBlock)) {
+	if block == nil {
+		return
+	}
+	for name, attribute := range block.Attributes {
+		walkSchemaAttribute(attribute, prefix+name, fn)
+	}
+	for name, nestedBlock := range block.NestedBlocks {
+		fn(prefix+name, nil, nestedBlock.Block)
+		walkSchemaBlock(nestedBlock.Block, prefix+name+".", fn)
+	}
+}
+
+func walkSchemaAttribute(attribute *tfjson.SchemaAttribute, path string, fn func(path string, attribute *tfjson.SchemaAttribute, block *tfjson.SchemaBlock)) {
+	fn(path, attribute, nil)
+	if attribute.AttributeNestedType == nil {
+		return
+	}
+	for name, nestedAttribute := range attribute.AttributeNestedType.Attributes {
+		walkSchemaAttribute(nestedAttribute, path+"."+name, fn)
+	}
+}
+
+// lookupSchemaPath returns the attribute or the nested block at the given path in the given block. Both are nil if
+// there is nothing at that path.
+func lookupSchemaPath(block *tfjson.SchemaBlock, path []string) (*tfjson.SchemaAttribute, *tfjson.SchemaBlock) {
+	if block == nil || len(path) == 0 {
+		return nil, nil
+	}
+	if attribute, hasAttribute := block.Attributes[path[0]]; hasAttribute {
+		for _, name := range path[1:] {
+			if attribute.AttributeNestedType == nil {
+				return nil, nil
+			}
+			if attribute, hasAttribute = attribute.AttributeNestedType.Attributes[name]; !hasAttribute {
+				return nil, nil
+			}
+		}
+		return attribute, nil
+	}
+	if nestedBlock, hasBlock := block.NestedBlocks[path[0]]; hasBlock {
+		if len(path) == 1 {
+			return nil, nestedBlock.Block
+		}
+		return lookupSchemaPath(nestedBlock.Block, path[1:])
+	}
+	return nil, nil
+}
+
+// SchemaViolation is an attribute or nested block used in the configuration of a resource that is either not in the
+// schema of the resource type (e.g., because it was removed from the provider), or deprecated.
+type SchemaViolation struct {
+	Address   string // The address of the resource in the module, e.g., aws_instance.web or data.aws_ami.ubuntu
+	Attribute string // The path of the attribute, e.g., ingress.from_port. Empty if the resource type itself is the issue.
+	Filename  string
+	Line      int
+	// Whether the attribute (or resource type) is deprecated, as opposed to missing from the schema.
+	Deprecated bool
+}
+
+func (violation SchemaViolation) String() string {
+	subject := violation.Address
+	if violation.Attribute != "" {
+		subject = fmt.Sprintf("%s (attribute %s)", violation.Address, violation.Attribute)
+	}
+	problem := "is not in the provider schema"
+	if violation.Deprecated {
+		problem = "is deprecated"
+	}
+	return fmt.Sprintf("%s:%d: %s %s", violation.Filename, violation.Line, subject, problem)
+}
+
+// resourceMetaArguments are the arguments and nested blocks of resources that are handled by Terraform itself rather
+// than the provider.
+var resourceMetaArguments = map[string]bool{
+	"count":       true,
+	"for_each":    true,
+	"provider":    true,
+	"depends_on":  true,
+	"lifecycle":   true,
+	"connection":  true,
+	"provisioner": true,
+}
+
+// CheckAttributesAgainstSchemasE checks every attribute and nested block set in the resource and data blocks of the
+// module at options.TerraformDir (in the .tf files of that directory, not in the modules it calls) against the given
+// provider schemas, and returns the ones that are not in the schema or that are deprecated. Resource types that are not
+// in any of the schemas (e.g., because the schemas are for another module) are reported as well.
+func CheckAttributesAgainstSchemasE(options *Options, schemas *ProviderSchemaSet) ([]SchemaViolation, error) {
+	paths, err := filepath.Glob(filepath.Join(options.TerraformDir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	violations := []SchemaViolation{}
+	parser := hclparse.NewParser()
+	for _, path := range paths {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		body, isSyntaxBody := file.Body.(*hclsyntax.Body)
+		if !isSyntaxBody {
+			continue
+		}
+		for _, block := range body.Blocks {
+			if (block.Type != "resource" && block.Type != "data") || len(block.Labels) != 2 {
+				continue
+			}
+			violations = append(violations, checkResourceBlockAgainstSchemas(block, schemas)...)
+		}
+	}
+	return violations, nil
+}
+
+func checkResourceBlockAgainstSchemas(block *hclsyntax.Block, schemas *ProviderSchemaSet) []SchemaViolation {
+	resourceType := block.Labels[0]
+	address := fmt.Sprintf("%s.%s", resourceType, block.Labels[1])
+	schema := schemas.ResourceSchema(resourceType)
+	if block.Type == "data" {
+		address = "data." + address
+		schema = schemas.DataSourceSchema(resourceType)
+	}
+
+	pos := block.DefRange()
+	if schema == nil || schema.Block == nil {
+		return []SchemaViolation{{Address: address, Filename: pos.Filename, Line: pos.Start.Line}}
+	}
+	violations := []SchemaViolation{}
+	if schema.Block.Deprecated {
+		violations = append(violations, SchemaViolation{Address: address, Filename: pos.Filename, Line: pos.Start.Line, Deprecated: true})
+	}
+
+	check := func(path []string, rng hcl.Range) {
+		attribute, nestedBlock := lookupSchemaPath(schema.Block, path)
+		violation := SchemaViolation{Address: address, Attribute: strings.Join(path, "."), Filename: rng.Filename, Line: rng.Start.Line}
+		switch {
+		case attribute == nil && nestedBlock == nil:
+			violations = append(violations, violation)
+		case (attribute != nil && attribute.Deprecated) || (nestedBlock != nil && nestedBlock.Deprecated):
+			violation.Deprecated = true
+			violations = append(violations, violation)
+		}
+	}
+
+	var walk func(body *hclsyntax.Body, prefix []string)
+	walk = func(body *hclsyntax.Body, prefix []string) {
+		for name, attribute := range body.Attributes {
+			if len(prefix) == 0 && resourceMetaArguments[name] {
+				continue
+			}
+			check(append(append([]string{}, prefix...), name), attribute.SrcRange)
+		}
+		for _, nestedBlock := range body.Blocks {
+			if len(prefix) == 0 && resourceMetaArguments[nestedBlock.Type] {
+				continue
+			}
+			name := nestedBlock.Type
+			nestedBody := nestedBlock.Body
+			if name == "dynamic" && len(nestedBlock.Labels) == 1 {
+				// A dynamic block generates nested blocks named after its label from its content block.
+				name = nestedBlock.Labels[0]
+				nestedBody = dynamicContentBody(nestedBlock.Body)
+			}
+			path := append(append([]string{}, prefix...), name)
+			check(path, nestedBlock.DefRange())
+			if nestedBody != nil {
+				walk(nestedBody, path)
+			}
+		}
+	}
+	walk(block.Body, []string{})
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Line != violations[j].Line {
+			return violations[i].Line < violations[j].Line
+		}
+		return violations[i].Attribute < violations[j].Attribute
+	})
+	return violations
+}
+
+// dynamicContentBody returns the body of the content block of a dynamic block, or nil if it has none.
+func dynamicContentBody(body *hclsyntax.Body) *hclsyntax.Body {
+	for _, block := range body.Blocks {
+		if block.Type == "content" {
+			return block.Body
+		}
+	}
+	return nil
+}
+
+// AssertAttributesMatchSchemas checks that every attribute and nested block set in the resource and data blocks of the
+// module at options.TerraformDir is in the given provider schemas and not deprecated, failing the test with the list of
+// violations otherwise. See CheckAttributesAgainstSchemasE for more info.
+func AssertAttributesMatchSchemas(t testing.TestingT, options *Options, schemas *ProviderSchemaSet) bool {
+	violations, err := CheckAttributesAgainstSchemasE(options, schemas)
+	if !assert.NoError(t, err) {
+		return false
+	}
+	return assert.Emptyf(t, violations, "Resources use attributes that are deprecated or not in the provider schemas:\n%s", formatSchemaViolations(violations))
+}
+
+// RequireAttributesMatchSchemas checks that every attribute and nested block set in the resource and data blocks of
+// the module at options.TerraformDir is in the given provider schemas and not deprecated, failing and halting the test
+// with the list of violations otherwise. See CheckAttributesAgainstSchemasE for more info.
+func RequireAttributesMatchSchemas(t testing.TestingT, options *Options, schemas *ProviderSchemaSet) {
+	violations, err := CheckAttributesAgainstSchemasE(options, schemas)
+	require.NoError(t, err)
+	require.Emptyf(t, violations, "Resources use attributes that are deprecated or not in the provider schemas:\n%s", formatSchemaViolations(violations))
+}
+
+func formatSchemaViolations(violations []SchemaViolation) string {
+	lines := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		lines = append(lines, violation.String())
+	}
+	return strings.Join(lines, "\n")
+}
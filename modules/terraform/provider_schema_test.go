@@ -0,0 +1,158 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProviderSchemasJSON = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/example/cloud": {
+      "provider": {"version": 0, "block": {"attributes": {"region": {"type": "string", "optional": true}}}},
+      "resource_schemas": {
+        "cloud_instance": {
+          "version": 1,
+          "block": {
+            "attributes": {
+              "id": {"type": "string", "computed": true},
+              "image": {"type": "string", "required": true},
+              "size": {"type": "string", "optional": true, "deprecated": true},
+              "password": {"type": "string", "optional": true, "sensitive": true},
+              "settings": {
+                "nested_type": {
+                  "nesting_mode": "single",
+                  "attributes": {
+                    "token": {"type": "string", "required": true, "sensitive": true}
+                  }
+                },
+                "optional": true
+              }
+            },
+            "block_types": {
+              "disk": {
+                "nesting_mode": "list",
+                "block": {
+                  "attributes": {
+                    "size_gb": {"type": "number", "required": true},
+                    "legacy_type": {"type": "string", "optional": true, "deprecated": true}
+                  }
+                }
+              }
+            }
+          }
+        },
+        "cloud_legacy_volume": {"version": 0, "block": {"attributes": {"name": {"type": "string", "optional": true}}, "deprecated": true}}
+      },
+      "data_source_schemas": {
+        "cloud_image": {"version": 0, "block": {"attributes": {"name": {"type": "string", "required": true}}}}
+      }
+    }
+  }
+}`
+
+func TestProviderSchemaLookups(t *testing.T) {
+	t.Parallel()
+
+	schemas, err := ParseProviderSchemasJSON(testProviderSchemasJSON)
+	require.NoError(t, err)
+
+	require.NotNil(t, schemas.ResourceSchema("cloud_instance"))
+	assert.Nil(t, schemas.ResourceSchema("cloud_image"))
+	require.NotNil(t, schemas.DataSourceSchema("cloud_image"))
+	assert.True(t, schemas.IsResourceDeprecated("cloud_legacy_volume"))
+	assert.False(t, schemas.IsResourceDeprecated("cloud_instance"))
+
+	require.NotNil(t, schemas.Attribute("cloud_instance", "disk.size_gb"))
+	assert.Nil(t, schemas.Attribute("cloud_instance", "disk"))
+	assert.Nil(t, schemas.Attribute("cloud_instance", "does_not_exist"))
+	assert.True(t, schemas.IsAttributeDeprecated("cloud_instance", "size"))
+	assert.True(t, schemas.IsAttributeDeprecated("cloud_instance", "disk.legacy_type"))
+	assert.False(t, schemas.IsAttributeDeprecated("cloud_instance", "image"))
+	assert.True(t, schemas.IsAttributeSensitive("cloud_instance", "settings.token"))
+	assert.True(t, schemas.IsAttributeRequired("cloud_instance", "image"))
+	assert.False(t, schemas.IsAttributeRequired("cloud_instance", "size"))
+
+	assert.Equal(t, []string{"disk.legacy_type", "size"}, schemas.DeprecatedAttributes("cloud_instance"))
+	assert.Equal(t, []string{"password", "settings.token"}, schemas.SensitiveAttributes("cloud_instance"))
+	assert.Equal(t, []string{"disk.size_gb", "image", "settings.token"}, schemas.RequiredAttributes("cloud_instance"))
+	assert.Empty(t, schemas.RequiredAttributes("does_not_exist"))
+}
+
+func TestCheckAttributesAgainstSchemas(t *testing.T) {
+	t.Parallel()
+
+	schemas, err := ParseProviderSchemasJSON(testProviderSchemasJSON)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+data "cloud_image" "base" {
+  name = "ubuntu"
+}
+
+resource "cloud_instance" "web" {
+  count = 2
+  image = data.cloud_image.base.id
+  size  = "small"
+  flavor = "large"
+
+  settings = {
+    token = "secret"
+  }
+
+  dynamic "disk" {
+    for_each = [10, 20]
+    content {
+      size_gb     = disk.value
+      legacy_type = "hdd"
+    }
+  }
+
+  lifecycle {
+    ignore_changes = [size]
+  }
+}
+
+resource "cloud_legacy_volume" "data" {}
+
+resource "other_thing" "this" {}
+`), 0644))
+
+	violations, err := CheckAttributesAgainstSchemasE(&Options{TerraformDir: dir}, schemas)
+	require.NoError(t, err)
+
+	mainTf := filepath.Join(dir, "main.tf")
+	assert.Equal(t, []SchemaViolation{
+		{Address: "cloud_instance.web", Attribute: "size", Filename: mainTf, Line: 9, Deprecated: true},
+		{Address: "cloud_instance.web", Attribute: "flavor", Filename: mainTf, Line: 10},
+		{Address: "cloud_instance.web", Attribute: "disk.legacy_type", Filename: mainTf, Line: 20, Deprecated: true},
+		{Address: "cloud_legacy_volume.data", Filename: mainTf, Line: 29, Deprecated: true},
+		{Address: "other_thing.this", Filename: mainTf, Line: 31},
+	}, violations)
+	assert.Equal(t, mainTf+":10: cloud_instance.web (attribute flavor) is not in the provider schema", violations[1].String())
+
+	mock := &mockT{}
+	AssertAttributesMatchSchemas(mock, &Options{TerraformDir: dir}, schemas)
+	assert.True(t, mock.Failed)
+}
+
+func TestProviderSchemas(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-basic-configuration", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{TerraformDir: testFolder}
+	Init(t, options)
+	schemas := ProviderSchemas(t, options)
+
+	require.NotNil(t, schemas.ResourceSchema("null_resource"))
+	assert.NotNil(t, schemas.Attribute("null_resource", "triggers"))
+	AssertAttributesMatchSchemas(t, options, schemas)
+}
@@ -0,0 +1,168 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RefactorChange is a resource that a plan moves, imports, or changes.
+type RefactorChange struct {
+	Address string
+	Action  ChangeAction
+	// The address the resource had in the prior state, if it is moved (e.g., with a moved block).
+	PreviousAddress string
+	// The ID the resource is imported with, if it is imported with an import block.
+	ImportID string
+}
+
+// IsMove returns true if the resource is moved to a new address.
+func (change RefactorChange) IsMove() bool {
+	return change.PreviousAddress != ""
+}
+
+// IsImport returns true if the resource is imported.
+func (change RefactorChange) IsImport() bool {
+	return change.ImportID != ""
+}
+
+func (change RefactorChange) String() string {
+	description := string(change.Action)
+	if change.IsMove() {
+		description += ", moved from " + change.PreviousAddress
+	}
+	if change.IsImport() {
+		description += ", imported with ID " + change.ImportID
+	}
+	return fmt.Sprintf("%s (%s)", change.Address, description)
+}
+
+// RefactorReport classifies the changes a plan makes to existing state after a refactor.
+type RefactorReport struct {
+	// The moved, imported, and changed resources, sorted by address. Resources that are left untouched are not included.
+	Changes []RefactorChange
+}
+
+// Moves returns a map from the previous address to the new address of every moved resource.
+func (report *RefactorReport) Moves() map[string]string {
+	out := map[string]string{}
+	for _, change := range report.Changes {
+		if change.IsMove() {
+			out[change.PreviousAddress] = change.Address
+		}
+	}
+	return out
+}
+
+// Imports returns a map from the address to the import ID of every imported resource.
+func (report *RefactorReport) Imports() map[string]string {
+	out := map[string]string{}
+	for _, change := range report.Changes {
+		if change.IsImport() {
+			out[change.Address] = change.ImportID
+		}
+	}
+	return out
+}
+
+// Violations returns a description of every way in which the plan does more than move resources as described by
+// expectedMoves (a map from previous address to new address) and import resources: resources that are created,
+// deleted, or replaced, moves that are not expected, and expected moves that do not happen. In-place updates of moved
+// and imported resources are allowed, but other in-place updates are not.
+func (report *RefactorReport) Violations(expectedMoves map[string]string) []string {
+	out := []string{}
+	actualMoves := report.Moves()
+	for _, change := range report.Changes {
+		switch {
+		case change.Action == ChangeActionCreate || change.Action == ChangeActionDelete || change.Action == ChangeActionReplace:
+			out = append(out, fmt.Sprintf("%s is recreated or destroyed", change))
+		case change.IsMove() && expectedMoves[change.PreviousAddress] != change.Address:
+			out = append(out, fmt.Sprintf("%s is not an expected move", change))
+		case change.Action == ChangeActionUpdate && !change.IsMove() && !change.IsImport():
+			out = append(out, fmt.Sprintf("%s is updated in place", change))
+		}
+	}
+
+	from := make([]string, 0, len(expectedMoves))
+	for previousAddress := range expectedMoves {
+		from = append(from, previousAddress)
+	}
+	sort.Strings(from)
+	for _, previousAddress := range from {
+		if actualMoves[previousAddress] != expectedMoves[previousAddress] {
+			out = append(out, fmt.Sprintf("expected %s to be moved to %s, but it was not", previousAddress, expectedMoves[previousAddress]))
+		}
+	}
+	return out
+}
+
+// AssertRefactorOnlyMovesAndImports checks that the plan in the report only moves resources as described by
+// expectedMoves (a map from previous address to new address) and imports resources, failing the test with the list of
+// violations if it does anything else.
+func AssertRefactorOnlyMovesAndImports(t testing.TestingT, report *RefactorReport, expectedMoves map[string]string) bool {
+	violations := report.Violations(expectedMoves)
+	return assert.Emptyf(t, violations, "Refactor does more than the expected moves and imports:\n%s", strings.Join(violations, "\n"))
+}
+
+// RequireRefactorOnlyMovesAndImports checks that the plan in the report only moves resources as described by
+// expectedMoves (a map from previous address to new address) and imports resources, failing and halting the test with
+// the list of violations if it does anything else.
+func RequireRefactorOnlyMovesAndImports(t testing.TestingT, report *RefactorReport, expectedMoves map[string]string) {
+	violations := report.Violations(expectedMoves)
+	require.Emptyf(t, violations, "Refactor does more than the expected moves and imports:\n%s", strings.Join(violations, "\n"))
+}
+
+// GetRefactorReport classifies the resource changes in the given plan into moves, imports, and other changes.
+func GetRefactorReport(plan *PlanStruct) *RefactorReport {
+	report := &RefactorReport{Changes: []RefactorChange{}}
+	for _, change := range plan.Resources().Changes() {
+		refactorChange := RefactorChange{
+			Address:         change.Address,
+			Action:          GetChangeAction(change),
+			PreviousAddress: plan.ResourcePreviousAddressMap[change.Address],
+			ImportID:        plan.ResourceImportIDMap[change.Address],
+		}
+		if refactorChange.Action == ChangeActionNoOp && !refactorChange.IsMove() && !refactorChange.IsImport() {
+			continue
+		}
+		if refactorChange.Action == ChangeActionRead {
+			continue
+		}
+		report.Changes = append(report.Changes, refactorChange)
+	}
+	return report
+}
+
+// RefactorTest runs terraform init and apply on the module in options.TerraformDir as of oldRef in its git repo, then
+// runs terraform init, plan, and show on the module as of newRef (which typically adds moved and import blocks) against
+// the resulting state, and returns a report of the moves, imports, and other changes in the plan. As with UpgradeTest,
+// the refs are checked out in a temporary git worktree, so the checkout that contains options.TerraformDir is not
+// touched, and the resources created by apply are destroyed before this returns. This will fail the test if there is
+// an error.
+//
+// Example:
+//
+//	report := terraform.RefactorTest(t, options, "v1.0.0", "v2.0.0")
+//	terraform.AssertRefactorOnlyMovesAndImports(t, report, map[string]string{
+//		"aws_s3_bucket.logs": "module.logs.aws_s3_bucket.this",
+//	})
+func RefactorTest(t testing.TestingT, options *Options, oldRef string, newRef string) *RefactorReport {
+	report, err := RefactorTestE(t, options, oldRef, newRef)
+	require.NoError(t, err)
+	return report
+}
+
+// RefactorTestE runs terraform init and apply on the module in options.TerraformDir as of oldRef in its git repo, then
+// runs terraform init, plan, and show on the module as of newRef against the resulting state, and returns a report of
+// the moves, imports, and other changes in the plan. See RefactorTest for more info.
+func RefactorTestE(t testing.TestingT, options *Options, oldRef string, newRef string) (*RefactorReport, error) {
+	plan, err := upgradePlanE(t, options, oldRef, newRef)
+	if err != nil {
+		return nil, err
+	}
+	return GetRefactorReport(plan), nil
+}
@@ -0,0 +1,120 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/internal/testgit"
+)
+
+const testRefactorPlanJSON = `{
+  "format_version": "1.2",
+  "terraform_version": "1.5.7",
+  "resource_changes": [
+    {
+      "address": "module.logs.aws_s3_bucket.this",
+      "previous_address": "aws_s3_bucket.logs",
+      "module_address": "module.logs",
+      "type": "aws_s3_bucket",
+      "name": "this",
+      "change": {"actions": ["no-op"], "before": {"bucket": "logs"}, "after": {"bucket": "logs"}}
+    },
+    {
+      "address": "aws_iam_role.renamed",
+      "previous_address": "aws_iam_role.old",
+      "type": "aws_iam_role",
+      "name": "renamed",
+      "change": {"actions": ["update"], "before": {"tags": {}}, "after": {"tags": {"a": "b"}}}
+    },
+    {
+      "address": "aws_vpc.main",
+      "type": "aws_vpc",
+      "name": "main",
+      "change": {"actions": ["no-op"], "before": {"id": "vpc-123"}, "after": {"id": "vpc-123"}, "importing": {"id": "vpc-123"}}
+    },
+    {
+      "address": "aws_instance.web",
+      "type": "aws_instance",
+      "name": "web",
+      "change": {"actions": ["delete", "create"], "before": {"ami": "ami-1"}, "after": {"ami": "ami-2"}}
+    },
+    {
+      "address": "aws_security_group.untouched",
+      "type": "aws_security_group",
+      "name": "untouched",
+      "change": {"actions": ["no-op"], "before": {}, "after": {}}
+    }
+  ]
+}`
+
+func TestGetRefactorReport(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlanJSON(testRefactorPlanJSON)
+	require.NoError(t, err)
+	assert.NotContains(t, plan.ResourceImportIDMap, "aws_security_group.untouched")
+
+	report := GetRefactorReport(plan)
+	assert.Equal(t, []RefactorChange{
+		{Address: "aws_iam_role.renamed", Action: ChangeActionUpdate, PreviousAddress: "aws_iam_role.old"},
+		{Address: "aws_instance.web", Action: ChangeActionReplace},
+		{Address: "aws_vpc.main", Action: ChangeActionNoOp, ImportID: "vpc-123"},
+		{Address: "module.logs.aws_s3_bucket.this", Action: ChangeActionNoOp, PreviousAddress: "aws_s3_bucket.logs"},
+	}, report.Changes)
+	assert.Equal(t, map[string]string{
+		"aws_iam_role.old":   "aws_iam_role.renamed",
+		"aws_s3_bucket.logs": "module.logs.aws_s3_bucket.this",
+	}, report.Moves())
+	assert.Equal(t, map[string]string{"aws_vpc.main": "vpc-123"}, report.Imports())
+
+	assert.Equal(t, []string{
+		"aws_instance.web (replace) is recreated or destroyed",
+		"module.logs.aws_s3_bucket.this (no-op, moved from aws_s3_bucket.logs) is not an expected move",
+		"expected aws_s3_bucket.logs to be moved to module.logs.aws_s3_bucket.logs, but it was not",
+	}, report.Violations(map[string]string{
+		"aws_iam_role.old":   "aws_iam_role.renamed",
+		"aws_s3_bucket.logs": "module.logs.aws_s3_bucket.logs",
+	}))
+
+	failingT := &mockT{}
+	AssertRefactorOnlyMovesAndImports(failingT, report, report.Moves())
+	assert.True(t, failingT.Failed)
+	require.Len(t, failingT.Messages, 1)
+	assert.Contains(t, failingT.Messages[0], "aws_instance.web (replace) is recreated or destroyed")
+}
+
+func TestRefactorTest(t *testing.T) {
+	t.Parallel()
+
+	repoDir := t.TempDir()
+	mainFile := filepath.Join(repoDir, "main.tf")
+	testgit.Run(t, repoDir, "init", "--quiet")
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, ".gitignore"), []byte(".terraform*\n*.tfstate*\n"), 0644))
+	require.NoError(t, os.WriteFile(mainFile, []byte(`
+resource "null_resource" "old_name" {}
+`), 0644))
+	testgit.Run(t, repoDir, "add", ".")
+	testgit.Run(t, repoDir, "commit", "--quiet", "-m", "v1")
+	testgit.Run(t, repoDir, "tag", "v1")
+	require.NoError(t, os.WriteFile(mainFile, []byte(`
+resource "null_resource" "new_name" {}
+
+moved {
+  from = null_resource.old_name
+  to   = null_resource.new_name
+}
+`), 0644))
+	testgit.Run(t, repoDir, "commit", "--quiet", "-am", "v2")
+	testgit.Run(t, repoDir, "tag", "v2")
+
+	options := &Options{
+		TerraformDir: repoDir,
+	}
+
+	report := RefactorTest(t, options, "v1", "v2")
+	RequireRefactorOnlyMovesAndImports(t, report, map[string]string{"null_resource.old_name": "null_resource.new_name"})
+}
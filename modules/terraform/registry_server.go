@@ -0,0 +1,433 @@
+package terraform
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// The paths under which the registry server serves the provider network mirror, the module registry API, and the
+// module archives.
+const (
+	registryProvidersPath = "/providers/"
+	registryModulesPath   = "/v1/modules/"
+	registryArchivesPath  = "/archives/modules/"
+)
+
+// registryHostnames are the hostnames of the public registries whose module registry API the registry server stands in
+// for. Terraform uses the first one by default and OpenTofu the second one.
+var registryHostnames = []string{"registry.terraform.io", "registry.opentofu.org"}
+
+// RegistryServer is an in-process stand-in for the public provider and module registries, which allows to run terraform
+// init without network access (e.g., on air-gapped CI runners) and without the transient registry errors of
+// DefaultRetryableTerraformErrors. It serves:
+//
+//   - The providers in ProvidersDir with the provider network mirror protocol
+//     (https://developer.hashicorp.com/terraform/internals/provider-network-mirror-protocol). ProvidersDir uses the
+//     layout created by `terraform providers mirror`: HOSTNAME/NAMESPACE/TYPE/terraform-provider-TYPE_VERSION_OS_ARCH.zip.
+//   - The modules in ModulesDir with the module registry protocol
+//     (https://developer.hashicorp.com/terraform/internals/module-registry-protocol), in place of the public registry, so
+//     that module sources such as NAMESPACE/NAME/SYSTEM work unchanged. ModulesDir uses the layout
+//     NAMESPACE/NAME/SYSTEM/VERSION.zip, with the files of the module at the root of each zip. See AddModule.
+//
+// Terraform only talks to registries and mirrors over HTTPS, so the server uses a self-signed certificate, which is
+// trusted through the SSL_CERT_DIR env var. This works on Linux and other Unix systems, but not on macOS or Windows,
+// where Terraform only trusts the certificates of the system keychain.
+//
+// Example:
+//
+//	registry := terraform.RunRegistryServer(t, "/opt/terraform/providers", "/opt/terraform/modules")
+//	defer registry.Close()
+//
+//	options := &terraform.Options{TerraformDir: "../examples/terraform-hello-world-example"}
+//	registry.Configure(t, options)
+//	terraform.InitAndApply(t, options)
+type RegistryServer struct {
+	// The URL of the server (e.g., https://127.0.0.1:54321).
+	URL string
+	// The directory of the provider zips, in the layout of a filesystem mirror. Optional.
+	ProvidersDir string
+	// The directory of the module zips. Optional.
+	ModulesDir string
+
+	server    *httptest.Server
+	configDir string
+}
+
+// RunRegistryServer starts a registry server that serves the providers in providersDir and the modules in modulesDir,
+// either of which can be empty. Make sure to call the Close() method on the server when you're done!
+func RunRegistryServer(t testing.TestingT, providersDir string, modulesDir string) *RegistryServer {
+	registry, err := RunRegistryServerE(t, providersDir, modulesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+// RunRegistryServerE starts a registry server that serves the providers in providersDir and the modules in modulesDir,
+// either of which can be empty. Make sure to call the Close() method on the server when you're done!
+func RunRegistryServerE(t testing.TestingT, providersDir string, modulesDir string) (*RegistryServer, error) {
+	configDir, err := os.MkdirTemp("", "terratest-registry-")
+	if err != nil {
+		return nil, err
+	}
+	registry := &RegistryServer{configDir: configDir}
+	if registry.ProvidersDir, err = absPathOrEmpty(providersDir); err != nil {
+		os.RemoveAll(configDir)
+		return nil, err
+	}
+	if registry.ModulesDir, err = absPathOrEmpty(modulesDir); err != nil {
+		os.RemoveAll(configDir)
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(registryProvidersPath, registry.handleProviders)
+	mux.HandleFunc(registryModulesPath, registry.handleModules)
+	mux.HandleFunc(registryArchivesPath, registry.handleModuleArchives)
+	registry.server = httptest.NewTLSServer(mux)
+	registry.URL = registry.server.URL
+
+	logger.Logf(t, "Started registry server at %s, serving providers from '%s' and modules from '%s'", registry.URL, registry.ProvidersDir, registry.ModulesDir)
+	return registry, nil
+}
+
+func absPathOrEmpty(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	return filepath.Abs(path)
+}
+
+// Close shuts down the server and removes the CLI configuration it generated.
+func (registry *RegistryServer) Close() {
+	registry.server.Close()
+	os.RemoveAll(registry.configDir)
+}
+
+// CLIConfig returns a Terraform CLI configuration (the contents of a .terraformrc file) that installs all providers
+// from the network mirror of this server, and gets the modules of the public registry from this server.
+func (registry *RegistryServer) CLIConfig() string {
+	var sb strings.Builder
+	sb.WriteString("disable_checkpoint = true\n\n")
+	sb.WriteString("provider_installation {\n")
+	fmt.Fprintf(&sb, "  network_mirror {\n    url = %q\n  }\n", registry.URL+registryProvidersPath)
+	sb.WriteString("}\n")
+	for _, hostname := range registryHostnames {
+		fmt.Fprintf(&sb, "\nhost %q {\n  services = {\n    \"modules.v1\" = %q\n  }\n}\n", hostname, registry.URL+registryModulesPath)
+	}
+	return sb.String()
+}
+
+// EnvVars writes the CLI configuration of this server and its certificate to a temp dir, and returns the environment
+// variables that make Terraform use them (TF_CLI_CONFIG_FILE and SSL_CERT_DIR).
+func (registry *RegistryServer) EnvVars() (map[string]string, error) {
+	cliConfigPath := filepath.Join(registry.configDir, cliConfigFileName)
+	if err := os.WriteFile(cliConfigPath, []byte(registry.CLIConfig()), 0644); err != nil {
+		return nil, err
+	}
+
+	certDir := filepath.Join(registry.configDir, "certs")
+	if err := os.MkdirAll(certDir, 0755); err != nil {
+		return nil, err
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: registry.server.Certificate().Raw})
+	if err := os.WriteFile(filepath.Join(certDir, "terratest-registry.pem"), cert, 0644); err != nil {
+		return nil, err
+	}
+
+	// Unlike SSL_CERT_FILE, SSL_CERT_DIR adds to the CA bundle of the system rather than replacing it, so that providers
+	// can still talk to real APIs
+	if existing := os.Getenv("SSL_CERT_DIR"); existing != "" {
+		certDir = certDir + string(os.PathListSeparator) + existing
+	}
+	return map[string]string{"TF_CLI_CONFIG_FILE": cliConfigPath, "SSL_CERT_DIR": certDir}, nil
+}
+
+// Configure sets the env vars returned by EnvVars on the given options, so that Terraform gets providers and modules
+// from this server. This will fail the test if there is an error.
+func (registry *RegistryServer) Configure(t testing.TestingT, options *Options) {
+	if err := registry.ConfigureE(options); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ConfigureE sets the env vars returned by EnvVars on the given options, so that Terraform gets providers and modules
+// from this server.
+func (registry *RegistryServer) ConfigureE(options *Options) error {
+	envVars, err := registry.EnvVars()
+	if err != nil {
+		return err
+	}
+	if options.EnvVars == nil {
+		options.EnvVars = map[string]string{}
+	}
+	for key, val := range envVars {
+		options.EnvVars[key] = val
+	}
+	return nil
+}
+
+// AddModule zips the module in moduleDir and adds it to ModulesDir as the given version of the module with the given
+// address (NAMESPACE/NAME/SYSTEM, e.g., gruntwork-io/vpc/aws). This will fail the test if there is an error.
+func (registry *RegistryServer) AddModule(t testing.TestingT, address string, version string, moduleDir string) {
+	if err := registry.AddModuleE(address, version, moduleDir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AddModuleE zips the module in moduleDir and adds it to ModulesDir as the given version of the module with the given
+// address (NAMESPACE/NAME/SYSTEM, e.g., gruntwork-io/vpc/aws). The .terraform folder of the module, if any, is left out.
+func (registry *RegistryServer) AddModuleE(address string, version string, moduleDir string) error {
+	if registry.ModulesDir == "" {
+		return fmt.Errorf("the registry server was started without a modules dir")
+	}
+	if len(strings.Split(address, "/")) != 3 {
+		return fmt.Errorf("invalid module address '%s': expected NAMESPACE/NAME/SYSTEM", address)
+	}
+
+	zipPath := filepath.Join(registry.ModulesDir, filepath.FromSlash(address), version+".zip")
+	if err := os.MkdirAll(filepath.Dir(zipPath), 0755); err != nil {
+		return err
+	}
+	return zipDir(moduleDir, zipPath)
+}
+
+// zipDir writes the files in dir, except for the .terraform folder, to a zip file at zipPath.
+func zipDir(dir string, zipPath string) error {
+	zipFile, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	defer zipFile.Close()
+
+	writer := zip.NewWriter(zipFile)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		entry, err := writer.Create(filepath.ToSlash(relPath))
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(entry, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// handleProviders implements the provider network mirror protocol:
+//
+//	GET /providers/HOSTNAME/NAMESPACE/TYPE/index.json lists the available versions
+//	GET /providers/HOSTNAME/NAMESPACE/TYPE/VERSION.json lists the archives of a version, per platform
+//	GET /providers/HOSTNAME/NAMESPACE/TYPE/ARCHIVE.zip downloads an archive
+func (registry *RegistryServer) handleProviders(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, registryProvidersPath), "/")
+	if registry.ProvidersDir == "" || len(parts) != 4 || !validPathSegments(parts) {
+		http.NotFound(w, r)
+		return
+	}
+	providerDir := filepath.Join(registry.ProvidersDir, parts[0], parts[1], parts[2])
+	file := parts[3]
+
+	if strings.HasSuffix(file, ".zip") {
+		http.ServeFile(w, r, filepath.Join(providerDir, file))
+		return
+	}
+
+	archives, err := providerArchives(providerDir, parts[2])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(archives) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	if file == "index.json" {
+		versions := map[string]interface{}{}
+		for version := range archives {
+			versions[version] = map[string]interface{}{}
+		}
+		writeRegistryJSON(w, map[string]interface{}{"versions": versions})
+		return
+	}
+
+	version := strings.TrimSuffix(file, ".json")
+	platforms, hasVersion := archives[version]
+	if !hasVersion || !strings.HasSuffix(file, ".json") {
+		http.NotFound(w, r)
+		return
+	}
+	out := map[string]interface{}{}
+	for platform, archive := range platforms {
+		hash, err := zipHash(filepath.Join(providerDir, archive))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out[platform] = map[string]interface{}{"url": archive, "hashes": []string{hash}}
+	}
+	writeRegistryJSON(w, map[string]interface{}{"archives": out})
+}
+
+// providerArchives returns the names of the provider archives in the given directory, keyed by version and then by
+// platform (e.g., linux_amd64).
+func providerArchives(providerDir string, providerType string) (map[string]map[string]string, error) {
+	matches, err := filepath.Glob(filepath.Join(providerDir, "*.zip"))
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("terraform-provider-%s_", providerType)
+	out := map[string]map[string]string{}
+	for _, match := range matches {
+		archive := filepath.Base(match)
+		parts := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(archive, prefix), ".zip"), "_", 2)
+		if !strings.HasPrefix(archive, prefix) || len(parts) != 2 {
+			continue
+		}
+		if out[parts[0]] == nil {
+			out[parts[0]] = map[string]string{}
+		}
+		out[parts[0]][parts[1]] = archive
+	}
+	return out, nil
+}
+
+// zipHash returns the hash of the given provider archive in the zh: (legacy zip hash) format Terraform verifies.
+func zipHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return "zh:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// handleModules implements the module registry protocol:
+//
+//	GET /v1/modules/NAMESPACE/NAME/SYSTEM/versions lists the available versions
+//	GET /v1/modules/NAMESPACE/NAME/SYSTEM/VERSION/download returns the URL of the archive of a version in the
+//	X-Terraform-Get header
+func (registry *RegistryServer) handleModules(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, registryModulesPath), "/")
+	if registry.ModulesDir == "" || len(parts) < 4 || !validPathSegments(parts) {
+		http.NotFound(w, r)
+		return
+	}
+	address := strings.Join(parts[:3], "/")
+	moduleDir := filepath.Join(registry.ModulesDir, parts[0], parts[1], parts[2])
+
+	switch {
+	case len(parts) == 4 && parts[3] == "versions":
+		versions, err := moduleVersions(moduleDir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(versions) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		moduleVersions := []map[string]string{}
+		for _, version := range versions {
+			moduleVersions = append(moduleVersions, map[string]string{"version": version})
+		}
+		writeRegistryJSON(w, map[string]interface{}{
+			"modules": []map[string]interface{}{{"source": address, "versions": moduleVersions}},
+		})
+
+	case len(parts) == 5 && parts[4] == "download":
+		if _, err := os.Stat(filepath.Join(moduleDir, parts[3]+".zip")); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Terraform-Get", fmt.Sprintf("%s%s%s/%s.zip", registry.URL, registryArchivesPath, address, parts[3]))
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleModuleArchives serves the module zips at /archives/modules/NAMESPACE/NAME/SYSTEM/VERSION.zip.
+func (registry *RegistryServer) handleModuleArchives(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, registryArchivesPath), "/")
+	if registry.ModulesDir == "" || len(parts) != 4 || !validPathSegments(parts) || !strings.HasSuffix(parts[3], ".zip") {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(registry.ModulesDir, parts[0], parts[1], parts[2], parts[3]))
+}
+
+// moduleVersions returns the versions of the module zips in the given directory, sorted.
+func moduleVersions(moduleDir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(moduleDir, "*.zip"))
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{}
+	for _, match := range matches {
+		versions = append(versions, strings.TrimSuffix(filepath.Base(match), ".zip"))
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// validPathSegments returns false if any of the given URL path segments is empty or could escape the served
+// directories.
+func validPathSegments(parts []string) bool {
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." || strings.Contains(part, `\`) {
+			return false
+		}
+	}
+	return true
+}
+
+func writeRegistryJSON(w http.ResponseWriter, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,143 @@
+package terraform
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getFromRegistry(t *testing.T, registry *RegistryServer, path string) (*http.Response, string) {
+	response, err := registry.server.Client().Get(registry.URL + path)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response, string(body)
+}
+
+func TestRegistryServerProviderMirror(t *testing.T) {
+	t.Parallel()
+
+	providersDir := t.TempDir()
+	providerDir := filepath.Join(providersDir, "registry.terraform.io", "hashicorp", "null")
+	require.NoError(t, os.MkdirAll(providerDir, 0755))
+	for _, archive := range []string{
+		"terraform-provider-null_3.2.1_linux_amd64.zip",
+		"terraform-provider-null_3.2.1_darwin_arm64.zip",
+		"terraform-provider-null_3.1.0_linux_amd64.zip",
+		"not-a-provider.zip",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(providerDir, archive), []byte(archive), 0644))
+	}
+
+	registry := RunRegistryServer(t, providersDir, "")
+	defer registry.Close()
+
+	response, body := getFromRegistry(t, registry, "/providers/registry.terraform.io/hashicorp/null/index.json")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"versions": {"3.2.1": {}, "3.1.0": {}}}`, body)
+
+	response, body = getFromRegistry(t, registry, "/providers/registry.terraform.io/hashicorp/null/3.2.1.json")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	archives := struct {
+		Archives map[string]struct {
+			URL    string   `json:"url"`
+			Hashes []string `json:"hashes"`
+		} `json:"archives"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(body), &archives))
+	require.Len(t, archives.Archives, 2)
+	assert.Equal(t, "terraform-provider-null_3.2.1_linux_amd64.zip", archives.Archives["linux_amd64"].URL)
+	assert.Len(t, archives.Archives["darwin_arm64"].Hashes, 1)
+
+	response, body = getFromRegistry(t, registry, "/providers/registry.terraform.io/hashicorp/null/terraform-provider-null_3.2.1_linux_amd64.zip")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "terraform-provider-null_3.2.1_linux_amd64.zip", body)
+
+	for _, path := range []string{
+		"/providers/registry.terraform.io/hashicorp/null/9.9.9.json",
+		"/providers/registry.terraform.io/hashicorp/random/index.json",
+		"/providers/registry.terraform.io/hashicorp/../null/index.json",
+	} {
+		response, _ = getFromRegistry(t, registry, path)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode, path)
+	}
+}
+
+func TestRegistryServerModules(t *testing.T) {
+	t.Parallel()
+
+	registry := RunRegistryServer(t, "", t.TempDir())
+	defer registry.Close()
+
+	registry.AddModule(t, "terratest/basic/null", "1.0.0", "../../test/fixtures/terraform-basic-configuration")
+	registry.AddModule(t, "terratest/basic/null", "1.1.0", "../../test/fixtures/terraform-basic-configuration")
+	assert.Error(t, registry.AddModuleE("terratest/basic", "1.0.0", "../../test/fixtures/terraform-basic-configuration"))
+
+	response, body := getFromRegistry(t, registry, "/v1/modules/terratest/basic/null/versions")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"modules": [{"source": "terratest/basic/null", "versions": [{"version": "1.0.0"}, {"version": "1.1.0"}]}]}`, body)
+
+	response, _ = getFromRegistry(t, registry, "/v1/modules/terratest/basic/null/1.1.0/download")
+	require.Equal(t, http.StatusNoContent, response.StatusCode)
+	archiveURL := response.Header.Get("X-Terraform-Get")
+	assert.Equal(t, registry.URL+"/archives/modules/terratest/basic/null/1.1.0.zip", archiveURL)
+
+	response, _ = getFromRegistry(t, registry, "/archives/modules/terratest/basic/null/1.1.0.zip")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, _ = getFromRegistry(t, registry, "/v1/modules/terratest/basic/null/2.0.0/download")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestRegistryServerConfigure(t *testing.T) {
+	t.Parallel()
+
+	registry := RunRegistryServer(t, "", "")
+	defer registry.Close()
+
+	options := &Options{}
+	registry.Configure(t, options)
+
+	cliConfig, err := os.ReadFile(options.EnvVars["TF_CLI_CONFIG_FILE"])
+	require.NoError(t, err)
+	assert.Equal(t, registry.CLIConfig(), string(cliConfig))
+	assert.Contains(t, string(cliConfig), `url = "`+registry.URL+`/providers/"`)
+	assert.Contains(t, string(cliConfig), `host "registry.terraform.io"`)
+	assert.FileExists(t, filepath.Join(filepath.SplitList(options.EnvVars["SSL_CERT_DIR"])[0], "terratest-registry.pem"))
+}
+
+func TestInitWithRegistryServer(t *testing.T) {
+	t.Parallel()
+
+	registry := RunRegistryServer(t, t.TempDir(), t.TempDir())
+	defer registry.Close()
+
+	moduleDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir, "main.tf"), []byte(`output "greeting" { value = "Hello, ${var.name}" }
+variable "name" { type = string }
+`), 0644))
+	registry.AddModule(t, "terratest/greeting/null", "1.0.0", moduleDir)
+
+	testFolder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testFolder, "main.tf"), []byte(`module "greeting" {
+  source  = "terratest/greeting/null"
+  version = "~> 1.0"
+  name    = "World"
+}
+
+output "greeting" { value = module.greeting.greeting }
+`), 0644))
+
+	options := &Options{TerraformDir: testFolder}
+	registry.Configure(t, options)
+	InitAndApply(t, options)
+
+	assert.Equal(t, "Hello, World", Output(t, options, "greeting"))
+}
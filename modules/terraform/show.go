@@ -46,3 +46,23 @@ func ShowWithStructE(t testing.TestingT, options *Options) (*PlanStruct, error)
 	}
 	return planStruct, nil
 }
+
+// ShowState calls terraform show in json mode against the current state of the terraform module at
+// options.TerraformDir and parses the json result into a go struct. Unlike ShowWithStruct, this ignores PlanFilePath
+// and always inspects the deployed state. This will fail the test if there is an error in the command.
+func ShowState(t testing.TestingT, options *Options) *StateStruct {
+	out, err := ShowStateE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// ShowStateE calls terraform show in json mode against the current state of the terraform module at
+// options.TerraformDir and parses the json result into a go struct. Unlike ShowWithStructE, this ignores PlanFilePath
+// and always inspects the deployed state.
+func ShowStateE(t testing.TestingT, options *Options) (*StateStruct, error) {
+	json, err := RunTerraformCommandAndGetStdoutE(t, options, "show", "-no-color", "-json")
+	if err != nil {
+		return nil, err
+	}
+	return ParseStateJSON(json)
+}
@@ -0,0 +1,171 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// StateStruct is a Go Struct representation of the state object returned from Terraform (after running `terraform
+// show` without a plan file). Unlike the raw state representation returned by terraform-json, this struct provides a
+// map that maps the resource addresses to the resource values to make it easier to navigate the raw state struct.
+type StateStruct struct {
+	// The raw representation of the state. See
+	// https://www.terraform.io/docs/internals/json-format.html#state-representation for details on the structure of the
+	// state output.
+	RawState tfjson.State
+
+	// A map that maps full resource addresses (e.g., module.foo.null_resource.test) to the current values of that
+	// resource in the state.
+	ResourceValuesMap map[string]*tfjson.StateResource
+
+	// A map that maps root module output names to the output values recorded in the state.
+	OutputsMap map[string]*tfjson.StateOutput
+}
+
+// ParseStateJSON takes in the json string representation of the terraform state and returns a go struct
+// representation for easy introspection.
+func ParseStateJSON(jsonStr string) (*StateStruct, error) {
+	state := &StateStruct{}
+
+	if err := json.Unmarshal([]byte(jsonStr), &state.RawState); err != nil {
+		return nil, err
+	}
+
+	state.ResourceValuesMap = parseStateValues(state.RawState.Values)
+	state.OutputsMap = map[string]*tfjson.StateOutput{}
+	if state.RawState.Values != nil {
+		for name, output := range state.RawState.Values.Outputs {
+			state.OutputsMap[name] = output
+		}
+	}
+	return state, nil
+}
+
+// parseStateValues walks through the given state values to return a map that maps the full resource addresses to the
+// resources. If there are no values (e.g., nothing has been applied yet), this returns an empty map instead of
+// erroring.
+func parseStateValues(values *tfjson.StateValues) map[string]*tfjson.StateResource {
+	if values == nil || values.RootModule == nil {
+		return map[string]*tfjson.StateResource{}
+	}
+	// The state values share the same module layout as the planned values, so we can reuse the same walker.
+	return parseModulePlannedValues(values.RootModule)
+}
+
+// ResourcesOfType returns all the resources in the state that have the given resource type (e.g., aws_s3_bucket),
+// regardless of which module they are defined in.
+func (state *StateStruct) ResourcesOfType(resourceType string) []*tfjson.StateResource {
+	out := []*tfjson.StateResource{}
+	for _, resource := range state.ResourceValuesMap {
+		if resource.Type == resourceType {
+			out = append(out, resource)
+		}
+	}
+	return out
+}
+
+// GetResourceAttributeE looks up the value of the given attribute on the resource at the given address. The attribute
+// can be a path into nested blocks and lists, using a dot as the separator and numbers as list indexes (e.g.,
+// versioning.0.enabled).
+func (state *StateStruct) GetResourceAttributeE(address string, attributePath string) (interface{}, error) {
+	resource, hasResource := state.ResourceValuesMap[address]
+	if !hasResource {
+		return nil, ResourceNotFound(address)
+	}
+	return getAttributeByPath(resource.AttributeValues, address, attributePath)
+}
+
+// getAttributeByPath walks the given attribute values following the dot separated path, where numeric path segments
+// are used as list indexes.
+func getAttributeByPath(values map[string]interface{}, address string, attributePath string) (interface{}, error) {
+	var current interface{} = values
+	for _, segment := range strings.Split(attributePath, ".") {
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			val, hasKey := typed[segment]
+			if !hasKey {
+				return nil, AttributeNotFound{Address: address, Attribute: attributePath}
+			}
+			current = val
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, AttributeNotFound{Address: address, Attribute: attributePath}
+			}
+			current = typed[index]
+		default:
+			return nil, AttributeNotFound{Address: address, Attribute: attributePath}
+		}
+	}
+	return current, nil
+}
+
+// AssertStateResourceExists checks if the given resource address exists in the state, failing the test if it does not.
+func AssertStateResourceExists(t testing.TestingT, state *StateStruct, address string) {
+	_, hasKey := state.ResourceValuesMap[address]
+	assert.Truef(t, hasKey, "Given state does not have resource %s", address)
+}
+
+// RequireStateResourceExists checks if the given resource address exists in the state, failing and halting the test
+// if it does not.
+func RequireStateResourceExists(t testing.TestingT, state *StateStruct, address string) {
+	_, hasKey := state.ResourceValuesMap[address]
+	require.Truef(t, hasKey, "Given state does not have resource %s", address)
+}
+
+// AssertStateResourceNotExists checks that the given resource address does not exist in the state, failing the test
+// if it does.
+func AssertStateResourceNotExists(t testing.TestingT, state *StateStruct, address string) {
+	_, hasKey := state.ResourceValuesMap[address]
+	assert.Falsef(t, hasKey, "Given state unexpectedly has resource %s", address)
+}
+
+// AssertStateResourceAttributeEquals checks that the attribute at the given path on the resource at the given address
+// equals the expected value, failing the test if it does not. Numbers in the state are decoded as float64, so the
+// comparison is done with assert.EqualValues to allow passing in ints as the expected value.
+func AssertStateResourceAttributeEquals(t testing.TestingT, state *StateStruct, address string, attributePath string, expected interface{}) {
+	actual, err := state.GetResourceAttributeE(address, attributePath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValuesf(t, expected, actual, "Unexpected value for attribute %s of resource %s", attributePath, address)
+}
+
+// RequireStateResourceAttributeEquals checks that the attribute at the given path on the resource at the given address
+// equals the expected value, failing and halting the test if it does not.
+func RequireStateResourceAttributeEquals(t testing.TestingT, state *StateStruct, address string, attributePath string, expected interface{}) {
+	actual, err := state.GetResourceAttributeE(address, attributePath)
+	require.NoError(t, err)
+	require.EqualValuesf(t, expected, actual, "Unexpected value for attribute %s of resource %s", attributePath, address)
+}
+
+// AssertStateResourceCountByType checks that the state contains exactly the expected number of resources of the given
+// type, failing the test if it does not.
+func AssertStateResourceCountByType(t testing.TestingT, state *StateStruct, resourceType string, expectedCount int) {
+	resources := state.ResourcesOfType(resourceType)
+	assert.Lenf(t, resources, expectedCount, "Unexpected number of resources of type %s: %s", resourceType, formatStateAddresses(resources))
+}
+
+// RequireStateResourceCountByType checks that the state contains exactly the expected number of resources of the
+// given type, failing and halting the test if it does not.
+func RequireStateResourceCountByType(t testing.TestingT, state *StateStruct, resourceType string, expectedCount int) {
+	resources := state.ResourcesOfType(resourceType)
+	require.Lenf(t, resources, expectedCount, "Unexpected number of resources of type %s: %s", resourceType, formatStateAddresses(resources))
+}
+
+func formatStateAddresses(resources []*tfjson.StateResource) string {
+	addresses := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		addresses = append(addresses, resource.Address)
+	}
+	sort.Strings(addresses)
+	return fmt.Sprintf("%v", addresses)
+}
@@ -0,0 +1,117 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStateJSON = `{
+  "format_version": "1.0",
+  "terraform_version": "1.5.7",
+  "values": {
+    "outputs": {
+      "bucket_name": {"sensitive": false, "value": "terratest-bucket"}
+    },
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_s3_bucket.logs",
+          "mode": "managed",
+          "type": "aws_s3_bucket",
+          "name": "logs",
+          "provider_name": "registry.terraform.io/hashicorp/aws",
+          "schema_version": 0,
+          "values": {
+            "bucket": "terratest-bucket",
+            "force_destroy": true,
+            "versioning": [{"enabled": true, "mfa_delete": false}],
+            "tags": {"Name": "logs"}
+          }
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.foo",
+          "resources": [
+            {
+              "address": "module.foo.aws_s3_bucket.data[0]",
+              "mode": "managed",
+              "type": "aws_s3_bucket",
+              "name": "data",
+              "index": 0,
+              "provider_name": "registry.terraform.io/hashicorp/aws",
+              "schema_version": 0,
+              "values": {"bucket": "data-0", "count_hint": 3}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func TestParseStateJSON(t *testing.T) {
+	t.Parallel()
+
+	state, err := ParseStateJSON(testStateJSON)
+	require.NoError(t, err)
+
+	RequireStateResourceExists(t, state, "aws_s3_bucket.logs")
+	RequireStateResourceExists(t, state, "module.foo.aws_s3_bucket.data[0]")
+	AssertStateResourceNotExists(t, state, "aws_s3_bucket.missing")
+
+	AssertStateResourceAttributeEquals(t, state, "aws_s3_bucket.logs", "bucket", "terratest-bucket")
+	AssertStateResourceAttributeEquals(t, state, "aws_s3_bucket.logs", "versioning.0.enabled", true)
+	AssertStateResourceAttributeEquals(t, state, "aws_s3_bucket.logs", "tags.Name", "logs")
+	AssertStateResourceAttributeEquals(t, state, "module.foo.aws_s3_bucket.data[0]", "count_hint", 3)
+	AssertStateResourceCountByType(t, state, "aws_s3_bucket", 2)
+
+	require.Contains(t, state.OutputsMap, "bucket_name")
+	assert.Equal(t, "terratest-bucket", state.OutputsMap["bucket_name"].Value)
+}
+
+func TestStateGetResourceAttributeErrors(t *testing.T) {
+	t.Parallel()
+
+	state, err := ParseStateJSON(testStateJSON)
+	require.NoError(t, err)
+
+	_, err = state.GetResourceAttributeE("aws_s3_bucket.missing", "bucket")
+	assert.Equal(t, ResourceNotFound("aws_s3_bucket.missing"), err)
+
+	_, err = state.GetResourceAttributeE("aws_s3_bucket.logs", "versioning.1.enabled")
+	assert.Equal(t, AttributeNotFound{Address: "aws_s3_bucket.logs", Attribute: "versioning.1.enabled"}, err)
+}
+
+func TestParseStateJSONEmptyState(t *testing.T) {
+	t.Parallel()
+
+	state, err := ParseStateJSON(`{"format_version": "1.0"}`)
+	require.NoError(t, err)
+	assert.Empty(t, state.ResourceValuesMap)
+	assert.Empty(t, state.OutputsMap)
+}
+
+func TestShowState(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-basic-configuration", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir: testFolder,
+		Vars: map[string]interface{}{
+			"cnt": 2,
+		},
+	}
+
+	InitAndApply(t, options)
+
+	state := ShowState(t, options)
+	RequireStateResourceExists(t, state, "null_resource.test[0]")
+	RequireStateResourceExists(t, state, "null_resource.test[1]")
+	AssertStateResourceCountByType(t, state, "null_resource", 2)
+}
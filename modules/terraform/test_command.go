@@ -0,0 +1,273 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	gotesting "testing"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatus is the result of a test file or run block executed by terraform test.
+type TestStatus string
+
+const (
+	TestStatusPending TestStatus = "pending"
+	TestStatusSkip    TestStatus = "skip"
+	TestStatusPass    TestStatus = "pass"
+	TestStatusFail    TestStatus = "fail"
+	TestStatusError   TestStatus = "error"
+)
+
+// TestRunResult is the result of a single run block in a test file.
+type TestRunResult struct {
+	Name        string
+	Status      TestStatus
+	Diagnostics []tfjson.Diagnostic // The diagnostics reported while executing the run block, e.g., failed assertions
+}
+
+// TestFileResult is the result of a single test file and its run blocks.
+type TestFileResult struct {
+	Path        string
+	Status      TestStatus
+	Runs        []TestRunResult     // In the order in which they are defined in the file
+	Diagnostics []tfjson.Diagnostic // The diagnostics reported for the file, but not for a specific run block
+}
+
+// Run returns the result of the run block with the given name, or nil if the file has no such run block.
+func (file *TestFileResult) Run(name string) *TestRunResult {
+	for i := range file.Runs {
+		if file.Runs[i].Name == name {
+			return &file.Runs[i]
+		}
+	}
+	return nil
+}
+
+// TestResults are the results of terraform test.
+type TestResults struct {
+	Status  TestStatus
+	Files   []TestFileResult // Sorted by path
+	Passed  int
+	Failed  int
+	Errored int
+	Skipped int
+	// The diagnostics reported for the test suite as a whole, e.g., configuration errors.
+	Diagnostics []tfjson.Diagnostic
+}
+
+// File returns the result of the test file with the given path, or nil if there is no such file.
+func (results *TestResults) File(path string) *TestFileResult {
+	for i := range results.Files {
+		if results.Files[i].Path == path {
+			return &results.Files[i]
+		}
+	}
+	return nil
+}
+
+// Test runs terraform test -json for the module at options.TerraformDir, honoring TestFilters and TestDirectory, and
+// reports the result of each run block as a subtest named after the test file and run block (e.g.,
+// TestModule/tests/main.tftest.hcl/creates_bucket), so that the results of native Terraform tests show up in go test
+// output and reports. If t does not support subtests, the test is failed with the list of failed run blocks instead. This
+// will fail the test if terraform test can't be executed.
+//
+// Example:
+//
+//	func TestModule(t *testing.T) {
+//		terraform.Test(t, &terraform.Options{TerraformDir: "../modules/bucket"})
+//	}
+func Test(t testing.TestingT, options *Options) *TestResults {
+	results, err := TestE(t, options)
+	if results == nil {
+		require.NoError(t, err)
+		return nil
+	}
+
+	if runner, supportsSubtests := t.(subtestRunner); supportsSubtests {
+		reportTestResults(runner, results)
+	} else {
+		require.NoError(t, err)
+	}
+	return results
+}
+
+// TestE runs terraform test -json for the module at options.TerraformDir, honoring TestFilters and TestDirectory, and
+// returns the result of each test file and run block. If any test fails, this returns both the results and a
+// TestsFailed error. If terraform test can't be executed at all, the results are nil.
+func TestE(t testing.TestingT, options *Options) (*TestResults, error) {
+	if options.TerraformBinary == "terragrunt" {
+		return nil, UnsupportedTerragruntCommand("test")
+	}
+
+	out, cmdErr := RunTerraformCommandAndGetStdoutE(t, options, formatTestArgs(options)...)
+	results, err := ParseTestResults(out)
+	if err != nil || results.Status == "" {
+		if cmdErr != nil {
+			return nil, cmdErr
+		}
+		return nil, fmt.Errorf("terraform test did not report a test summary: %v", err)
+	}
+	if failed := results.failedRuns(); len(failed) > 0 || results.Status == TestStatusFail || results.Status == TestStatusError {
+		return results, TestsFailed(failed)
+	}
+	return results, nil
+}
+
+// formatTestArgs returns the args to run terraform test with for the given options.
+func formatTestArgs(options *Options) []string {
+	args := []string{"test", "-json"}
+	if options.NoColor {
+		args = append(args, "-no-color")
+	}
+	for _, filter := range options.TestFilters {
+		args = append(args, "-filter="+filter)
+	}
+	if options.TestDirectory != "" {
+		args = append(args, "-test-directory="+options.TestDirectory)
+	}
+	// When WriteVarsToFile is set, the vars are passed in through an auto var file instead (see writeAutoVarFile)
+	if !options.WriteVarsToFile {
+		args = append(args, FormatTerraformVarsAsArgs(options.Vars)...)
+	}
+	return append(args, FormatTerraformArgs("-var-file", options.VarFiles)...)
+}
+
+// ParseTestResults parses the machine readable output of terraform test -json into test results.
+func ParseTestResults(output string) (*TestResults, error) {
+	events, err := ParseUIEvents(output)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &TestResults{Diagnostics: []tfjson.Diagnostic{}}
+	files := map[string]*TestFileResult{}
+	getFile := func(path string) *TestFileResult {
+		if _, exists := files[path]; !exists {
+			files[path] = &TestFileResult{Path: path, Status: TestStatusPending, Runs: []TestRunResult{}, Diagnostics: []tfjson.Diagnostic{}}
+		}
+		return files[path]
+	}
+	getRun := func(file *TestFileResult, name string) *TestRunResult {
+		if run := file.Run(name); run != nil {
+			return run
+		}
+		file.Runs = append(file.Runs, TestRunResult{Name: name, Status: TestStatusPending, Diagnostics: []tfjson.Diagnostic{}})
+		return &file.Runs[len(file.Runs)-1]
+	}
+
+	for _, event := range events {
+		switch {
+		case event.Type == UIEventTestAbstract:
+			for path, runs := range event.TestAbstract {
+				file := getFile(path)
+				for _, run := range runs {
+					getRun(file, run)
+				}
+			}
+		case event.Type == UIEventTestFile && event.TestFileStatus != nil && event.TestFileStatus.Status != "":
+			getFile(event.TestFileStatus.Path).Status = TestStatus(event.TestFileStatus.Status)
+		case event.Type == UIEventTestRun && event.TestRunStatus != nil && event.TestRunStatus.Status != "":
+			getRun(getFile(event.TestRunStatus.Path), event.TestRunStatus.Run).Status = TestStatus(event.TestRunStatus.Status)
+		case event.Type == UIEventTestSummary && event.TestSummary != nil:
+			results.Status = TestStatus(event.TestSummary.Status)
+			results.Passed = event.TestSummary.Passed
+			results.Failed = event.TestSummary.Failed
+			results.Errored = event.TestSummary.Errored
+			results.Skipped = event.TestSummary.Skipped
+		case event.Type == UIEventDiagnostic && event.Diagnostic != nil:
+			switch {
+			case event.TestFile != "" && event.TestRun != "":
+				run := getRun(getFile(event.TestFile), event.TestRun)
+				run.Diagnostics = append(run.Diagnostics, *event.Diagnostic)
+			case event.TestFile != "":
+				file := getFile(event.TestFile)
+				file.Diagnostics = append(file.Diagnostics, *event.Diagnostic)
+			default:
+				results.Diagnostics = append(results.Diagnostics, *event.Diagnostic)
+			}
+		}
+	}
+
+	for _, file := range files {
+		results.Files = append(results.Files, *file)
+	}
+	sort.Slice(results.Files, func(i, j int) bool { return results.Files[i].Path < results.Files[j].Path })
+	return results, nil
+}
+
+// failedRuns returns a description of every test file and run block that failed or errored.
+func (results *TestResults) failedRuns() []string {
+	out := []string{}
+	for _, file := range results.Files {
+		failedRunInFile := false
+		for _, run := range file.Runs {
+			if run.Status == TestStatusFail || run.Status == TestStatusError {
+				failedRunInFile = true
+				out = append(out, fmt.Sprintf("%s/%s (%s)", file.Path, run.Name, run.Status))
+			}
+		}
+		if !failedRunInFile && (file.Status == TestStatusFail || file.Status == TestStatusError) {
+			out = append(out, fmt.Sprintf("%s (%s)", file.Path, file.Status))
+		}
+	}
+	return out
+}
+
+// subtestRunner is implemented by *testing.T.
+type subtestRunner interface {
+	testing.TestingT
+	Run(name string, f func(t *gotesting.T)) bool
+}
+
+// reportTestResults reports every test file and run block as a subtest with the corresponding result.
+func reportTestResults(t subtestRunner, results *TestResults) {
+	if len(results.Diagnostics) > 0 && (results.Status == TestStatusFail || results.Status == TestStatusError) {
+		t.Errorf("terraform test failed:\n%s", formatDiagnostics(results.Diagnostics))
+	}
+	for _, file := range results.Files {
+		file := file
+		t.Run(file.Path, func(t *gotesting.T) {
+			if len(file.Diagnostics) > 0 && (file.Status == TestStatusFail || file.Status == TestStatusError) {
+				t.Errorf("%s\n%s", file.Status, formatDiagnostics(file.Diagnostics))
+			}
+			for _, run := range file.Runs {
+				run := run
+				t.Run(run.Name, func(t *gotesting.T) {
+					switch run.Status {
+					case TestStatusFail, TestStatusError:
+						t.Errorf("%s\n%s", run.Status, formatDiagnostics(run.Diagnostics))
+					case TestStatusSkip, TestStatusPending:
+						t.Skipf("run block was not executed (%s)", run.Status)
+					}
+				})
+			}
+			if len(file.Runs) == 0 && file.Status == TestStatusSkip {
+				t.Skip("test file was skipped")
+			}
+		})
+	}
+}
+
+// formatDiagnostics formats diagnostics in the same way as the human readable Terraform output.
+func formatDiagnostics(diagnostics []tfjson.Diagnostic) string {
+	messages := []string{}
+	for _, diagnostic := range diagnostics {
+		severity := "Error"
+		if diagnostic.Severity == tfjson.DiagnosticSeverityWarning {
+			severity = "Warning"
+		}
+		message := fmt.Sprintf("%s: %s", severity, diagnostic.Summary)
+		if diagnostic.Range != nil {
+			message += fmt.Sprintf("\n  on %s line %d", diagnostic.Range.Filename, diagnostic.Range.Start.Line)
+		}
+		if diagnostic.Detail != "" {
+			message += "\n\n" + diagnostic.Detail
+		}
+		messages = append(messages, message)
+	}
+	return strings.Join(messages, "\n\n")
+}
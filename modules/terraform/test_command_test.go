@@ -0,0 +1,115 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTerraformTestJSONOutput = `{"@level":"info","@message":"Terraform 1.6.0","@module":"terraform.ui","terraform":"1.6.0","type":"version","ui":"1.2"}
+{"@level":"info","@message":"Found 2 files and 3 run blocks","@module":"terraform.ui","test_abstract":{"tests/failing.tftest.hcl":["wrong_greeting"],"tests/passing.tftest.hcl":["default_greeting","custom_greeting"]},"type":"test_abstract"}
+{"@level":"info","@message":"tests/failing.tftest.hcl... in progress","@module":"terraform.ui","@testfile":"tests/failing.tftest.hcl","test_file":{"path":"tests/failing.tftest.hcl","progress":"starting"},"type":"test_file"}
+{"@level":"info","@message":"  \"wrong_greeting\"... fail","@module":"terraform.ui","@testfile":"tests/failing.tftest.hcl","@testrun":"wrong_greeting","test_run":{"path":"tests/failing.tftest.hcl","run":"wrong_greeting","progress":"complete","status":"fail"},"type":"test_run"}
+{"@level":"error","@message":"Error: Test assertion failed","@module":"terraform.ui","@testfile":"tests/failing.tftest.hcl","@testrun":"wrong_greeting","diagnostic":{"severity":"error","summary":"Test assertion failed","detail":"Greeting is not a goodbye","range":{"filename":"tests/failing.tftest.hcl","start":{"line":5,"column":21,"byte":70},"end":{"line":5,"column":62,"byte":111}}},"type":"diagnostic"}
+{"@level":"info","@message":"tests/failing.tftest.hcl... fail","@module":"terraform.ui","@testfile":"tests/failing.tftest.hcl","test_file":{"path":"tests/failing.tftest.hcl","progress":"complete","status":"fail"},"type":"test_file"}
+{"@level":"info","@message":"tests/passing.tftest.hcl... in progress","@module":"terraform.ui","@testfile":"tests/passing.tftest.hcl","test_file":{"path":"tests/passing.tftest.hcl","progress":"starting"},"type":"test_file"}
+{"@level":"info","@message":"  \"default_greeting\"... pass","@module":"terraform.ui","@testfile":"tests/passing.tftest.hcl","@testrun":"default_greeting","test_run":{"path":"tests/passing.tftest.hcl","run":"default_greeting","progress":"complete","status":"pass"},"type":"test_run"}
+{"@level":"info","@message":"  \"custom_greeting\"... skip","@module":"terraform.ui","@testfile":"tests/passing.tftest.hcl","@testrun":"custom_greeting","test_run":{"path":"tests/passing.tftest.hcl","run":"custom_greeting","progress":"complete","status":"skip"},"type":"test_run"}
+{"@level":"info","@message":"tests/passing.tftest.hcl... pass","@module":"terraform.ui","@testfile":"tests/passing.tftest.hcl","test_file":{"path":"tests/passing.tftest.hcl","progress":"complete","status":"pass"},"type":"test_file"}
+{"@level":"info","@message":"Failure! 1 passed, 1 failed, 1 skipped.","@module":"terraform.ui","test_summary":{"status":"fail","passed":1,"failed":1,"errored":0,"skipped":1},"type":"test_summary"}
+`
+
+func TestParseTestResults(t *testing.T) {
+	t.Parallel()
+
+	results, err := ParseTestResults(testTerraformTestJSONOutput)
+	require.NoError(t, err)
+
+	assert.Equal(t, TestStatusFail, results.Status)
+	assert.Equal(t, 1, results.Passed)
+	assert.Equal(t, 1, results.Failed)
+	assert.Equal(t, 0, results.Errored)
+	assert.Equal(t, 1, results.Skipped)
+	assert.Empty(t, results.Diagnostics)
+	require.Len(t, results.Files, 2)
+
+	failing := results.File("tests/failing.tftest.hcl")
+	require.NotNil(t, failing)
+	assert.Equal(t, TestStatusFail, failing.Status)
+	require.Len(t, failing.Runs, 1)
+	assert.Equal(t, TestStatusFail, failing.Runs[0].Status)
+	require.Len(t, failing.Runs[0].Diagnostics, 1)
+	assert.Equal(t, "Greeting is not a goodbye", failing.Runs[0].Diagnostics[0].Detail)
+
+	passing := results.File("tests/passing.tftest.hcl")
+	require.NotNil(t, passing)
+	assert.Equal(t, TestStatusPass, passing.Status)
+	require.Len(t, passing.Runs, 2)
+	assert.Equal(t, "default_greeting", passing.Runs[0].Name)
+	assert.Equal(t, TestStatusPass, passing.Runs[0].Status)
+	assert.Equal(t, TestStatusSkip, passing.Run("custom_greeting").Status)
+	assert.Nil(t, passing.Run("does_not_exist"))
+
+	assert.Equal(t, []string{"tests/failing.tftest.hcl/wrong_greeting (fail)"}, results.failedRuns())
+}
+
+func TestTestEWithTerragruntIsNotSupported(t *testing.T) {
+	t.Parallel()
+
+	_, err := TestE(t, &Options{TerraformDir: ".", TerraformBinary: "terragrunt"})
+	assert.Equal(t, UnsupportedTerragruntCommand("test"), err)
+}
+
+func TestFormatTestArgs(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{
+		TestFilters: []string{"tests/main.tftest.hcl"},
+		Vars:        map[string]interface{}{"password": "hunter2"},
+		VarFiles:    []string{"test.tfvars"},
+	}
+	assert.Equal(t, []string{"test", "-json", "-filter=tests/main.tftest.hcl", "-var", "password=hunter2", "-var-file", "test.tfvars"}, formatTestArgs(options))
+
+	// The vars are passed in through an auto var file, so they don't show up on the command line
+	options.WriteVarsToFile = true
+	assert.Equal(t, []string{"test", "-json", "-filter=tests/main.tftest.hcl", "-var-file", "test.tfvars"}, formatTestArgs(options))
+}
+
+func TestTestWithFilter(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-native-tests", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir: testFolder,
+		TestFilters:  []string{"tests/passing.tftest.hcl"},
+	}
+	Init(t, options)
+	results := Test(t, options)
+
+	assert.Equal(t, TestStatusPass, results.Status)
+	assert.Equal(t, 2, results.Passed)
+	require.Len(t, results.Files, 1)
+	assert.Equal(t, "tests/passing.tftest.hcl", results.Files[0].Path)
+}
+
+func TestTestEReportsFailedRuns(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-native-tests", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{TerraformDir: testFolder}
+	Init(t, options)
+	results, err := TestE(t, options)
+
+	require.Error(t, err)
+	assert.IsType(t, TestsFailed{}, err)
+	require.NotNil(t, results)
+	assert.Equal(t, TestStatusFail, results.Status)
+	assert.Equal(t, TestStatusFail, results.File("tests/failing.tftest.hcl").Run("wrong_greeting").Status)
+	assert.Equal(t, TestStatusPass, results.File("tests/passing.tftest.hcl").Status)
+}
@@ -0,0 +1,258 @@
+package terraform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// UIEventType is the type of a message in the machine readable UI output of Terraform (enabled with the -json flag).
+// See https://developer.hashicorp.com/terraform/internals/machine-readable-ui for details.
+type UIEventType string
+
+const (
+	UIEventVersion         UIEventType = "version"
+	UIEventLog             UIEventType = "log"
+	UIEventDiagnostic      UIEventType = "diagnostic"
+	UIEventResourceDrift   UIEventType = "resource_drift"
+	UIEventPlannedChange   UIEventType = "planned_change"
+	UIEventChangeSummary   UIEventType = "change_summary"
+	UIEventOutputs         UIEventType = "outputs"
+	UIEventApplyStart      UIEventType = "apply_start"
+	UIEventApplyProgress   UIEventType = "apply_progress"
+	UIEventApplyComplete   UIEventType = "apply_complete"
+	UIEventApplyErrored    UIEventType = "apply_errored"
+	UIEventRefreshStart    UIEventType = "refresh_start"
+	UIEventRefreshComplete UIEventType = "refresh_complete"
+	UIEventTestAbstract    UIEventType = "test_abstract"
+	UIEventTestFile        UIEventType = "test_file"
+	UIEventTestRun         UIEventType = "test_run"
+	UIEventTestSummary     UIEventType = "test_summary"
+	UIEventTestCleanup     UIEventType = "test_cleanup"
+	UIEventTestInterrupt   UIEventType = "test_interrupt"
+)
+
+// UIEvent is a single message in the machine readable UI output of Terraform. Depending on the Type, one of Hook,
+// Change, Changes, Diagnostic, Outputs, or one of the Test fields is set.
+type UIEvent struct {
+	Level     string      `json:"@level"`
+	Message   string      `json:"@message"`
+	Module    string      `json:"@module"`
+	Timestamp time.Time   `json:"@timestamp"`
+	Type      UIEventType `json:"type"`
+
+	// Set for the apply_* and refresh_* events.
+	Hook *UIHook `json:"hook,omitempty"`
+	// Set for the planned_change and resource_drift events.
+	Change *UIChange `json:"change,omitempty"`
+	// Set for the change_summary event.
+	Changes *UIChangeSummary `json:"changes,omitempty"`
+	// Set for the diagnostic event.
+	Diagnostic *tfjson.Diagnostic `json:"diagnostic,omitempty"`
+	// Set for the outputs event.
+	Outputs map[string]UIOutput `json:"outputs,omitempty"`
+
+	// Set for the events of terraform test that are about a specific test file or run block.
+	TestFile string `json:"@testfile,omitempty"`
+	TestRun  string `json:"@testrun,omitempty"`
+	// Set for the test_abstract event, which maps each test file to the names of its run blocks.
+	TestAbstract map[string][]string `json:"test_abstract,omitempty"`
+	// Set for the test_file event.
+	TestFileStatus *UITestFile `json:"test_file,omitempty"`
+	// Set for the test_run event.
+	TestRunStatus *UITestRun `json:"test_run,omitempty"`
+	// Set for the test_summary event.
+	TestSummary *UITestSummary `json:"test_summary,omitempty"`
+}
+
+// UIResourceAddr identifies the resource an event is about.
+type UIResourceAddr struct {
+	Addr            string      `json:"addr"`
+	Module          string      `json:"module"`
+	Resource        string      `json:"resource"`
+	ImpliedProvider string      `json:"implied_provider"`
+	ResourceType    string      `json:"resource_type"`
+	ResourceName    string      `json:"resource_name"`
+	ResourceKey     interface{} `json:"resource_key"`
+}
+
+// UIHook is the progress of an operation on a single resource during apply or refresh.
+type UIHook struct {
+	Resource       UIResourceAddr `json:"resource"`
+	Action         string         `json:"action"`
+	IDKey          string         `json:"id_key,omitempty"`
+	IDValue        string         `json:"id_value,omitempty"`
+	ElapsedSeconds float64        `json:"elapsed_seconds,omitempty"`
+}
+
+// UIChange is a planned change to, or detected drift of, a single resource.
+type UIChange struct {
+	Resource         UIResourceAddr  `json:"resource"`
+	PreviousResource *UIResourceAddr `json:"previous_resource,omitempty"`
+	Action           string          `json:"action"`
+	Reason           string          `json:"reason,omitempty"`
+}
+
+// UIChangeSummary is the number of resources affected by a plan, apply, or destroy.
+type UIChangeSummary struct {
+	Add       int    `json:"add"`
+	Change    int    `json:"change"`
+	Import    int    `json:"import"`
+	Remove    int    `json:"remove"`
+	Operation string `json:"operation"`
+}
+
+// UIOutput is the value of a single root module output.
+type UIOutput struct {
+	Sensitive bool            `json:"sensitive"`
+	Type      json.RawMessage `json:"type,omitempty"`
+	Value     interface{}     `json:"value,omitempty"`
+	Action    string          `json:"action,omitempty"`
+}
+
+// UITestFile is the progress or result of a test file run by terraform test.
+type UITestFile struct {
+	Path     string `json:"path"`
+	Progress string `json:"progress,omitempty"`
+	Status   string `json:"status,omitempty"`
+}
+
+// UITestRun is the progress or result of a single run block in a test file run by terraform test.
+type UITestRun struct {
+	Path     string  `json:"path"`
+	Run      string  `json:"run"`
+	Progress string  `json:"progress,omitempty"`
+	Status   string  `json:"status,omitempty"`
+	Elapsed  float64 `json:"elapsed,omitempty"`
+}
+
+// UITestSummary is the overall result of terraform test.
+type UITestSummary struct {
+	Status  string `json:"status"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Errored int    `json:"errored"`
+	Skipped int    `json:"skipped"`
+}
+
+// UIEvents is the list of events emitted by a single Terraform command, in order.
+type UIEvents []UIEvent
+
+// ParseUIEvents takes in the output of a Terraform command that was run with -json (e.g., the output of Apply when
+// JSONOutput is set on the options) and decodes each machine readable UI message into a UIEvent. Lines that are not JSON
+// objects (e.g., from stderr) are skipped.
+func ParseUIEvents(output string) (UIEvents, error) {
+	events := UIEvents{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var event UIEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse terraform UI event %q: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// OfType returns the events of the given type.
+func (events UIEvents) OfType(eventType UIEventType) UIEvents {
+	out := UIEvents{}
+	for _, event := range events {
+		if event.Type == eventType {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+// Diagnostics returns all the diagnostics (errors and warnings) reported by the command.
+func (events UIEvents) Diagnostics() []tfjson.Diagnostic {
+	out := []tfjson.Diagnostic{}
+	for _, event := range events.OfType(UIEventDiagnostic) {
+		if event.Diagnostic != nil {
+			out = append(out, *event.Diagnostic)
+		}
+	}
+	return out
+}
+
+// Errors returns the error diagnostics reported by the command.
+func (events UIEvents) Errors() []tfjson.Diagnostic {
+	out := []tfjson.Diagnostic{}
+	for _, diagnostic := range events.Diagnostics() {
+		if diagnostic.Severity == tfjson.DiagnosticSeverityError {
+			out = append(out, diagnostic)
+		}
+	}
+	return out
+}
+
+// ChangeSummary returns the last change summary reported by the command, or nil if there is none (e.g., because the
+// command failed).
+func (events UIEvents) ChangeSummary() *UIChangeSummary {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == UIEventChangeSummary && events[i].Changes != nil {
+			return events[i].Changes
+		}
+	}
+	return nil
+}
+
+// uiEventLogger is the logger used for Terraform commands run with -json. It logs the human readable message of each
+// UI event instead of the raw JSON, and passes each event on to the handler configured on the options, if any.
+type uiEventLogger struct {
+	delegate *logger.Logger
+	handler  func(event UIEvent)
+	// Serializes the calls to the handler, as the lines of stdout and stderr are logged from separate goroutines
+	mutex *sync.Mutex
+}
+
+func (l uiEventLogger) Logf(t testing.TestingT, format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	if !strings.HasPrefix(line, "{") {
+		l.delegate.Logf(t, "%s", line)
+		return
+	}
+
+	var event UIEvent
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		l.delegate.Logf(t, "%s", line)
+		return
+	}
+	l.delegate.Logf(t, "%s", event.Message)
+	if l.handler != nil {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		l.handler(event)
+	}
+}
+
+// withDiagnostics wraps errors from Terraform commands run with -json in a DiagnosticsError, so that the diagnostics
+// reported in the machine readable output are included in the error message, and therefore matched against
+// RetryableTerraformErrors in the same way as the human readable output would be.
+func withDiagnostics(options *Options, out string, err error) (string, error) {
+	if err == nil || !options.JSONOutput {
+		return out, err
+	}
+	events, parseErr := ParseUIEvents(out)
+	if parseErr != nil {
+		return out, err
+	}
+	diagnostics := events.Errors()
+	if len(diagnostics) == 0 {
+		return out, err
+	}
+	return out, &DiagnosticsError{Underlying: err, Diagnostics: diagnostics}
+}
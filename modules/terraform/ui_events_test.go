@@ -0,0 +1,154 @@
+package terraform
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testApplyUIEventsOutput = `Running terraform apply
+{"@level":"info","@message":"Terraform 1.5.7","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:00.000000+02:00","terraform":"1.5.7","type":"version","ui":"1.1"}
+{"@level":"info","@message":"null_resource.test[0]: Plan to create","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:01.000000+02:00","change":{"resource":{"addr":"null_resource.test[0]","module":"","resource":"null_resource.test[0]","implied_provider":"null","resource_type":"null_resource","resource_name":"test","resource_key":0},"action":"create"},"type":"planned_change"}
+{"@level":"info","@message":"Plan: 1 to add, 0 to change, 0 to destroy.","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:01.000000+02:00","changes":{"add":1,"change":0,"import":0,"remove":0,"operation":"plan"},"type":"change_summary"}
+{"@level":"info","@message":"null_resource.test[0]: Creating...","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:02.000000+02:00","hook":{"resource":{"addr":"null_resource.test[0]","module":"","resource":"null_resource.test[0]","implied_provider":"null","resource_type":"null_resource","resource_name":"test","resource_key":0},"action":"create"},"type":"apply_start"}
+{"@level":"info","@message":"null_resource.test[0]: Creation complete after 0s [id=123]","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:02.000000+02:00","hook":{"resource":{"addr":"null_resource.test[0]","module":"","resource":"null_resource.test[0]","implied_provider":"null","resource_type":"null_resource","resource_name":"test","resource_key":0},"action":"create","id_key":"id","id_value":"123","elapsed_seconds":0},"type":"apply_complete"}
+{"@level":"warn","@message":"Warning: Deprecated attribute","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:02.000000+02:00","diagnostic":{"severity":"warning","summary":"Deprecated attribute","detail":"The attribute \"foo\" is deprecated."},"type":"diagnostic"}
+{"@level":"info","@message":"Apply complete! Resources: 1 added, 0 changed, 0 destroyed.","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:02.000000+02:00","changes":{"add":1,"change":0,"import":0,"remove":0,"operation":"apply"},"type":"change_summary"}
+{"@level":"info","@message":"Outputs: 1","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:02.000000+02:00","outputs":{"id":{"sensitive":false,"type":"string","value":"123"}},"type":"outputs"}`
+
+func TestParseUIEvents(t *testing.T) {
+	t.Parallel()
+
+	events, err := ParseUIEvents(testApplyUIEventsOutput)
+	require.NoError(t, err)
+	require.Len(t, events, 8)
+
+	assert.Equal(t, UIEventVersion, events[0].Type)
+	assert.Equal(t, 2023, events[0].Timestamp.Year())
+
+	planned := events.OfType(UIEventPlannedChange)
+	require.Len(t, planned, 1)
+	assert.Equal(t, "null_resource.test[0]", planned[0].Change.Resource.Addr)
+	assert.Equal(t, "create", planned[0].Change.Action)
+
+	completed := events.OfType(UIEventApplyComplete)
+	require.Len(t, completed, 1)
+	assert.Equal(t, "null_resource", completed[0].Hook.Resource.ResourceType)
+	assert.Equal(t, "123", completed[0].Hook.IDValue)
+
+	assert.Equal(t, &UIChangeSummary{Add: 1, Operation: "apply"}, events.ChangeSummary())
+	assert.Equal(t, "123", events.OfType(UIEventOutputs)[0].Outputs["id"].Value)
+
+	diagnostics := events.Diagnostics()
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, "Deprecated attribute", diagnostics[0].Summary)
+	assert.Empty(t, events.Errors())
+
+	cnt, err := GetResourceCountE(t, testApplyUIEventsOutput)
+	require.NoError(t, err)
+	assert.Equal(t, &ResourceCount{Add: 1}, cnt)
+}
+
+func TestParseUIEventsInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseUIEvents(`{"@level":"info",`)
+	require.Error(t, err)
+}
+
+func TestDiagnosticsError(t *testing.T) {
+	t.Parallel()
+
+	underlying := errors.New("exit status 1")
+	output := `{"@level":"error","@message":"Error: error configuring Terraform AWS Provider","@module":"terraform.ui","@timestamp":"2023-09-01T10:00:00.000000+02:00","diagnostic":{"severity":"error","summary":"error configuring Terraform AWS Provider","detail":"no valid credential sources found"},"type":"diagnostic"}`
+
+	_, err := withDiagnostics(&Options{JSONOutput: true}, output, underlying)
+	var diagnosticsErr *DiagnosticsError
+	require.True(t, errors.As(err, &diagnosticsErr))
+	assert.Equal(t, tfjson.DiagnosticSeverityError, diagnosticsErr.Diagnostics[0].Severity)
+	assert.Contains(t, err.Error(), "Error: error configuring Terraform AWS Provider")
+	assert.Contains(t, err.Error(), "no valid credential sources found")
+	assert.True(t, errors.Is(err, underlying))
+
+	_, err = withDiagnostics(&Options{}, output, underlying)
+	assert.Equal(t, underlying, err)
+}
+
+func TestUIEventLogger(t *testing.T) {
+	t.Parallel()
+
+	events := UIEvents{}
+	log := uiEventLogger{delegate: logger.Discard, mutex: &sync.Mutex{}, handler: func(event UIEvent) {
+		events = append(events, event)
+	}}
+	for _, line := range []string{"Running terraform apply", `{"@message":"null_resource.test[0]: Creating...","type":"apply_start"}`, "{not json"} {
+		log.Logf(t, "%s", line)
+	}
+	require.Len(t, events, 1)
+	assert.Equal(t, UIEventApplyStart, events[0].Type)
+}
+
+func TestUIEventHandlerCalledOneAtATime(t *testing.T) {
+	t.Parallel()
+
+	events := UIEvents{}
+	cmd := generateCommand(&Options{
+		JSONOutput: true,
+		Logger:     logger.Discard,
+		UIEventHandler: func(event UIEvent) {
+			events = append(events, event)
+		},
+	})
+
+	// The lines of stdout and stderr are logged from separate goroutines
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cmd.Logger.Logf(t, "%s", `{"@message":"null_resource.test[0]: Creating...","type":"apply_start"}`)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, events, 200)
+}
+
+func TestInitAndApplyWithJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-basic-configuration", t.Name())
+	require.NoError(t, err)
+
+	var streamed []UIEventType
+	options := &Options{
+		TerraformDir: testFolder,
+		JSONOutput:   true,
+		Vars: map[string]interface{}{
+			"cnt": 2,
+		},
+		UIEventHandler: func(event UIEvent) {
+			streamed = append(streamed, event.Type)
+		},
+	}
+	defer Destroy(t, options)
+
+	out := InitAndApply(t, options)
+	events, err := ParseUIEvents(out)
+	require.NoError(t, err)
+	assert.Len(t, events.OfType(UIEventApplyComplete), 2)
+	assert.Contains(t, streamed, UIEventApplyComplete)
+	assert.Equal(t, &ResourceCount{Add: 2}, GetResourceCount(t, out))
+
+	options.PlanFilePath = filepath.Join(testFolder, "plan.out")
+	plan := InitAndPlanAndShowWithStruct(t, options)
+	plan.Resources().WithAction(ChangeActionCreate).IsEmpty(t)
+}
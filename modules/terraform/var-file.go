@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/gruntwork-io/terratest/modules/testing"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/stretchr/testify/require"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/gocty"
@@ -247,3 +249,76 @@ func generateTypeFromValuesMap(valMap map[string]cty.Value) cty.Type {
 	}
 	return cty.Object(outType)
 }
+
+// WriteVarFile writes the given variables to a var file at the given path, which Terraform can then load with
+// -var-file (or automatically, if the name ends in .auto.tfvars or .auto.tfvars.json). The file is written as JSON if
+// the path ends in .json, and as HCL otherwise. Unlike Vars on the Options, this supports passing null as the value of
+// a variable, and keeps the values off the command line. This will fail the test if there is an error.
+func WriteVarFile(t testing.TestingT, fileName string, vars map[string]interface{}) {
+	require.NoError(t, WriteVarFileE(t, fileName, vars))
+}
+
+// WriteVarFileE writes the given variables to a var file at the given path, which Terraform can then load with
+// -var-file (or automatically, if the name ends in .auto.tfvars or .auto.tfvars.json). The file is written as JSON if
+// the path ends in .json, and as HCL otherwise. As var files often contain secrets, the file is only readable by the
+// current user.
+func WriteVarFileE(t testing.TestingT, fileName string, vars map[string]interface{}) error {
+	var contents []byte
+	var err error
+	if strings.HasSuffix(fileName, ".json") {
+		contents, err = FormatVarsAsJSONE(vars)
+	} else {
+		contents, err = FormatVarsAsHCLE(vars)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, contents, 0600)
+}
+
+// FormatVarsAsJSONE serializes the given variables to the contents of a .tfvars.json file.
+func FormatVarsAsJSONE(vars map[string]interface{}) ([]byte, error) {
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+	contents, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(contents, '\n'), nil
+}
+
+// FormatVarsAsHCLE serializes the given variables to the contents of a .tfvars file. Variables are written in
+// alphabetical order. Any Go value that can be marshalled to JSON is supported, including nested maps, lists, numbers,
+// and nils, which are written as null.
+func FormatVarsAsHCLE(vars map[string]interface{}) ([]byte, error) {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	file := hclwrite.NewEmptyFile()
+	for _, name := range names {
+		val, err := goValueToCtyValue(vars[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert variable %q to HCL: %w", name, err)
+		}
+		file.Body().SetAttributeValue(name, val)
+	}
+	return file.Bytes(), nil
+}
+
+// goValueToCtyValue converts an arbitrary Go value to a cty value by round tripping it through JSON, which is the same
+// way Terraform reads .tfvars.json files.
+func goValueToCtyValue(value interface{}) (cty.Value, error) {
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ctyType, err := ctyjson.ImpliedType(jsonBytes)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(jsonBytes, ctyType)
+}
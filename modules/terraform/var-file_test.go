@@ -3,8 +3,10 @@ package terraform
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/gruntwork-io/terratest/modules/files"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/require"
 )
@@ -473,3 +475,87 @@ func WriteFile(t *testing.T, fileName string, bytes []byte) {
 
 	require.NoError(t, err)
 }
+
+func TestWriteVarFileRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]interface{}{
+		"aws_region": "us-east-2",
+		"count":      3,
+		"ratio":      0.5,
+		"enabled":    true,
+		"nothing":    nil,
+		"quoted":     `say "hi" ${not_interpolated}`,
+		"list":       []string{"item1", "item2"},
+		"tags": map[string]interface{}{
+			"foo":    "bar",
+			"absent": nil,
+			"nested": map[string]interface{}{"ports": []int{80, 443}},
+		},
+	}
+	expected := map[string]interface{}{
+		"aws_region": "us-east-2",
+		"count":      float64(3),
+		"ratio":      0.5,
+		"enabled":    true,
+		"nothing":    nil,
+		"quoted":     `say "hi" ${not_interpolated}`,
+		"list":       []interface{}{"item1", "item2"},
+		"tags": map[string]interface{}{
+			"foo":    "bar",
+			"absent": nil,
+			"nested": map[string]interface{}{"ports": []interface{}{float64(80), float64(443)}},
+		},
+	}
+
+	for _, fileName := range []string{"vars.tfvars", "vars.tfvars.json"} {
+		fileName := filepath.Join(t.TempDir(), fileName)
+		WriteVarFile(t, fileName, vars)
+
+		info, err := os.Stat(fileName)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		actual := map[string]interface{}{}
+		GetAllVariablesFromVarFile(t, fileName, &actual)
+		require.Equal(t, expected, actual, fileName)
+	}
+}
+
+func TestFormatVarsAsHCLE(t *testing.T) {
+	t.Parallel()
+
+	hcl, err := FormatVarsAsHCLE(map[string]interface{}{
+		"name":    "test",
+		"nothing": nil,
+		"cnt":     2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "cnt     = 2\nname    = \"test\"\nnothing = null\n", string(hcl))
+
+	_, err = FormatVarsAsHCLE(map[string]interface{}{"invalid": func() {}})
+	require.Error(t, err)
+}
+
+func TestInitAndApplyWithWriteVarsToFile(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-basic-configuration", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir:    testFolder,
+		WriteVarsToFile: true,
+		Vars: map[string]interface{}{
+			"cnt": 2,
+		},
+	}
+	defer Destroy(t, options)
+
+	out := InitAndApply(t, options)
+	require.Equal(t, 2, GetResourceCount(t, out).Add)
+
+	autoVarFiles, err := filepath.Glob(filepath.Join(testFolder, "*.auto.tfvars.json"))
+	require.NoError(t, err)
+	require.Empty(t, autoVarFiles)
+}
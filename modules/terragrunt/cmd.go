@@ -0,0 +1,91 @@
+package terragrunt
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// RunTerragruntCommand runs terragrunt with the given arguments and options and returns stdout/stderr. This will fail
+// the test if there is an error.
+func RunTerragruntCommand(t testing.TestingT, options *Options, args ...string) string {
+	out, err := RunTerragruntCommandE(t, options, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// RunTerragruntCommandE runs terragrunt with the given arguments and options and returns stdout/stderr.
+func RunTerragruntCommandE(t testing.TestingT, options *Options, args ...string) (string, error) {
+	cmd := generateCommand(options, args...)
+	description := fmt.Sprintf("%s %v", cmd.Command, cmd.Args)
+	return retry.DoWithRetryableErrorsE(t, description, options.RetryableTerragruntErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
+		return shell.RunCommandAndGetOutputE(t, cmd)
+	})
+}
+
+// RunTerragruntCommandAndGetStdoutE runs terragrunt with the given arguments and options and returns solely its stdout
+// (but not stderr), e.g. to parse json output.
+func RunTerragruntCommandAndGetStdoutE(t testing.TestingT, options *Options, args ...string) (string, error) {
+	cmd := generateCommand(options, args...)
+	description := fmt.Sprintf("%s %v", cmd.Command, cmd.Args)
+	return retry.DoWithRetryableErrorsE(t, description, options.RetryableTerragruntErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
+		return shell.RunCommandAndGetStdOutE(t, cmd)
+	})
+}
+
+func generateCommand(options *Options, args ...string) shell.Command {
+	binary := options.TerragruntBinary
+	if binary == "" {
+		binary = DefaultTerragruntBinary
+	}
+	return shell.Command{
+		Command:    binary,
+		Args:       append(append([]string{}, args...), formatTerragruntArgs(options)...),
+		WorkingDir: options.TerragruntDir,
+		Env:        options.EnvVars,
+		Logger:     options.Logger,
+		Context:    options.Context,
+		Timeout:    options.Timeout,
+	}
+}
+
+// formatTerragruntArgs returns the terragrunt specific flags for the given options. Terragrunt strips these before
+// passing the remaining args on to terraform.
+func formatTerragruntArgs(options *Options) []string {
+	args := []string{"--terragrunt-non-interactive"}
+	if options.TerraformBinary != "" {
+		args = append(args, "--terragrunt-tfpath", options.TerraformBinary)
+	}
+	for _, dir := range options.IncludeDirs {
+		args = append(args, "--terragrunt-include-dir", dir)
+	}
+	for _, dir := range options.ExcludeDirs {
+		args = append(args, "--terragrunt-exclude-dir", dir)
+	}
+	if options.StrictInclude {
+		args = append(args, "--terragrunt-strict-include")
+	}
+	if options.IgnoreDependencyErrors {
+		args = append(args, "--terragrunt-ignore-dependency-errors")
+	}
+	if options.Parallelism > 0 {
+		args = append(args, "--terragrunt-parallelism", strconv.Itoa(options.Parallelism))
+	}
+	return args
+}
+
+// formatTerraformArgs returns the args for the given terraform command, followed by the terraform flags from the
+// options.
+func formatTerraformArgs(options *Options, args ...string) []string {
+	out := append([]string{}, args...)
+	out = append(out, options.ExtraArgs...)
+	if options.NoColor {
+		out = append(out, "-no-color")
+	}
+	return out
+}
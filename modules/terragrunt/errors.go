@@ -0,0 +1,79 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UnitError is the failure of a single unit in a run-all command.
+type UnitError struct {
+	// The path of the unit, relative to TerragruntDir.
+	Unit string
+	// The error terragrunt reported for the unit.
+	Message string
+	// If set, the unit did not run (or failed) because this dependency, relative to TerragruntDir, failed first.
+	FailedDependency string
+}
+
+func (err UnitError) Error() string {
+	if err.FailedDependency != "" {
+		return fmt.Sprintf("unit %s failed because its dependency %s failed", err.Unit, err.FailedDependency)
+	}
+	return fmt.Sprintf("unit %s failed: %s", err.Unit, err.Message)
+}
+
+// RunAllError is returned when a run-all command fails, with the failures of the individual units that terragrunt
+// reported.
+type RunAllError struct {
+	Command    string
+	Units      []UnitError // Sorted by unit path
+	Underlying error
+}
+
+func (err *RunAllError) Error() string {
+	messages := []string{}
+	for _, unit := range err.Units {
+		messages = append(messages, "  "+unit.Error())
+	}
+	return fmt.Sprintf("terragrunt run-all %s failed for %d unit(s):\n%s\n%v", err.Command, len(err.Units), strings.Join(messages, "\n"), err.Underlying)
+}
+
+func (err *RunAllError) Unwrap() error {
+	return err.Underlying
+}
+
+// Unit returns the failure of the unit with the given path (relative to TerragruntDir), or nil if that unit did not
+// fail.
+func (err *RunAllError) Unit(path string) *UnitError {
+	for i := range err.Units {
+		if err.Units[i].Unit == path {
+			return &err.Units[i]
+		}
+	}
+	return nil
+}
+
+// FailedUnits returns the paths of all the units that failed, including those that failed due to a dependency.
+func (err *RunAllError) FailedUnits() []string {
+	out := []string{}
+	for _, unit := range err.Units {
+		out = append(out, unit.Unit)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// UnitNotFound is returned when a unit is not part of the stack.
+type UnitNotFound string
+
+func (err UnitNotFound) Error() string {
+	return fmt.Sprintf("unit %q not found in the terragrunt stack", string(err))
+}
+
+// DependencyCycle is returned when the dependency graph of a stack contains a cycle.
+type DependencyCycle []string
+
+func (err DependencyCycle) Error() string {
+	return fmt.Sprintf("found a dependency cycle between units: %s", strings.Join(err, " -> "))
+}
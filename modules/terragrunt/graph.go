@@ -0,0 +1,165 @@
+package terragrunt
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// DependencyGraph is the graph of dependencies between the units of a terragrunt stack. Units are identified by their
+// path relative to the root of the stack.
+type DependencyGraph struct {
+	// All the units in the stack, sorted.
+	Units []string
+
+	dependencies map[string][]string
+	dependents   map[string][]string
+}
+
+// GraphDependencies runs terragrunt graph-dependencies for the stack at options.TerragruntDir and parses the result
+// into a DependencyGraph. This will fail the test if there is an error.
+func GraphDependencies(t testing.TestingT, options *Options) *DependencyGraph {
+	graph, err := GraphDependenciesE(t, options)
+	require.NoError(t, err)
+	return graph
+}
+
+// GraphDependenciesE runs terragrunt graph-dependencies for the stack at options.TerragruntDir and parses the result
+// into a DependencyGraph.
+func GraphDependenciesE(t testing.TestingT, options *Options) (*DependencyGraph, error) {
+	out, err := RunTerragruntCommandAndGetStdoutE(t, options, "graph-dependencies")
+	if err != nil {
+		return nil, err
+	}
+	return ParseDependencyGraph(options.TerragruntDir, out), nil
+}
+
+var (
+	dotEdgeRegexp = regexp.MustCompile(`^\s*"([^"]+)"\s*->\s*"([^"]+)"`)
+	dotNodeRegexp = regexp.MustCompile(`^\s*"([^"]+)"\s*;`)
+)
+
+// ParseDependencyGraph parses the DOT output of terragrunt graph-dependencies into a DependencyGraph, making the unit
+// paths relative to the given root dir of the stack. An edge from unit A to unit B means that A depends on B.
+func ParseDependencyGraph(rootDir string, dot string) *DependencyGraph {
+	graph := &DependencyGraph{dependencies: map[string][]string{}, dependents: map[string][]string{}}
+	units := map[string]bool{}
+
+	for _, line := range strings.Split(dot, "\n") {
+		if matches := dotEdgeRegexp.FindStringSubmatch(line); matches != nil {
+			unit := relativeUnitPath(rootDir, matches[1])
+			dependency := relativeUnitPath(rootDir, matches[2])
+			units[unit] = true
+			units[dependency] = true
+			graph.dependencies[unit] = append(graph.dependencies[unit], dependency)
+			graph.dependents[dependency] = append(graph.dependents[dependency], unit)
+		} else if matches := dotNodeRegexp.FindStringSubmatch(line); matches != nil {
+			units[relativeUnitPath(rootDir, matches[1])] = true
+		}
+	}
+
+	for unit := range units {
+		graph.Units = append(graph.Units, unit)
+		sort.Strings(graph.dependencies[unit])
+		sort.Strings(graph.dependents[unit])
+	}
+	sort.Strings(graph.Units)
+	return graph
+}
+
+// HasUnit returns true if the given unit is part of the stack.
+func (graph *DependencyGraph) HasUnit(unit string) bool {
+	index := sort.SearchStrings(graph.Units, unit)
+	return index < len(graph.Units) && graph.Units[index] == unit
+}
+
+// Dependencies returns the units the given unit directly depends on, sorted.
+func (graph *DependencyGraph) Dependencies(unit string) []string {
+	return append([]string{}, graph.dependencies[unit]...)
+}
+
+// Dependents returns the units that directly depend on the given unit, sorted.
+func (graph *DependencyGraph) Dependents(unit string) []string {
+	return append([]string{}, graph.dependents[unit]...)
+}
+
+// DependsOn returns true if the given unit depends on the given dependency, directly or indirectly.
+func (graph *DependencyGraph) DependsOn(unit string, dependency string) bool {
+	for _, transitiveDependency := range graph.TransitiveDependencies(unit) {
+		if transitiveDependency == dependency {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitiveDependencies returns all the units the given unit depends on, directly or indirectly, sorted.
+func (graph *DependencyGraph) TransitiveDependencies(unit string) []string {
+	seen := map[string]bool{}
+	var visit func(unit string)
+	visit = func(unit string) {
+		for _, dependency := range graph.dependencies[unit] {
+			if !seen[dependency] {
+				seen[dependency] = true
+				visit(dependency)
+			}
+		}
+	}
+	visit(unit)
+
+	out := []string{}
+	for dependency := range seen {
+		out = append(out, dependency)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TopologicalOrder returns all the units in the order in which terragrunt applies them: every unit comes after all of
+// its dependencies. Units without an ordering constraint between them are sorted by path. This returns a
+// DependencyCycle error if the dependencies contain a cycle.
+func (graph *DependencyGraph) TopologicalOrder() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	out := []string{}
+	path := []string{}
+
+	var visit func(unit string) error
+	visit = func(unit string) error {
+		switch state[unit] {
+		case visited:
+			return nil
+		case visiting:
+			for i, pathUnit := range path {
+				if pathUnit == unit {
+					return DependencyCycle(append(append([]string{}, path[i:]...), unit))
+				}
+			}
+		}
+		state[unit] = visiting
+		path = append(path, unit)
+		for _, dependency := range graph.dependencies[unit] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[unit] = visited
+		out = append(out, unit)
+		return nil
+	}
+
+	for _, unit := range graph.Units {
+		if err := visit(unit); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,66 @@
+package terragrunt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDependencyGraph(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	dot := `digraph {
+	"` + filepath.Join(rootDir, "app") + `" ;
+	"` + filepath.Join(rootDir, "app") + `" -> "` + filepath.Join(rootDir, "network") + `";
+	"` + filepath.Join(rootDir, "app") + `" -> "` + filepath.Join(rootDir, "dns") + `";
+	"` + filepath.Join(rootDir, "dns") + `" ;
+	"` + filepath.Join(rootDir, "dns") + `" -> "` + filepath.Join(rootDir, "network") + `";
+	"` + filepath.Join(rootDir, "network") + `" ;
+	"` + filepath.Join(rootDir, "monitoring") + `" ;
+}
+`
+
+	graph := ParseDependencyGraph(rootDir, dot)
+	assert.Equal(t, []string{"app", "dns", "monitoring", "network"}, graph.Units)
+	assert.True(t, graph.HasUnit("monitoring"))
+	assert.False(t, graph.HasUnit("database"))
+	assert.Equal(t, []string{"dns", "network"}, graph.Dependencies("app"))
+	assert.Equal(t, []string{"app", "dns"}, graph.Dependents("network"))
+	assert.Equal(t, []string{"dns", "network"}, graph.TransitiveDependencies("app"))
+	assert.True(t, graph.DependsOn("app", "network"))
+	assert.False(t, graph.DependsOn("network", "app"))
+	assert.Empty(t, graph.Dependencies("database"))
+
+	order, err := graph.TopologicalOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"network", "dns", "app", "monitoring"}, order)
+}
+
+func TestTopologicalOrderWithCycle(t *testing.T) {
+	t.Parallel()
+
+	graph := ParseDependencyGraph(".", `digraph {
+	"a" -> "b";
+	"b" -> "c";
+	"c" -> "a";
+}`)
+
+	_, err := graph.TopologicalOrder()
+	require.Error(t, err)
+	assert.Equal(t, DependencyCycle{"a", "b", "c", "a"}, err)
+}
+
+func TestGraphDependencies(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerragruntFolderToTemp("../../test/fixtures/terragrunt/terragrunt-stack", t.Name())
+	require.NoError(t, err)
+
+	graph := GraphDependencies(t, &Options{TerragruntDir: testFolder})
+	assert.Equal(t, []string{"app", "network"}, graph.Units)
+	assert.Equal(t, []string{"network"}, graph.Dependencies("app"))
+}
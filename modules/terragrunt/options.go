@@ -0,0 +1,41 @@
+// Package terragrunt allows to interact with Terragrunt, including stacks of units run with run-all.
+package terragrunt
+
+import (
+	"context"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// DefaultTerragruntBinary is the name of the binary used when TerragruntBinary is not set on the Options.
+const DefaultTerragruntBinary = "terragrunt"
+
+// Options for running Terragrunt commands
+type Options struct {
+	TerragruntBinary string // Name of the terragrunt binary. Defaults to terragrunt.
+	TerraformBinary  string // Name of the terraform (or tofu) binary that terragrunt should run (--terragrunt-tfpath). Defaults to terragrunt's own default.
+	TerragruntDir    string // The path to the folder with the terragrunt.hcl of a single unit, or the root folder of a stack for the *All functions.
+
+	IncludeDirs               []string          // Glob patterns of the units to include in run-all commands (--terragrunt-include-dir)
+	ExcludeDirs               []string          // Glob patterns of the units to exclude from run-all commands (--terragrunt-exclude-dir)
+	StrictInclude             bool              // Only include the units in IncludeDirs, but not their dependencies (--terragrunt-strict-include)
+	IgnoreDependencyErrors    bool              // Keep running the dependents of units that failed (--terragrunt-ignore-dependency-errors)
+	Parallelism               int               // The maximum number of units to run concurrently in run-all commands (--terragrunt-parallelism)
+	NoColor                   bool              // Whether the -no-color flag will be passed to terraform or not
+	EnvVars                   map[string]string // Environment variables to set when running Terragrunt
+	ExtraArgs                 []string          // Extra arguments to pass to every terraform command (e.g., -var-file=common.tfvars)
+	RetryableTerragruntErrors map[string]string // If a command fails with one of these (transient) errors, retry. The keys are a regexp to match against the error and the message is what to display to a user if that error is matched.
+	MaxRetries                int               // Maximum number of times to retry errors matching RetryableTerragruntErrors
+	TimeBetweenRetries        time.Duration     // The amount of time to wait between retries
+	Logger                    *logger.Logger    // Set a non-default logger that should be used. See the logger package for more info.
+	Context                   context.Context   `json:"-"` // If set, running Terragrunt commands are interrupted when the context is cancelled. See shell.Command for details.
+	Timeout                   time.Duration     // The maximum amount of time each individual Terragrunt command is allowed to run before it is interrupted.
+}
+
+// forUnit returns a copy of the options that runs commands in the given unit directory.
+func (options *Options) forUnit(unitDir string) *Options {
+	unitOptions := *options
+	unitOptions.TerragruntDir = unitDir
+	return &unitOptions
+}
@@ -0,0 +1,97 @@
+package terragrunt
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// Output runs terragrunt output for the unit at options.TerragruntDir and returns the values of all its outputs. This
+// will fail the test if there is an error.
+func Output(t testing.TestingT, options *Options) map[string]interface{} {
+	outputs, err := OutputE(t, options)
+	require.NoError(t, err)
+	return outputs
+}
+
+// OutputE runs terragrunt output for the unit at options.TerragruntDir and returns the values of all its outputs.
+func OutputE(t testing.TestingT, options *Options) (map[string]interface{}, error) {
+	out, err := RunTerragruntCommandAndGetStdoutE(t, options, "output", "-no-color", "-json")
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs map[string]struct {
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(out), &outputs); err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	for name, output := range outputs {
+		values[name] = output.Value
+	}
+	return values, nil
+}
+
+// OutputAll returns the outputs of every unit in the stack at options.TerragruntDir, as a map from the unit path
+// (relative to TerragruntDir) to the values of the outputs of that unit. Units are discovered with graph-dependencies,
+// and filtered with IncludeDirs and ExcludeDirs (matched against the relative unit path). This will fail the test if
+// there is an error.
+func OutputAll(t testing.TestingT, options *Options) map[string]map[string]interface{} {
+	outputs, err := OutputAllE(t, options)
+	require.NoError(t, err)
+	return outputs
+}
+
+// OutputAllE returns the outputs of every unit in the stack at options.TerragruntDir, as a map from the unit path
+// (relative to TerragruntDir) to the values of the outputs of that unit. Units are discovered with graph-dependencies,
+// and filtered with IncludeDirs and ExcludeDirs (matched against the relative unit path).
+func OutputAllE(t testing.TestingT, options *Options) (map[string]map[string]interface{}, error) {
+	graph, err := GraphDependenciesE(t, options)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]map[string]interface{}{}
+	for _, unit := range graph.Units {
+		included, err := isUnitIncluded(options, unit)
+		if err != nil {
+			return nil, err
+		}
+		if !included {
+			continue
+		}
+		unitDir := unit
+		if !filepath.IsAbs(unitDir) {
+			unitDir = filepath.Join(options.TerragruntDir, unit)
+		}
+		outputs, err := OutputE(t, options.forUnit(unitDir))
+		if err != nil {
+			return nil, err
+		}
+		out[unit] = outputs
+	}
+	return out, nil
+}
+
+// isUnitIncluded returns true if the unit with the given relative path matches IncludeDirs (if set) and does not match
+// ExcludeDirs.
+func isUnitIncluded(options *Options, unit string) (bool, error) {
+	for _, pattern := range options.ExcludeDirs {
+		if matched, err := filepath.Match(filepath.ToSlash(pattern), unit); err != nil || matched {
+			return false, err
+		}
+	}
+	if len(options.IncludeDirs) == 0 {
+		return true, nil
+	}
+	for _, pattern := range options.IncludeDirs {
+		if matched, err := filepath.Match(filepath.ToSlash(pattern), unit); err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,94 @@
+package terragrunt
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// RenderedConfig is the terragrunt.hcl of a unit with all the locals, includes, and functions resolved, as returned by
+// terragrunt render-json.
+type RenderedConfig struct {
+	// The source of the terraform module the unit deploys (the terraform.source attribute).
+	TerraformSource string
+	// The inputs that are passed to the terraform module.
+	Inputs map[string]interface{}
+	// The resolved locals.
+	Locals map[string]interface{}
+	// The paths of the units this unit depends on (the dependencies.paths attribute), as written in the config.
+	DependencyPaths []string
+	// The complete rendered config, for all the attributes that are not available as fields.
+	Raw map[string]interface{}
+}
+
+// RenderJSON runs terragrunt render-json for the unit at options.TerragruntDir and parses the result. This will fail
+// the test if there is an error.
+func RenderJSON(t testing.TestingT, options *Options) *RenderedConfig {
+	config, err := RenderJSONE(t, options)
+	require.NoError(t, err)
+	return config
+}
+
+// RenderJSONE runs terragrunt render-json for the unit at options.TerragruntDir and parses the result.
+func RenderJSONE(t testing.TestingT, options *Options) (*RenderedConfig, error) {
+	tmpDir, err := os.MkdirTemp("", "terratest-render-json-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	outFile := filepath.Join(tmpDir, "terragrunt_rendered.json")
+	if _, err := RunTerragruntCommandE(t, options, "render-json", "--terragrunt-json-out", outFile); err != nil {
+		return nil, err
+	}
+	rendered, err := os.ReadFile(outFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRenderedConfigJSON(string(rendered))
+}
+
+// ParseRenderedConfigJSON parses the output of terragrunt render-json.
+func ParseRenderedConfigJSON(jsonStr string) (*RenderedConfig, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &raw); err != nil {
+		return nil, err
+	}
+
+	var typed struct {
+		Terraform *struct {
+			Source string `json:"source"`
+		} `json:"terraform"`
+		Inputs       map[string]interface{} `json:"inputs"`
+		Locals       map[string]interface{} `json:"locals"`
+		Dependencies *struct {
+			Paths []string `json:"paths"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &typed); err != nil {
+		return nil, err
+	}
+
+	config := &RenderedConfig{
+		Inputs:          typed.Inputs,
+		Locals:          typed.Locals,
+		DependencyPaths: []string{},
+		Raw:             raw,
+	}
+	if config.Inputs == nil {
+		config.Inputs = map[string]interface{}{}
+	}
+	if config.Locals == nil {
+		config.Locals = map[string]interface{}{}
+	}
+	if typed.Terraform != nil {
+		config.TerraformSource = typed.Terraform.Source
+	}
+	if typed.Dependencies != nil {
+		config.DependencyPaths = typed.Dependencies.Paths
+	}
+	return config, nil
+}
@@ -0,0 +1,43 @@
+package terragrunt
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRenderedConfigJSON(t *testing.T) {
+	t.Parallel()
+
+	config, err := ParseRenderedConfigJSON(`{
+  "terraform": {"source": "git::https://example.com/modules.git//vpc?ref=v1.0.0"},
+  "inputs": {"cidr_block": "10.0.0.0/16", "azs": ["a", "b"]},
+  "locals": {"env": "test"},
+  "dependencies": {"paths": ["../network"]},
+  "remote_state": {"backend": "s3"}
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, "git::https://example.com/modules.git//vpc?ref=v1.0.0", config.TerraformSource)
+	assert.Equal(t, map[string]interface{}{"cidr_block": "10.0.0.0/16", "azs": []interface{}{"a", "b"}}, config.Inputs)
+	assert.Equal(t, map[string]interface{}{"env": "test"}, config.Locals)
+	assert.Equal(t, []string{"../network"}, config.DependencyPaths)
+	assert.Equal(t, map[string]interface{}{"backend": "s3"}, config.Raw["remote_state"])
+
+	empty, err := ParseRenderedConfigJSON(`{}`)
+	require.NoError(t, err)
+	assert.Empty(t, empty.Inputs)
+	assert.Empty(t, empty.DependencyPaths)
+}
+
+func TestRenderJSON(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerragruntFolderToTemp("../../test/fixtures/terragrunt/terragrunt-stack", t.Name())
+	require.NoError(t, err)
+
+	config := RenderJSON(t, &Options{TerragruntDir: testFolder + "/app"})
+	assert.Equal(t, map[string]interface{}{"name": "app"}, config.Locals)
+	assert.Equal(t, "0.0.0.0/0", config.Inputs["cidr_block"])
+}
@@ -0,0 +1,155 @@
+package terragrunt
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// RunAll runs terragrunt run-all with the given terraform command and args for all the units in the stack at
+// options.TerragruntDir, honoring IncludeDirs and ExcludeDirs, and returns stdout/stderr. This will fail the test if
+// there is an error.
+func RunAll(t testing.TestingT, options *Options, args ...string) string {
+	out, err := RunAllE(t, options, args...)
+	require.NoError(t, err)
+	return out
+}
+
+// RunAllE runs terragrunt run-all with the given terraform command and args for all the units in the stack at
+// options.TerragruntDir, honoring IncludeDirs and ExcludeDirs, and returns stdout/stderr. If any unit fails, the error
+// is a *RunAllError that lists the failed units.
+func RunAllE(t testing.TestingT, options *Options, args ...string) (string, error) {
+	out, err := RunTerragruntCommandE(t, options, append([]string{"run-all"}, formatTerraformArgs(options, args...)...)...)
+	if err == nil {
+		return out, nil
+	}
+	units := parseUnitErrors(options.TerragruntDir, out)
+	if len(units) == 0 {
+		return out, err
+	}
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+	return out, &RunAllError{Command: command, Units: units, Underlying: err}
+}
+
+// ApplyAll runs terragrunt run-all apply for all the units in the stack and returns stdout/stderr. Note that this
+// method does NOT call destroy and assumes the caller is responsible for cleaning up any resources created by running
+// apply. This will fail the test if there is an error.
+func ApplyAll(t testing.TestingT, options *Options) string {
+	out, err := ApplyAllE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// ApplyAllE runs terragrunt run-all apply for all the units in the stack and returns stdout/stderr. Note that this
+// method does NOT call destroy and assumes the caller is responsible for cleaning up any resources created by running
+// apply.
+func ApplyAllE(t testing.TestingT, options *Options) (string, error) {
+	return RunAllE(t, options, "apply", "-input=false", "-auto-approve")
+}
+
+// DestroyAll runs terragrunt run-all destroy for all the units in the stack and returns stdout/stderr. This will fail
+// the test if there is an error.
+func DestroyAll(t testing.TestingT, options *Options) string {
+	out, err := DestroyAllE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// DestroyAllE runs terragrunt run-all destroy for all the units in the stack and returns stdout/stderr.
+func DestroyAllE(t testing.TestingT, options *Options) (string, error) {
+	return RunAllE(t, options, "destroy", "-input=false", "-auto-approve")
+}
+
+// PlanAllExitCode runs terragrunt run-all plan with -detailed-exitcode for all the units in the stack and returns the
+// exit code, which is 2 if any unit has changes. This will fail the test if there is an error.
+func PlanAllExitCode(t testing.TestingT, options *Options) int {
+	exitCode, err := PlanAllExitCodeE(t, options)
+	require.NoError(t, err)
+	return exitCode
+}
+
+// PlanAllExitCodeE runs terragrunt run-all plan with -detailed-exitcode for all the units in the stack and returns the
+// exit code, which is 2 if any unit has changes.
+func PlanAllExitCodeE(t testing.TestingT, options *Options) (int, error) {
+	cmd := generateCommand(options, append([]string{"run-all"}, formatTerraformArgs(options, "plan", "-input=false", "-lock=false", "-detailed-exitcode")...)...)
+	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	if err == nil {
+		return 0, nil
+	}
+	return shell.GetExitCodeForRunCommandError(err)
+}
+
+var (
+	unitErrorRegexp       = regexp.MustCompile(`Module (\S+) has finished with an error: (.*)`)
+	dependencyErrorRegexp = regexp.MustCompile(`Dependency (\S+) of module (\S+) just finished with an error`)
+)
+
+// parseUnitErrors extracts the failures of the individual units from the output of a run-all command, with the unit
+// paths made relative to the given root dir.
+func parseUnitErrors(rootDir string, output string) []UnitError {
+	units := map[string]*UnitError{}
+	getUnit := func(path string) *UnitError {
+		path = relativeUnitPath(rootDir, path)
+		if _, exists := units[path]; !exists {
+			units[path] = &UnitError{Unit: path}
+		}
+		return units[path]
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if matches := unitErrorRegexp.FindStringSubmatch(line); matches != nil {
+			unit := getUnit(matches[1])
+			if unit.Message == "" {
+				unit.Message = cleanLogMessage(matches[2])
+			}
+		}
+		if matches := dependencyErrorRegexp.FindStringSubmatch(line); matches != nil {
+			unit := getUnit(matches[2])
+			unit.FailedDependency = relativeUnitPath(rootDir, matches[1])
+		}
+	}
+
+	out := make([]UnitError, 0, len(units))
+	for _, unit := range units {
+		out = append(out, *unit)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Unit < out[j].Unit })
+	return out
+}
+
+// cleanLogMessage strips the structured logging fields that newer versions of terragrunt append to log messages.
+func cleanLogMessage(message string) string {
+	if index := strings.Index(message, `" prefix=`); index >= 0 {
+		message = message[:index]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(message, `"`))
+}
+
+// relativeUnitPath returns the given (absolute) unit path relative to the given root dir, using forward slashes, or
+// the path as is if it is not inside the root dir.
+func relativeUnitPath(rootDir string, path string) string {
+	path = strings.Trim(path, `"`)
+	absRoot, err := filepath.Abs(rootDir)
+	if err != nil {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	relPath, err := filepath.Rel(absRoot, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return path
+	}
+	return filepath.ToSlash(relPath)
+}
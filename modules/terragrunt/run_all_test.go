@@ -0,0 +1,130 @@
+package terragrunt
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatTerragruntArgs(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{
+		TerraformBinary:        "tofu",
+		IncludeDirs:            []string{"app", "network/*"},
+		ExcludeDirs:            []string{"legacy"},
+		StrictInclude:          true,
+		IgnoreDependencyErrors: true,
+		Parallelism:            2,
+		NoColor:                true,
+		ExtraArgs:              []string{"-lock-timeout=5m"},
+	}
+
+	cmd := generateCommand(options, formatTerraformArgs(options, "run-all", "plan")...)
+	assert.Equal(t, "terragrunt", cmd.Command)
+	assert.Equal(t, []string{
+		"run-all", "plan", "-lock-timeout=5m", "-no-color",
+		"--terragrunt-non-interactive",
+		"--terragrunt-tfpath", "tofu",
+		"--terragrunt-include-dir", "app",
+		"--terragrunt-include-dir", "network/*",
+		"--terragrunt-exclude-dir", "legacy",
+		"--terragrunt-strict-include",
+		"--terragrunt-ignore-dependency-errors",
+		"--terragrunt-parallelism", "2",
+	}, cmd.Args)
+}
+
+func TestParseUnitErrors(t *testing.T) {
+	t.Parallel()
+
+	rootDir := t.TempDir()
+	output := `
+[terragrunt] 2023/09/01 10:00:00 Module ` + filepath.Join(rootDir, "network") + ` has finished with an error: exit status 1
+time=2023-09-01T10:00:01Z level=error msg=Dependency ` + filepath.Join(rootDir, "network") + ` of module ` + filepath.Join(rootDir, "app") + ` just finished with an error. Module ` + filepath.Join(rootDir, "app") + ` will have to return an error too.
+time=2023-09-01T10:00:01Z level=error msg="Module ` + filepath.Join(rootDir, "app") + ` has finished with an error: Cannot process module because one of its dependencies finished with an error" prefix=[` + filepath.Join(rootDir, "app") + `]
+`
+
+	units := parseUnitErrors(rootDir, output)
+	assert.Equal(t, []UnitError{
+		{Unit: "app", Message: "Cannot process module because one of its dependencies finished with an error", FailedDependency: "network"},
+		{Unit: "network", Message: "exit status 1"},
+	}, units)
+
+	underlying := errors.New("exit status 1")
+	err := &RunAllError{Command: "apply", Units: units, Underlying: underlying}
+	assert.Equal(t, []string{"app", "network"}, err.FailedUnits())
+	assert.Equal(t, "exit status 1", err.Unit("network").Message)
+	assert.Nil(t, err.Unit("dns"))
+	assert.True(t, errors.Is(err, underlying))
+	assert.Contains(t, err.Error(), "unit app failed because its dependency network failed")
+}
+
+func TestIsUnitIncluded(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		options  *Options
+		unit     string
+		expected bool
+	}{
+		{&Options{}, "app", true},
+		{&Options{IncludeDirs: []string{"app"}}, "app", true},
+		{&Options{IncludeDirs: []string{"app"}}, "network", false},
+		{&Options{IncludeDirs: []string{"services/*"}}, "services/api", true},
+		{&Options{ExcludeDirs: []string{"services/*"}}, "services/api", false},
+		{&Options{IncludeDirs: []string{"*"}, ExcludeDirs: []string{"network"}}, "network", false},
+	}
+
+	for _, testCase := range testCases {
+		included, err := isUnitIncluded(testCase.options, testCase.unit)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, included, "%s with %+v", testCase.unit, testCase.options)
+	}
+}
+
+func TestApplyAllAndOutputAll(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerragruntFolderToTemp("../../test/fixtures/terragrunt/terragrunt-stack", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerragruntDir: testFolder,
+	}
+	defer DestroyAll(t, options)
+
+	ApplyAll(t, options)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"app":     {"greeting": "app in 10.0.0.0/16"},
+		"network": {"cidr_block": "10.0.0.0/16"},
+	}, OutputAll(t, options))
+
+	networkOnly := &Options{TerragruntDir: testFolder, IncludeDirs: []string{"network"}}
+	assert.Equal(t, []string{"network"}, mapKeys(OutputAll(t, networkOnly)))
+	assert.Equal(t, 0, PlanAllExitCode(t, options))
+}
+
+func TestApplyAllWithUnitError(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerragruntFolderToTemp("../../test/fixtures/terragrunt/terragrunt-with-plan-error", t.Name())
+	require.NoError(t, err)
+
+	_, err = ApplyAllE(t, &Options{TerragruntDir: testFolder})
+	var runAllErr *RunAllError
+	require.True(t, errors.As(err, &runAllErr), "expected a *RunAllError, got %v", err)
+	assert.Equal(t, []string{"."}, runAllErr.FailedUnits())
+}
+
+func mapKeys(m map[string]map[string]interface{}) []string {
+	out := []string{}
+	for key := range m {
+		out = append(out, key)
+	}
+	return out
+}
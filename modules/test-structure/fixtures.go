@@ -0,0 +1,247 @@
+package test_structure
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	go_test "testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Fixture is an expensive piece of infrastructure (e.g., a VPC or a Kubernetes cluster) that several tests in a package
+// can share. See UseFixture.
+type Fixture struct {
+	// The name of the fixture, which identifies it within the test binary.
+	Name string
+	// Sets up the fixture and returns a value the tests can use (e.g., the outputs of a module). If it returns an error,
+	// or fails the test passed to it, the fixture is considered broken.
+	Setup func(t testing.TestingT) (interface{}, error)
+	// Tears down the fixture, given the value returned by Setup. Optional.
+	Teardown func(t testing.TestingT, value interface{}) error
+}
+
+// sharedFixture is the state of a fixture within the test binary.
+type sharedFixture struct {
+	// Guards the setup and teardown of the fixture
+	mutex sync.Mutex
+	ready bool
+	value interface{}
+	err   error
+
+	// The number of tests using the fixture, guarded by sharedFixturesMutex
+	users int
+}
+
+var (
+	sharedFixtures      = map[string]*sharedFixture{}
+	sharedFixturesMutex sync.Mutex
+)
+
+// cleanupTestingT is implemented by *testing.T.
+type cleanupTestingT interface {
+	testing.TestingT
+	Cleanup(f func())
+}
+
+// UseFixture returns the value of the given fixture, setting it up if no other test is using it. The fixture is torn
+// down when the last test using it completes, with t.Cleanup. Tests that request a fixture while another test is setting
+// it up wait for the setup to finish and then share its value, so fixtures work well with parallel tests. Note that a
+// fixture that is torn down because no test was using it anymore is set up again if another test requests it later
+// (e.g., with sequential tests), so call t.Parallel() in the tests that share a fixture, and keep in mind that the
+// number of tests that run at the same time is limited by the -parallel flag of go test (GOMAXPROCS by default).
+//
+// If the setup fails, every test that requests the fixture fails with the original error, without retrying the setup.
+//
+// Example:
+//
+//	var vpcFixture = test_structure.Fixture{
+//		Name: "vpc",
+//		Setup: func(t testing.TestingT) (interface{}, error) {
+//			return createVpc(t)
+//		},
+//		Teardown: func(t testing.TestingT, vpc interface{}) error {
+//			return deleteVpc(t, vpc.(*Vpc))
+//		},
+//	}
+//
+//	func TestFoo(t *testing.T) {
+//		t.Parallel()
+//		vpc := test_structure.UseFixture(t, vpcFixture).(*Vpc)
+//	}
+func UseFixture(t *go_test.T, fixture Fixture) interface{} {
+	return useFixture(t, fixture)
+}
+
+func useFixture(t cleanupTestingT, fixture Fixture) interface{} {
+	// Tests count as users as soon as they request the fixture, so that it is not torn down while they wait for the setup
+	sharedFixturesMutex.Lock()
+	shared, exists := sharedFixtures[fixture.Name]
+	if !exists {
+		shared = &sharedFixture{}
+		sharedFixtures[fixture.Name] = shared
+	}
+	shared.users++
+	sharedFixturesMutex.Unlock()
+
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+
+	if !shared.ready && shared.err == nil {
+		shared.setUp(t, fixture)
+	}
+	if shared.err != nil {
+		shared.removeUser()
+		t.Fatalf("Fixture '%s' failed to set up: %v", fixture.Name, shared.err)
+	}
+
+	t.Cleanup(func() { shared.release(t, fixture) })
+	return shared.value
+}
+
+// removeUser decrements the number of tests using the fixture, and returns true if there are none left.
+func (shared *sharedFixture) removeUser() bool {
+	sharedFixturesMutex.Lock()
+	defer sharedFixturesMutex.Unlock()
+
+	shared.users--
+	return shared.users == 0
+}
+
+// setUp runs the setup of the fixture and records its outcome. The caller must hold the mutex of the fixture.
+func (shared *sharedFixture) setUp(t testing.TestingT, fixture Fixture) {
+	logger.Logf(t, "Setting up fixture '%s'", fixture.Name)
+	setupT := &fixtureSetupT{TestingT: t}
+	completed := false
+
+	// If the setup fails the test, it stops with runtime.Goexit, so the error is recorded in a deferred function for the
+	// other tests waiting for the fixture
+	defer func() {
+		if !completed && shared.err == nil {
+			shared.err = setupT.failure(fmt.Sprintf("the setup did not complete, see the logs of %s", t.Name()))
+		}
+	}()
+
+	value, err := fixture.Setup(setupT)
+	completed = true
+	switch {
+	case err != nil:
+		shared.err = err
+	case setupT.failed:
+		shared.err = setupT.failure("the setup failed the test")
+	default:
+		shared.value = value
+		shared.ready = true
+	}
+}
+
+// release marks that t no longer uses the fixture, and tears the fixture down if it was the last test using it.
+func (shared *sharedFixture) release(t testing.TestingT, fixture Fixture) {
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+
+	if !shared.removeUser() || !shared.ready {
+		return
+	}
+
+	value := shared.value
+	shared.ready = false
+	shared.value = nil
+	if fixture.Teardown == nil {
+		return
+	}
+	logger.Logf(t, "Tearing down fixture '%s' as %s was the last test using it", fixture.Name, t.Name())
+	if err := fixture.Teardown(t, value); err != nil {
+		t.Errorf("Failed to tear down fixture '%s': %v", fixture.Name, err)
+	}
+}
+
+// fixtureSetupT is passed to the setup of a fixture to record why it fails the test, so that the same error can be
+// reported to the other tests using the fixture.
+type fixtureSetupT struct {
+	testing.TestingT
+	failed   bool
+	messages []string
+}
+
+func (t *fixtureSetupT) record(message string) {
+	t.failed = true
+	t.messages = append(t.messages, message)
+}
+
+func (t *fixtureSetupT) failure(fallback string) error {
+	if len(t.messages) == 0 {
+		return fmt.Errorf("%s", fallback)
+	}
+	return fmt.Errorf("%s", strings.Join(t.messages, "\n"))
+}
+
+func (t *fixtureSetupT) Fail() {
+	t.failed = true
+	t.TestingT.Fail()
+}
+
+func (t *fixtureSetupT) FailNow() {
+	t.failed = true
+	t.TestingT.FailNow()
+}
+
+func (t *fixtureSetupT) Fatal(args ...interface{}) {
+	t.record(fmt.Sprint(args...))
+	t.TestingT.Fatal(args...)
+}
+
+func (t *fixtureSetupT) Fatalf(format string, args ...interface{}) {
+	t.record(fmt.Sprintf(format, args...))
+	t.TestingT.Fatalf(format, args...)
+}
+
+func (t *fixtureSetupT) Error(args ...interface{}) {
+	t.record(fmt.Sprint(args...))
+	t.TestingT.Error(args...)
+}
+
+func (t *fixtureSetupT) Errorf(format string, args ...interface{}) {
+	t.record(fmt.Sprintf(format, args...))
+	t.TestingT.Errorf(format, args...)
+}
+
+// TerraformFixture returns a fixture that runs terraform init and apply with the given options, and terraform destroy
+// on teardown. Its value is a map of all the outputs of the module (see terraform.OutputAll). If apply fails, the
+// fixture runs destroy right away to clean up what was created. Make sure TerraformDir is not used by other tests (e.g.,
+// by copying the module with CopyTerraformFolderToTemp).
+func TerraformFixture(name string, options *terraform.Options) Fixture {
+	return Fixture{
+		Name: name,
+		Setup: func(t testing.TestingT) (interface{}, error) {
+			if _, err := terraform.InitAndApplyE(t, options); err != nil {
+				if _, destroyErr := terraform.DestroyE(t, options); destroyErr != nil {
+					logger.Logf(t, "Failed to destroy fixture '%s' after its apply failed: %v", name, destroyErr)
+				}
+				return nil, err
+			}
+			return terraform.OutputAllE(t, options)
+		},
+		Teardown: func(t testing.TestingT, value interface{}) error {
+			_, err := terraform.DestroyE(t, options)
+			return err
+		},
+	}
+}
+
+// UseTerraformFixture returns the outputs of the module in the given options, which is applied by the first test that
+// requests the fixture with the given name, and destroyed when the last test using it completes. See UseFixture and
+// TerraformFixture for more info.
+//
+// Example:
+//
+//	func TestFoo(t *testing.T) {
+//		t.Parallel()
+//		vpcOutputs := test_structure.UseTerraformFixture(t, "vpc", vpcOptions)
+//		vpcID := vpcOutputs["vpc_id"].(string)
+//	}
+func UseTerraformFixture(t *go_test.T, name string, options *terraform.Options) map[string]interface{} {
+	return UseFixture(t, TerraformFixture(name, options)).(map[string]interface{})
+}
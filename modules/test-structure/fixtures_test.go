@@ -0,0 +1,174 @@
+package test_structure
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFixtureT is a TestingT that records failures and cleanups instead of failing the real test, and that stops with
+// runtime.Goexit on a fatal failure, as *testing.T does.
+type fakeFixtureT struct {
+	failed   bool
+	messages []string
+	cleanups []func()
+}
+
+func (t *fakeFixtureT) Fail()                     { t.failed = true }
+func (t *fakeFixtureT) FailNow()                  { t.failed = true; runtime.Goexit() }
+func (t *fakeFixtureT) Fatal(args ...interface{}) { t.Error(args...); t.FailNow() }
+func (t *fakeFixtureT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	t.FailNow()
+}
+func (t *fakeFixtureT) Error(args ...interface{}) {
+	t.messages = append(t.messages, fmt.Sprint(args...))
+	t.Fail()
+}
+func (t *fakeFixtureT) Errorf(format string, args ...interface{}) {
+	t.messages = append(t.messages, fmt.Sprintf(format, args...))
+	t.Fail()
+}
+func (t *fakeFixtureT) Name() string     { return "fakeFixtureT" }
+func (t *fakeFixtureT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+
+// run runs the given function as if it were the test, including its cleanups, and returns the test.
+func (t *fakeFixtureT) run(fn func(t *fakeFixtureT)) *fakeFixtureT {
+	runStages(func() { fn(t) })
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		runStages(t.cleanups[i])
+	}
+	return t
+}
+
+func TestUseFixtureSharedByConcurrentTests(t *testing.T) {
+	t.Parallel()
+
+	var setups, teardowns, finished int32
+	fixture := Fixture{
+		Name: t.Name() + random.UniqueId(),
+		Setup: func(t terratesting.TestingT) (interface{}, error) {
+			atomic.AddInt32(&setups, 1)
+			time.Sleep(50 * time.Millisecond)
+			return "shared-value", nil
+		},
+		Teardown: func(t terratesting.TestingT, value interface{}) error {
+			assert.Equal(t, "shared-value", value)
+			assert.Equal(t, int32(5), atomic.LoadInt32(&finished), "The fixture was torn down while tests were still using it")
+			atomic.AddInt32(&teardowns, 1)
+			return nil
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user := (&fakeFixtureT{}).run(func(t *fakeFixtureT) {
+				assert.Equal(t, "shared-value", useFixture(t, fixture))
+				time.Sleep(time.Duration(i*10) * time.Millisecond)
+				atomic.AddInt32(&finished, 1)
+			})
+			assert.False(t, user.failed, "%v", user.messages)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), setups)
+	assert.Equal(t, int32(1), teardowns)
+}
+
+func TestUseFixtureSetsUpAgainAfterTeardown(t *testing.T) {
+	t.Parallel()
+
+	var setups, teardowns int32
+	fixture := Fixture{
+		Name: t.Name() + random.UniqueId(),
+		Setup: func(t terratesting.TestingT) (interface{}, error) {
+			return atomic.AddInt32(&setups, 1), nil
+		},
+		Teardown: func(t terratesting.TestingT, value interface{}) error {
+			atomic.AddInt32(&teardowns, 1)
+			return nil
+		},
+	}
+
+	first := (&fakeFixtureT{}).run(func(t *fakeFixtureT) { assert.Equal(t, int32(1), useFixture(t, fixture)) })
+	second := (&fakeFixtureT{}).run(func(t *fakeFixtureT) { assert.Equal(t, int32(2), useFixture(t, fixture)) })
+	assert.False(t, first.failed)
+	assert.False(t, second.failed)
+	assert.Equal(t, int32(2), teardowns)
+}
+
+func TestUseFixtureSetupErrorIsCached(t *testing.T) {
+	t.Parallel()
+
+	var setups int32
+	fixture := Fixture{
+		Name: t.Name() + random.UniqueId(),
+		Setup: func(t terratesting.TestingT) (interface{}, error) {
+			atomic.AddInt32(&setups, 1)
+			return nil, errors.New("quota exceeded")
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		user := (&fakeFixtureT{}).run(func(t *fakeFixtureT) { useFixture(t, fixture) })
+		assert.True(t, user.failed)
+		assert.Equal(t, []string{fmt.Sprintf("Fixture '%s' failed to set up: quota exceeded", fixture.Name)}, user.messages)
+	}
+	assert.Equal(t, int32(1), setups)
+}
+
+func TestUseFixtureSetupFailingTheTest(t *testing.T) {
+	t.Parallel()
+
+	fixture := Fixture{
+		Name: t.Name() + random.UniqueId(),
+		Setup: func(t terratesting.TestingT) (interface{}, error) {
+			require.NoError(t, errors.New("apply failed"))
+			return "unreachable", nil
+		},
+	}
+
+	first := (&fakeFixtureT{}).run(func(t *fakeFixtureT) { useFixture(t, fixture) })
+	require.True(t, first.failed)
+	require.Len(t, first.messages, 1)
+	assert.Contains(t, first.messages[0], "apply failed")
+
+	second := (&fakeFixtureT{}).run(func(t *fakeFixtureT) { useFixture(t, fixture) })
+	require.True(t, second.failed)
+	require.Len(t, second.messages, 1)
+	assert.Contains(t, second.messages[0], "apply failed")
+}
+
+func TestUseTerraformFixture(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-output", t.Name())
+	require.NoError(t, err)
+	options := &terraform.Options{TerraformDir: testFolder}
+
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			t.Run(fmt.Sprintf("user-%d", i), func(t *testing.T) {
+				t.Parallel()
+				outputs := UseTerraformFixture(t, testFolder, options)
+				assert.NotEmpty(t, outputs)
+			})
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package test_structure
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	go_test "testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// MatrixVariant is a named combination of inputs to test a Terraform module with.
+type MatrixVariant struct {
+	// The name of the variant, which is used as the name of its subtest.
+	Name string
+	// Variables to set on top of the Vars of the base options. Variables that are set in both use the value here.
+	Vars map[string]interface{}
+	// Var files to use in addition to the VarFiles of the base options, relative to the copy of the module folder if
+	// they are relative paths.
+	VarFiles []string
+	// The workspace to select (or create) before apply. Optional.
+	Workspace string
+	// The binary to use (e.g., terraform or tofu) instead of the TerraformBinary of the base options. Optional.
+	TerraformBinary string
+}
+
+// Matrix describes a set of variants of a Terraform module to test. See RunMatrix.
+type Matrix struct {
+	// The root folder that is copied for each variant (e.g., ".."), so that relative paths keep working.
+	RootFolder string
+	// The path of the module to test, relative to RootFolder (e.g., "examples/terraform-hello-world-example").
+	TerraformModuleFolder string
+	// The options all variants start from. Their TerraformDir is replaced with the copy of the module folder.
+	BaseOptions *terraform.Options
+	// The variants to test.
+	Variants []MatrixVariant
+	// The maximum number of variants to run at the same time, or 0 for no limit other than the -parallel flag of go test.
+	// This is useful to stay within the quotas of a cloud account.
+	MaxParallel int
+	// The checks to run for each variant after apply (e.g., on its outputs). Optional.
+	Validate func(t *go_test.T, variant MatrixVariant, options *terraform.Options)
+}
+
+// MatrixVariantTiming is how long each phase of testing a variant took.
+type MatrixVariantTiming struct {
+	Name     string
+	Apply    time.Duration // Including init and selecting the workspace
+	Validate time.Duration
+	Destroy  time.Duration
+	Failed   bool
+}
+
+// Total returns how long testing the variant took overall.
+func (timing MatrixVariantTiming) Total() time.Duration {
+	return timing.Apply + timing.Validate + timing.Destroy
+}
+
+// RunMatrix tests every variant of the matrix as a parallel subtest of t. Each variant gets its own copy of the module
+// folder (see CopyTerraformFolderToTemp), which is initialized and applied with the base options plus the variables,
+// var files, workspace, and binary of the variant, and then checked with the Validate function of the matrix. Each
+// variant is destroyed when its subtest completes, even if it fails, using t.Cleanup. Once all the variants are done, a
+// summary of how long each of them took is logged.
+//
+// Example:
+//
+//	test_structure.RunMatrix(t, test_structure.Matrix{
+//		RootFolder:            "..",
+//		TerraformModuleFolder: "examples/terraform-aws-example",
+//		BaseOptions:           &terraform.Options{Vars: map[string]interface{}{"region": "us-east-1"}},
+//		Variants: []test_structure.MatrixVariant{
+//			{Name: "small", Vars: map[string]interface{}{"instance_type": "t3.micro"}},
+//			{Name: "large", Vars: map[string]interface{}{"instance_type": "m5.large"}, Workspace: "large"},
+//		},
+//		MaxParallel: 2,
+//		Validate: func(t *testing.T, variant test_structure.MatrixVariant, options *terraform.Options) {
+//			assert.NotEmpty(t, terraform.Output(t, options, "instance_id"))
+//		},
+//	})
+//
+// Note that if any of the SKIP_<stage> environment variables is set, CopyTerraformFolderToTemp does not copy anything,
+// so the variants would share the same folder. Don't run matrices with these environment variables set.
+func RunMatrix(t *go_test.T, matrix Matrix) {
+	names := map[string]bool{}
+	for _, variant := range matrix.Variants {
+		require.NotEmpty(t, variant.Name, "Every variant of the matrix must have a name")
+		require.Falsef(t, names[variant.Name], "The matrix has more than one variant called %s", variant.Name)
+		names[variant.Name] = true
+	}
+
+	var semaphore chan struct{}
+	if matrix.MaxParallel > 0 {
+		semaphore = make(chan struct{}, matrix.MaxParallel)
+	}
+
+	timings := make([]MatrixVariantTiming, len(matrix.Variants))
+	for i, variant := range matrix.Variants {
+		timings[i].Name = variant.Name
+	}
+	var timingsMutex sync.Mutex
+	t.Cleanup(func() {
+		logger.Logf(t, "Matrix timings:\n%s", formatMatrixTimings(timings))
+	})
+
+	for i, variant := range matrix.Variants {
+		i, variant := i, variant
+		t.Run(variant.Name, func(t *go_test.T) {
+			t.Parallel()
+
+			if semaphore != nil {
+				semaphore <- struct{}{}
+				t.Cleanup(func() { <-semaphore })
+			}
+
+			timing := MatrixVariantTiming{Name: variant.Name}
+			// The cleanups run in the reverse order they were added, so this one runs after the destroy
+			t.Cleanup(func() {
+				timing.Failed = t.Failed()
+				timingsMutex.Lock()
+				timings[i] = timing
+				timingsMutex.Unlock()
+			})
+
+			testFolder := CopyTerraformFolderToTemp(t, matrix.RootFolder, matrix.TerraformModuleFolder)
+			options, err := matrixVariantOptions(matrix.BaseOptions, variant, testFolder)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				timeMatrixPhase(&timing.Destroy, func() { terraform.Destroy(t, options) })
+			})
+
+			timeMatrixPhase(&timing.Apply, func() {
+				terraform.Init(t, options)
+				if variant.Workspace != "" {
+					terraform.WorkspaceSelectOrNew(t, options, variant.Workspace)
+				}
+				terraform.Apply(t, options)
+			})
+
+			if matrix.Validate != nil {
+				timeMatrixPhase(&timing.Validate, func() { matrix.Validate(t, variant, options) })
+			}
+		})
+	}
+}
+
+// timeMatrixPhase runs the given phase of testing a variant and stores how long it took in duration, even if the phase
+// fails the test.
+func timeMatrixPhase(duration *time.Duration, phase func()) {
+	start := time.Now()
+	defer func() { *duration = time.Since(start) }()
+	phase()
+}
+
+// matrixVariantOptions returns the options to test the given variant with in the given folder.
+func matrixVariantOptions(baseOptions *terraform.Options, variant MatrixVariant, testFolder string) (*terraform.Options, error) {
+	if baseOptions == nil {
+		baseOptions = &terraform.Options{}
+	}
+	options, err := baseOptions.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	options.TerraformDir = testFolder
+	if options.Vars == nil {
+		options.Vars = map[string]interface{}{}
+	}
+	for key, val := range variant.Vars {
+		options.Vars[key] = val
+	}
+	options.VarFiles = append(append([]string{}, options.VarFiles...), variant.VarFiles...)
+	if variant.TerraformBinary != "" {
+		options.TerraformBinary = variant.TerraformBinary
+	}
+	return options, nil
+}
+
+// formatMatrixTimings formats the given timings as a table, with one row per variant.
+func formatMatrixTimings(timings []MatrixVariantTiming) string {
+	nameWidth := len("VARIANT")
+	for _, timing := range timings {
+		if len(timing.Name) > nameWidth {
+			nameWidth = len(timing.Name)
+		}
+	}
+
+	var sb strings.Builder
+	row := fmt.Sprintf("%%-%ds  %%-6s  %%10s  %%10s  %%10s  %%10s\n", nameWidth)
+	fmt.Fprintf(&sb, row, "VARIANT", "RESULT", "APPLY", "VALIDATE", "DESTROY", "TOTAL")
+	for _, timing := range timings {
+		result := "PASS"
+		if timing.Failed {
+			result = "FAIL"
+		}
+		fmt.Fprintf(&sb, row, timing.Name, result, formatMatrixDuration(timing.Apply), formatMatrixDuration(timing.Validate), formatMatrixDuration(timing.Destroy), formatMatrixDuration(timing.Total()))
+	}
+	return sb.String()
+}
+
+func formatMatrixDuration(duration time.Duration) string {
+	return duration.Round(time.Millisecond).String()
+}
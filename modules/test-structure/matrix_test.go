@@ -0,0 +1,79 @@
+package test_structure
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatrixVariantOptions(t *testing.T) {
+	t.Parallel()
+
+	baseOptions := &terraform.Options{
+		Vars:     map[string]interface{}{"region": "us-east-1", "size": "small"},
+		VarFiles: []string{"common.tfvars"},
+	}
+	variant := MatrixVariant{
+		Name:            "large",
+		Vars:            map[string]interface{}{"size": "large"},
+		VarFiles:        []string{"large.tfvars"},
+		TerraformBinary: "tofu",
+	}
+
+	options, err := matrixVariantOptions(baseOptions, variant, "/tmp/large")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/tmp/large", options.TerraformDir)
+	assert.Equal(t, map[string]interface{}{"region": "us-east-1", "size": "large"}, options.Vars)
+	assert.Equal(t, []string{"common.tfvars", "large.tfvars"}, options.VarFiles)
+	assert.Equal(t, "tofu", options.TerraformBinary)
+
+	// The base options must not be modified
+	assert.Equal(t, "small", baseOptions.Vars["size"])
+	assert.Equal(t, []string{"common.tfvars"}, baseOptions.VarFiles)
+	assert.Empty(t, baseOptions.TerraformBinary)
+}
+
+func TestFormatMatrixTimings(t *testing.T) {
+	t.Parallel()
+
+	out := formatMatrixTimings([]MatrixVariantTiming{
+		{Name: "small", Apply: 1500 * time.Millisecond, Validate: 20 * time.Millisecond, Destroy: time.Second},
+		{Name: "very-large", Apply: 3 * time.Second, Failed: true},
+	})
+
+	assert.Equal(t, ""+
+		"VARIANT     RESULT       APPLY    VALIDATE     DESTROY       TOTAL\n"+
+		"small       PASS          1.5s        20ms          1s       2.52s\n"+
+		"very-large  FAIL            3s          0s          0s          3s\n", out)
+}
+
+func TestRunMatrix(t *testing.T) {
+	t.Parallel()
+
+	var validated int32
+	RunMatrix(t, Matrix{
+		RootFolder:            "../../test/fixtures",
+		TerraformModuleFolder: "terraform-basic-configuration",
+		BaseOptions:           &terraform.Options{Vars: map[string]interface{}{"cnt": 1}},
+		Variants: []MatrixVariant{
+			{Name: "one"},
+			{Name: "two", Vars: map[string]interface{}{"cnt": 2}},
+			{Name: "workspace", Workspace: "matrix"},
+		},
+		MaxParallel: 2,
+		Validate: func(t *testing.T, variant MatrixVariant, options *terraform.Options) {
+			atomic.AddInt32(&validated, 1)
+			state := terraform.Show(t, options)
+			assert.NotEmpty(t, state)
+		},
+	})
+
+	t.Cleanup(func() {
+		assert.Equal(t, int32(3), atomic.LoadInt32(&validated))
+	})
+}
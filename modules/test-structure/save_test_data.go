@@ -3,11 +3,9 @@ package test_structure
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"github.com/gruntwork-io/terratest/modules/aws"
-	"github.com/gruntwork-io/terratest/modules/files"
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/packer"
@@ -183,6 +181,16 @@ func formatNamedTestDataPath(testFolder string, name string) string {
 	return FormatTestDataPath(testFolder, filename)
 }
 
+// getTestDataStore returns the test data store selected with the TERRATEST_TEST_DATA_STORE environment variable, failing
+// the test if it is invalid.
+func getTestDataStore(t testing.TestingT) TestDataStore {
+	store, err := GetTestDataStoreE()
+	if err != nil {
+		t.Fatalf("Failed to get the test data store: %v", err)
+	}
+	return store
+}
+
 // FormatTestDataPath formats a path to save test data.
 func FormatTestDataPath(testFolder string, filename string) string {
 	return filepath.Join(testFolder, ".test-data", filename)
@@ -211,12 +219,7 @@ func SaveTestData(t testing.TestingT, path string, overwrite bool, value interfa
 
 	logger.Logf(t, "Marshalled JSON: %s", string(bytes))
 
-	parentDir := filepath.Dir(path)
-	if err := os.MkdirAll(parentDir, 0777); err != nil {
-		t.Fatalf("Failed to create folder %s: %v", parentDir, err)
-	}
-
-	if err := os.WriteFile(path, bytes, 0644); err != nil {
+	if err := getTestDataStore(t).Save(path, bytes); err != nil {
 		t.Fatalf("Failed to save value %s: %v", path, err)
 	}
 }
@@ -227,7 +230,7 @@ func SaveTestData(t testing.TestingT, path string, overwrite bool, value interfa
 func LoadTestData(t testing.TestingT, path string, value interface{}) {
 	logger.Logf(t, "Loading test data from %s", path)
 
-	bytes, err := os.ReadFile(path)
+	bytes, err := getTestDataStore(t).Load(path)
 	if err != nil {
 		t.Fatalf("Failed to load value from %s: %v", path, err)
 	}
@@ -239,7 +242,8 @@ func LoadTestData(t testing.TestingT, path string, value interface{}) {
 
 // IsTestDataPresent returns true if a file exists at $path and the test data there is non-empty.
 func IsTestDataPresent(t testing.TestingT, path string) bool {
-	exists, err := files.FileExistsE(path)
+	store := getTestDataStore(t)
+	exists, err := store.Exists(path)
 	if err != nil {
 		t.Fatalf("Failed to load test data from %s due to unexpected error: %v", path, err)
 	}
@@ -247,7 +251,7 @@ func IsTestDataPresent(t testing.TestingT, path string) bool {
 		return false
 	}
 
-	bytes, err := os.ReadFile(path)
+	bytes, err := store.Load(path)
 
 	if err != nil {
 		t.Fatalf("Failed to load test data from %s due to unexpected error: %v", path, err)
@@ -307,9 +311,14 @@ func isEmptyJSON(t testing.TestingT, bytes []byte) bool {
 
 // CleanupTestData cleans up the test data at the given path.
 func CleanupTestData(t testing.TestingT, path string) {
-	if files.FileExists(path) {
+	store := getTestDataStore(t)
+	exists, err := store.Exists(path)
+	if err != nil {
+		t.Fatalf("Failed to clean up file at %s: %v", path, err)
+	}
+	if exists {
 		logger.Logf(t, "Cleaning up test data from %s", path)
-		if err := os.Remove(path); err != nil {
+		if err := store.Delete(path); err != nil {
 			t.Fatalf("Failed to clean up file at %s: %v", path, err)
 		}
 	} else {
@@ -327,17 +336,14 @@ func CleanupTestDataFolder(t testing.TestingT, path string) {
 // CleanupTestDataFolderE cleans up the .test-data folder inside the given folder.
 func CleanupTestDataFolderE(t testing.TestingT, path string) error {
 	path = filepath.Join(path, ".test-data")
-	exists, err := files.FileExistsE(path)
+	store, err := GetTestDataStoreE()
 	if err != nil {
 		logger.Logf(t, "Failed to clean up test data folder at %s: %v", path, err)
 		return err
 	}
 
-	if !exists {
-		logger.Logf(t, "%s does not exist. Nothing to cleanup.", path)
-		return nil
-	}
-	if err := os.RemoveAll(path); err != nil {
+	logger.Logf(t, "Cleaning up test data folder %s", path)
+	if err := store.DeleteAll(path); err != nil {
 		logger.Logf(t, "Failed to clean up test data folder at %s: %v", path, err)
 		return err
 	}
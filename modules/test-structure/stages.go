@@ -0,0 +1,244 @@
+package test_structure
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// RUN_ALL_STAGES_ENV_VAR is the environment variable that makes a StageRunner ignore the outcome of previous runs and run
+// all stages.
+const RUN_ALL_STAGES_ENV_VAR = "TERRATEST_RUN_ALL_STAGES"
+
+// StageStatus is the outcome of a test stage.
+type StageStatus string
+
+const (
+	StageSucceeded StageStatus = "succeeded"
+	StageFailed    StageStatus = "failed"
+	// StageSkipped is only used in the summary of a run, for stages that succeeded in a previous run or whose
+	// SKIP_<stage> environment variable is set.
+	StageSkipped StageStatus = "skipped"
+)
+
+// StageResult is the outcome of a single run of a test stage.
+type StageResult struct {
+	Name      string
+	Status    StageStatus
+	Duration  time.Duration
+	Timestamp time.Time // When the stage started
+}
+
+// StageRunner runs the stages of a test (e.g., setup, validate, teardown) like RunTestStage, but also records the
+// outcome of each stage in the .test-data folder of the given test folder, next to the data saved with
+// SaveTerraformOptions. When the test is run again after a failure, the stages that succeeded before the first failure
+// are skipped, and the test resumes at the stage that failed, with all the stages after it running as usual. Set the
+// TERRATEST_RUN_ALL_STAGES environment variable to run all stages anyway. The SKIP_<stage> environment variables keep
+// working as with RunTestStage.
+//
+// Once a run completes without failures, or a teardown stage (see RunTeardownStage) succeeds, the recorded outcomes are
+// cleared, as there is nothing left to resume, so the next run starts from scratch. Note that this means that to resume
+// a test, its teardown must not have succeeded, e.g., because the SKIP_teardown environment variable was set.
+//
+// Whether a stage failed is told from whether it failed the test. Once the test has failed (e.g., because of an assert
+// outside of any stage), that can't be told anymore, so all the stages after that are recorded as failed, and a
+// teardown stage clears the recorded outcomes as if it had succeeded.
+//
+// The test folder must be the same across runs, so use the original module folder rather than a copy in a temp folder
+// (CopyTerraformFolderToTemp returns the original folder when a SKIP_<stage> environment variable is set).
+//
+// Example:
+//
+//	stages := test_structure.NewStageRunner(t, workingDir)
+//	defer stages.Done()
+//
+//	defer stages.RunTeardownStage("teardown", func() {
+//		terraform.Destroy(t, test_structure.LoadTerraformOptions(t, workingDir))
+//	})
+//	stages.RunStage("setup", func() {
+//		terraformOptions := &terraform.Options{TerraformDir: workingDir}
+//		test_structure.SaveTerraformOptions(t, workingDir, terraformOptions)
+//		terraform.InitAndApply(t, terraformOptions)
+//	})
+//	stages.RunStage("validate", func() {
+//		validate(t, test_structure.LoadTerraformOptions(t, workingDir))
+//	})
+type StageRunner struct {
+	t          testing.TestingT
+	testFolder string
+
+	// The outcome of the stages in previous runs, which are only used until the first stage that has to run
+	previous map[string]StageResult
+	// The outcome of the stages that ran in this and previous runs, as recorded in the .test-data folder
+	recorded map[string]StageResult
+	resumed  bool
+	failed   bool
+	summary  []StageResult
+	done     bool
+}
+
+// failureReporter is implemented by *testing.T.
+type failureReporter interface {
+	Failed() bool
+}
+
+// cleanupRegistrar is implemented by *testing.T.
+type cleanupRegistrar interface {
+	Cleanup(f func())
+}
+
+// NewStageRunner returns a stage runner that records the outcome of the stages in the .test-data folder of the given
+// test folder. If t is a *testing.T, Done is called automatically when the test completes, otherwise make sure to call
+// it yourself.
+func NewStageRunner(t testing.TestingT, testFolder string) *StageRunner {
+	runner := &StageRunner{
+		t:          t,
+		testFolder: testFolder,
+		previous:   map[string]StageResult{},
+		recorded:   map[string]StageResult{},
+	}
+
+	path := formatStageResultsPath(testFolder)
+	if os.Getenv(RUN_ALL_STAGES_ENV_VAR) != "" {
+		logger.Logf(t, "The '%s' environment variable is set, so running all stages.", RUN_ALL_STAGES_ENV_VAR)
+	} else if IsTestDataPresent(t, path) {
+		LoadTestData(t, path, &runner.previous)
+		for name, result := range runner.previous {
+			runner.recorded[name] = result
+		}
+	}
+
+	if registrar, canRegister := t.(cleanupRegistrar); canRegister {
+		registrar.Cleanup(runner.Done)
+	}
+	return runner
+}
+
+// formatStageResultsPath formats a path to save the outcome of the stages in the given folder.
+func formatStageResultsPath(testFolder string) string {
+	return FormatTestDataPath(testFolder, "TestStages.json")
+}
+
+// RunStage runs the given test stage, unless it succeeded in a previous run and no stage before it had to run again in
+// this run, or the SKIP_<stageName> environment variable is set.
+func (runner *StageRunner) RunStage(stageName string, stage func()) {
+	runner.runStage(stageName, stage, false)
+}
+
+// RunTeardownStage runs the given test stage like RunStage. If it succeeds, the recorded outcomes of all the stages are
+// cleared, as the resources they created are gone, so the next run starts from scratch.
+func (runner *StageRunner) RunTeardownStage(stageName string, stage func()) {
+	runner.runStage(stageName, stage, true)
+}
+
+func (runner *StageRunner) runStage(stageName string, stage func(), isTeardown bool) {
+	envVarName := fmt.Sprintf("%s%s", SKIP_STAGE_ENV_VAR_PREFIX, stageName)
+	if os.Getenv(envVarName) != "" {
+		logger.Logf(runner.t, "The '%s' environment variable is set, so skipping stage '%s'.", envVarName, stageName)
+		runner.summary = append(runner.summary, StageResult{Name: stageName, Status: StageSkipped, Timestamp: time.Now()})
+		return
+	}
+
+	if previous, hasPrevious := runner.previous[stageName]; !runner.resumed && hasPrevious && previous.Status == StageSucceeded {
+		logger.Logf(runner.t, "Stage '%s' already succeeded at %s, so skipping it.", stageName, previous.Timestamp.Format(time.RFC3339))
+		runner.summary = append(runner.summary, StageResult{Name: stageName, Status: StageSkipped, Timestamp: previous.Timestamp})
+		return
+	}
+	if !runner.resumed && len(runner.previous) > 0 {
+		logger.Logf(runner.t, "Resuming at stage '%s'.", stageName)
+	}
+	runner.resumed = true
+
+	logger.Logf(runner.t, "Executing stage '%s'.", stageName)
+	result := StageResult{Name: stageName, Status: StageFailed, Timestamp: time.Now()}
+	failedBefore := runner.testFailed()
+
+	// The stage fails the test with runtime.Goexit, so the outcome is recorded in a deferred function
+	defer func() {
+		result.Duration = time.Since(result.Timestamp)
+		if result.Status == StageFailed {
+			runner.failed = true
+		}
+		runner.summary = append(runner.summary, result)
+
+		// A teardown that might have succeeded might have destroyed the resources of the earlier stages, so the next run
+		// must not skip them
+		if isTeardown && (result.Status == StageSucceeded || failedBefore) {
+			runner.recorded = map[string]StageResult{}
+		} else {
+			runner.recorded[stageName] = result
+		}
+		runner.saveResults()
+	}()
+
+	stage()
+	if failedBefore {
+		logger.Logf(runner.t, "The test had already failed before stage '%s', so it can't be told whether the stage succeeded. Recording it as failed.", stageName)
+	} else if !runner.testFailed() {
+		result.Status = StageSucceeded
+	}
+}
+
+// testFailed returns true if the test has failed so far, if that can be told from the TestingT.
+func (runner *StageRunner) testFailed() bool {
+	if reporter, canReport := runner.t.(failureReporter); canReport {
+		return reporter.Failed()
+	}
+	return false
+}
+
+func (runner *StageRunner) saveResults() {
+	path := formatStageResultsPath(runner.testFolder)
+	if len(runner.recorded) == 0 {
+		CleanupTestData(runner.t, path)
+		return
+	}
+	SaveTestData(runner.t, path, true, runner.recorded)
+}
+
+// Done logs a summary of the stages of this run and, if none of them failed, clears the recorded outcomes so that the
+// next run starts from scratch. This is called automatically when the test completes if the runner was created with a
+// *testing.T. Calling it more than once has no effect.
+func (runner *StageRunner) Done() {
+	if runner.done {
+		return
+	}
+	runner.done = true
+
+	if !runner.failed && !runner.testFailed() && len(runner.recorded) > 0 {
+		runner.recorded = map[string]StageResult{}
+		runner.saveResults()
+	}
+	logger.Logf(runner.t, "Test stages:\n%s", formatStageSummary(runner.summary))
+}
+
+// Summary returns the outcome of the stages of this run so far, in the order they ran (or were skipped).
+func (runner *StageRunner) Summary() []StageResult {
+	return append([]StageResult{}, runner.summary...)
+}
+
+// formatStageSummary formats the given stage results as a table, with one row per stage.
+func formatStageSummary(results []StageResult) string {
+	nameWidth := len("STAGE")
+	for _, result := range results {
+		if len(result.Name) > nameWidth {
+			nameWidth = len(result.Name)
+		}
+	}
+
+	var sb strings.Builder
+	row := fmt.Sprintf("%%-%ds  %%-9s  %%10s  %%s\n", nameWidth)
+	fmt.Fprintf(&sb, row, "STAGE", "STATUS", "DURATION", "STARTED")
+	for _, result := range results {
+		duration := "-"
+		if result.Status != StageSkipped {
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(&sb, row, result.Name, result.Status, duration, result.Timestamp.Format(time.RFC3339))
+	}
+	return sb.String()
+}
@@ -0,0 +1,186 @@
+package test_structure
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStageT is a TestingT that records failures instead of failing the real test, and that stops the stage with
+// runtime.Goexit on a fatal failure, as *testing.T does.
+type fakeStageT struct {
+	failed bool
+}
+
+func (t *fakeStageT) Fail()                                     { t.failed = true }
+func (t *fakeStageT) FailNow()                                  { t.failed = true; runtime.Goexit() }
+func (t *fakeStageT) Fatal(args ...interface{})                 { t.FailNow() }
+func (t *fakeStageT) Fatalf(format string, args ...interface{}) { t.FailNow() }
+func (t *fakeStageT) Error(args ...interface{})                 { t.Fail() }
+func (t *fakeStageT) Errorf(format string, args ...interface{}) { t.Fail() }
+func (t *fakeStageT) Name() string                              { return "fakeStageT" }
+func (t *fakeStageT) Failed() bool                              { return t.failed }
+
+// runStages runs the given function in a new goroutine, so that a fatal failure only stops that goroutine.
+func runStages(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+}
+
+func stageNamesAndStatuses(results []StageResult) []string {
+	out := []string{}
+	for _, result := range results {
+		out = append(out, fmt.Sprintf("%s:%s", result.Name, result.Status))
+	}
+	return out
+}
+
+func TestStageRunnerResumesAtFailedStage(t *testing.T) {
+	t.Parallel()
+
+	testFolder := t.TempDir()
+	ran := []string{}
+
+	// The first run fails at the validate stage
+	fakeT := &fakeStageT{}
+	runner := NewStageRunner(fakeT, testFolder)
+	runStages(func() {
+		runner.RunStage("setup", func() { ran = append(ran, "setup") })
+		runner.RunStage("validate", func() { ran = append(ran, "validate"); fakeT.FailNow() })
+		runner.RunStage("never", func() { ran = append(ran, "never") })
+	})
+	runner.Done()
+	assert.Equal(t, []string{"setup", "validate"}, ran)
+	assert.Equal(t, []string{"setup:succeeded", "validate:failed"}, stageNamesAndStatuses(runner.Summary()))
+	require.True(t, files.FileExists(formatStageResultsPath(testFolder)))
+
+	// The second run skips setup and resumes at validate, after which all stages run
+	ran = []string{}
+	runner = NewStageRunner(&fakeStageT{}, testFolder)
+	runStages(func() {
+		runner.RunStage("setup", func() { ran = append(ran, "setup") })
+		runner.RunStage("validate", func() { ran = append(ran, "validate") })
+		runner.RunStage("more", func() { ran = append(ran, "more") })
+	})
+	runner.Done()
+	assert.Equal(t, []string{"validate", "more"}, ran)
+	assert.Equal(t, []string{"setup:skipped", "validate:succeeded", "more:succeeded"}, stageNamesAndStatuses(runner.Summary()))
+
+	// The second run succeeded, so the third run starts from scratch
+	assert.False(t, files.FileExists(formatStageResultsPath(testFolder)))
+	ran = []string{}
+	runner = NewStageRunner(&fakeStageT{}, testFolder)
+	runner.RunStage("setup", func() { ran = append(ran, "setup") })
+	runner.Done()
+	assert.Equal(t, []string{"setup"}, ran)
+}
+
+func TestStageRunnerTeardownClearsResults(t *testing.T) {
+	t.Parallel()
+
+	testFolder := t.TempDir()
+	ran := []string{}
+
+	fakeT := &fakeStageT{}
+	runner := NewStageRunner(fakeT, testFolder)
+	runStages(func() {
+		defer runner.RunTeardownStage("teardown", func() { ran = append(ran, "teardown") })
+		runner.RunStage("setup", func() { ran = append(ran, "setup") })
+		runner.RunStage("validate", func() { ran = append(ran, "validate"); fakeT.FailNow() })
+	})
+	runner.Done()
+	assert.Equal(t, []string{"setup", "validate", "teardown"}, ran)
+	assert.False(t, files.FileExists(formatStageResultsPath(testFolder)))
+
+	// The teardown destroyed everything, so there is nothing to resume
+	ran = []string{}
+	runner = NewStageRunner(&fakeStageT{}, testFolder)
+	runner.RunStage("setup", func() { ran = append(ran, "setup") })
+	runner.Done()
+	assert.Equal(t, []string{"setup"}, ran)
+}
+
+func TestStageRunnerRunAllStages(t *testing.T) {
+	testFolder := t.TempDir()
+
+	fakeT := &fakeStageT{}
+	runner := NewStageRunner(fakeT, testFolder)
+	runStages(func() {
+		runner.RunStage("setup", func() {})
+		runner.RunStage("validate", func() { fakeT.FailNow() })
+	})
+	runner.Done()
+
+	t.Setenv(RUN_ALL_STAGES_ENV_VAR, "true")
+	ran := []string{}
+	runner = NewStageRunner(&fakeStageT{}, testFolder)
+	runner.RunStage("setup", func() { ran = append(ran, "setup") })
+	runner.RunStage("validate", func() { ran = append(ran, "validate") })
+	runner.Done()
+	assert.Equal(t, []string{"setup", "validate"}, ran)
+}
+
+func TestStageRunnerNonFatalFailure(t *testing.T) {
+	t.Parallel()
+
+	fakeT := &fakeStageT{}
+	runner := NewStageRunner(fakeT, t.TempDir())
+	runner.RunStage("validate", func() { fakeT.Errorf("oops") })
+	runner.RunStage("more", func() {})
+	// The test has already failed, so the outcome of the later stage can't be told
+	assert.Equal(t, []string{"validate:failed", "more:failed"}, stageNamesAndStatuses(runner.Summary()))
+}
+
+func TestStageRunnerFailureBeforeStage(t *testing.T) {
+	t.Parallel()
+
+	testFolder := t.TempDir()
+	ran := []string{}
+
+	// The first run fails an assert between the stages, and then the validate stage fails an assert as well
+	fakeT := &fakeStageT{}
+	runner := NewStageRunner(fakeT, testFolder)
+	runner.RunStage("setup", func() { ran = append(ran, "setup") })
+	assert.Fail(fakeT, "outside of any stage")
+	runner.RunStage("validate", func() { ran = append(ran, "validate"); assert.Fail(fakeT, "in the stage") })
+	runner.Done()
+	assert.Equal(t, []string{"setup:succeeded", "validate:failed"}, stageNamesAndStatuses(runner.Summary()))
+
+	// The second run resumes at validate
+	ran = []string{}
+	runner = NewStageRunner(&fakeStageT{}, testFolder)
+	runner.RunStage("setup", func() { ran = append(ran, "setup") })
+	runner.RunStage("validate", func() { ran = append(ran, "validate") })
+	runner.Done()
+	assert.Equal(t, []string{"validate"}, ran)
+
+	// A teardown after a failure might have succeeded, so the next run starts from scratch
+	fakeT = &fakeStageT{}
+	runner = NewStageRunner(fakeT, testFolder)
+	runner.RunStage("setup", func() {})
+	assert.Fail(fakeT, "outside of any stage")
+	runner.RunTeardownStage("teardown", func() {})
+	runner.Done()
+	assert.Equal(t, []string{"setup:succeeded", "teardown:failed"}, stageNamesAndStatuses(runner.Summary()))
+	assert.False(t, files.FileExists(formatStageResultsPath(testFolder)))
+}
+
+func TestStageRunnerWithTestingT(t *testing.T) {
+	t.Parallel()
+
+	testFolder := t.TempDir()
+	t.Run("run", func(t *testing.T) {
+		runner := NewStageRunner(t, testFolder)
+		runner.RunStage("setup", func() {})
+	})
+	// Done is called automatically when the subtest completes, and the run succeeded
+	assert.False(t, files.FileExists(formatStageResultsPath(testFolder)))
+}
@@ -0,0 +1,373 @@
+package test_structure
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// TEST_DATA_STORE_ENV_VAR is the environment variable that selects where SaveTestData and the other test data helpers
+	// store their data. See GetTestDataStoreE for the supported values.
+	TEST_DATA_STORE_ENV_VAR = "TERRATEST_TEST_DATA_STORE"
+	// TEST_DATA_STORE_S3_ENDPOINT_ENV_VAR is the environment variable with the endpoint of an S3-compatible service
+	// (e.g., http://localhost:9000 for MinIO) to use instead of AWS S3.
+	TEST_DATA_STORE_S3_ENDPOINT_ENV_VAR = "TERRATEST_TEST_DATA_STORE_S3_ENDPOINT"
+	// TEST_DATA_STORE_HTTP_AUTH_ENV_VAR is the environment variable with the value of the Authorization header to send
+	// to an HTTP test data store (e.g., "Bearer TOKEN").
+	TEST_DATA_STORE_HTTP_AUTH_ENV_VAR = "TERRATEST_TEST_DATA_STORE_HTTP_AUTH"
+)
+
+// TestDataStore is where the test data helpers (e.g., SaveTerraformOptions and LoadTerraformOptions) store their data.
+// The paths are the ones the helpers use for local files (e.g., examples/foo/.test-data/TerraformOptions.json), which
+// stores that are not local turn into keys with TestDataKey.
+type TestDataStore interface {
+	// Save stores the data at the given path, overwriting any data that is already there.
+	Save(path string, data []byte) error
+	// Load returns the data stored at the given path, or a TestDataNotFound error if there is none.
+	Load(path string) ([]byte, error)
+	// Exists returns true if there is data stored at the given path.
+	Exists(path string) (bool, error)
+	// Delete deletes the data stored at the given path, if any.
+	Delete(path string) error
+	// DeleteAll deletes all the data stored under the given folder path, if any.
+	DeleteAll(path string) error
+}
+
+// TestDataNotFound is returned when there is no test data at a path.
+type TestDataNotFound struct {
+	Path string
+}
+
+func (err TestDataNotFound) Error() string {
+	return fmt.Sprintf("no test data found at %s", err.Path)
+}
+
+// GetTestDataStoreE returns the test data store selected with the TERRATEST_TEST_DATA_STORE environment variable, which
+// can be set to:
+//
+//   - Nothing, to store the data in local files at the paths the helpers use (i.e., in the .test-data folder of the
+//     test folder). This is the default.
+//   - A local directory path, or a file:// URL, to store the data in that directory (e.g., a volume shared by CI
+//     workers), using the keys returned by TestDataKey.
+//   - An s3://BUCKET/PREFIX URL, to store the data in an S3 bucket. The credentials and region are read from the usual
+//     AWS environment variables and config files. Set TERRATEST_TEST_DATA_STORE_S3_ENDPOINT to use an S3-compatible
+//     service instead (e.g., MinIO).
+//   - An http:// or https:// URL, to store the data in an HTTP server (e.g., an artifact store) with PUT, GET, HEAD, and
+//     DELETE requests. Set TERRATEST_TEST_DATA_STORE_HTTP_AUTH to send an Authorization header.
+func GetTestDataStoreE() (TestDataStore, error) {
+	location := os.Getenv(TEST_DATA_STORE_ENV_VAR)
+	if location == "" {
+		return &LocalTestDataStore{}, nil
+	}
+
+	parsed, err := url.Parse(location)
+	if err != nil || parsed.Scheme == "" || len(parsed.Scheme) == 1 {
+		// Not a URL (a scheme of a single letter is a Windows drive letter), so it is a local directory
+		return &LocalTestDataStore{Dir: location}, nil
+	}
+
+	switch parsed.Scheme {
+	case "file":
+		return &LocalTestDataStore{Dir: filepath.FromSlash(parsed.Path)}, nil
+	case "s3":
+		return NewS3TestDataStoreE(parsed.Host, strings.TrimPrefix(parsed.Path, "/"), os.Getenv(TEST_DATA_STORE_S3_ENDPOINT_ENV_VAR))
+	case "http", "https":
+		store := &HTTPTestDataStore{URL: location, Headers: map[string]string{}}
+		if auth := os.Getenv(TEST_DATA_STORE_HTTP_AUTH_ENV_VAR); auth != "" {
+			store.Headers["Authorization"] = auth
+		}
+		return store, nil
+	}
+	return nil, fmt.Errorf("unsupported value for %s: %s", TEST_DATA_STORE_ENV_VAR, location)
+}
+
+// TestDataKey returns the key under which the stores that are not local files store the test data at the given path:
+// the path relative to the working directory if it is within it, or the absolute path otherwise, with forward slashes
+// and without a leading slash. As go test runs in the folder of the package being tested, the keys are the same on
+// every machine that runs the same test, as long as the test folder is within the repo (e.g., the original module
+// folder rather than a copy in a temp folder).
+func TestDataKey(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	if workingDir, err := os.Getwd(); err == nil {
+		if relPath, err := filepath.Rel(workingDir, absPath); err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(relPath)
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(strings.TrimPrefix(absPath, filepath.VolumeName(absPath)))), "/")
+}
+
+// LocalTestDataStore stores test data in local files.
+type LocalTestDataStore struct {
+	// The directory to store the data in, using the keys returned by TestDataKey, or empty to store the data at the
+	// paths the helpers use.
+	Dir string
+}
+
+func (store *LocalTestDataStore) filePath(path string) string {
+	if store.Dir == "" {
+		return path
+	}
+	return filepath.Join(store.Dir, filepath.FromSlash(TestDataKey(path)))
+}
+
+// Save stores the data at the given path, creating its parent folders as needed.
+func (store *LocalTestDataStore) Save(path string, data []byte) error {
+	filePath := store.filePath(path)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// Load returns the data stored at the given path.
+func (store *LocalTestDataStore) Load(path string) ([]byte, error) {
+	data, err := os.ReadFile(store.filePath(path))
+	if os.IsNotExist(err) {
+		return nil, TestDataNotFound{Path: path}
+	}
+	return data, err
+}
+
+// Exists returns true if there is a file at the given path.
+func (store *LocalTestDataStore) Exists(path string) (bool, error) {
+	_, err := os.Stat(store.filePath(path))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete deletes the file at the given path, if any.
+func (store *LocalTestDataStore) Delete(path string) error {
+	if err := os.Remove(store.filePath(path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteAll deletes the folder at the given path, if any.
+func (store *LocalTestDataStore) DeleteAll(path string) error {
+	return os.RemoveAll(store.filePath(path))
+}
+
+// S3TestDataStore stores test data as objects in an S3 bucket, or in a bucket of an S3-compatible service.
+type S3TestDataStore struct {
+	Client *s3.S3
+	Bucket string
+	// The prefix of the keys of the objects, if any (e.g., the ID of the CI pipeline).
+	Prefix string
+}
+
+// NewS3TestDataStoreE returns a store for the given S3 bucket and key prefix. The credentials and region are read from
+// the usual AWS environment variables and config files. If endpoint is not empty, the store uses the S3-compatible
+// service at that endpoint (e.g., http://localhost:9000) instead of AWS S3.
+func NewS3TestDataStoreE(bucket string, prefix string, endpoint string) (*S3TestDataStore, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("the S3 test data store needs a bucket")
+	}
+	config := aws.NewConfig()
+	if endpoint != "" {
+		config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{Config: *config, SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(sess.Config.Region) == "" {
+		// Any region works with S3-compatible services, and the AWS SDK needs one to sign requests
+		sess.Config.Region = aws.String("us-east-1")
+	}
+	return &S3TestDataStore{Client: s3.New(sess), Bucket: bucket, Prefix: prefix}, nil
+}
+
+func (store *S3TestDataStore) key(path string) string {
+	key := TestDataKey(path)
+	if store.Prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(store.Prefix, "/") + "/" + key
+}
+
+// Save stores the data as the object for the given path.
+func (store *S3TestDataStore) Save(path string, data []byte) error {
+	_, err := store.Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(store.Bucket),
+		Key:    aws.String(store.key(path)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// Load returns the contents of the object for the given path.
+func (store *S3TestDataStore) Load(path string) ([]byte, error) {
+	out, err := store.Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(store.Bucket),
+		Key:    aws.String(store.key(path)),
+	})
+	if isS3NotFound(err) {
+		return nil, TestDataNotFound{Path: path}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+// Exists returns true if there is an object for the given path.
+func (store *S3TestDataStore) Exists(path string) (bool, error) {
+	_, err := store.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(store.Bucket),
+		Key:    aws.String(store.key(path)),
+	})
+	if isS3NotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete deletes the object for the given path, if any.
+func (store *S3TestDataStore) Delete(path string) error {
+	_, err := store.Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(store.Bucket),
+		Key:    aws.String(store.key(path)),
+	})
+	return err
+}
+
+// DeleteAll deletes all the objects under the given folder path.
+func (store *S3TestDataStore) DeleteAll(path string) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(store.Bucket),
+		Prefix: aws.String(store.key(path) + "/"),
+	}
+	var deleteErr error
+	err := store.Client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if _, deleteErr = store.Client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(store.Bucket), Key: object.Key}); deleteErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return deleteErr
+}
+
+func isS3NotFound(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	switch awsErr.Code() {
+	case s3.ErrCodeNoSuchKey, "NotFound":
+		return true
+	}
+	return false
+}
+
+// HTTPTestDataStore stores test data in an HTTP server: the data for a path is stored with a PUT request to URL/KEY,
+// where KEY is returned by TestDataKey, loaded with a GET request, checked with a HEAD request, and deleted with a
+// DELETE request. DeleteAll sends a DELETE request to URL/KEY/, which the server is expected to handle by deleting
+// everything under that key.
+type HTTPTestDataStore struct {
+	// The base URL of the store.
+	URL string
+	// Headers to send with every request (e.g., Authorization).
+	Headers map[string]string
+	// The client to send the requests with. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+func (store *HTTPTestDataStore) do(method string, path string, body []byte) (*http.Response, error) {
+	request, err := http.NewRequest(method, strings.TrimSuffix(store.URL, "/")+"/"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range store.Headers {
+		request.Header.Set(key, val)
+	}
+	client := store.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(request)
+}
+
+// doAndCheck sends a request and returns an error if the response has a status code other than 2xx, or 404 if
+// allowNotFound is true.
+func (store *HTTPTestDataStore) doAndCheck(method string, path string, body []byte, allowNotFound bool) error {
+	response, err := store.do(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if (response.StatusCode >= 200 && response.StatusCode < 300) || (allowNotFound && response.StatusCode == http.StatusNotFound) {
+		return nil
+	}
+	return fmt.Errorf("%s %s returned %s", method, path, response.Status)
+}
+
+// Save stores the data for the given path with a PUT request.
+func (store *HTTPTestDataStore) Save(path string, data []byte) error {
+	return store.doAndCheck(http.MethodPut, TestDataKey(path), data, false)
+}
+
+// Load returns the data for the given path with a GET request.
+func (store *HTTPTestDataStore) Load(path string) ([]byte, error) {
+	response, err := store.do(http.MethodGet, TestDataKey(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, TestDataNotFound{Path: path}
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("GET %s returned %s", TestDataKey(path), response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// Exists returns true if a HEAD request for the given path succeeds.
+func (store *HTTPTestDataStore) Exists(path string) (bool, error) {
+	response, err := store.do(http.MethodHead, TestDataKey(path), nil)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return false, fmt.Errorf("HEAD %s returned %s", TestDataKey(path), response.Status)
+	}
+	return true, nil
+}
+
+// Delete deletes the data for the given path with a DELETE request.
+func (store *HTTPTestDataStore) Delete(path string) error {
+	return store.doAndCheck(http.MethodDelete, TestDataKey(path), nil, true)
+}
+
+// DeleteAll deletes all the data under the given folder path with a DELETE request to its key followed by a slash.
+func (store *HTTPTestDataStore) DeleteAll(path string) error {
+	return store.doAndCheck(http.MethodDelete, TestDataKey(path)+"/", nil, true)
+}
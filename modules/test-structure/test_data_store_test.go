@@ -0,0 +1,238 @@
+package test_structure
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeObjectServer is an in-memory stand-in for an S3-compatible service (e.g., MinIO) that supports path-style requests
+// to put, get, head, delete, and list objects. If s3 is false, it behaves as a plain HTTP store instead, where a DELETE
+// request to a path ending with a slash deletes everything under it.
+type fakeObjectServer struct {
+	s3      bool
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func runFakeObjectServer(t *testing.T, s3 bool) (*fakeObjectServer, string) {
+	server := &fakeObjectServer{s3: s3, objects: map[string][]byte{}}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return server, httpServer.URL
+}
+
+func (server *fakeObjectServer) keys() []string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	keys := []string{}
+	for key := range server.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (server *fakeObjectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		type object struct {
+			Key string
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			Prefix      string
+			KeyCount    int
+			IsTruncated bool
+			Contents    []object
+		}{Name: key, Prefix: r.URL.Query().Get("prefix")}
+		for objectKey := range server.objects {
+			if strings.HasPrefix(objectKey, key+"/"+result.Prefix) {
+				result.Contents = append(result.Contents, object{Key: strings.TrimPrefix(objectKey, key+"/")})
+			}
+		}
+		result.KeyCount = len(result.Contents)
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		server.objects[key] = body
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		body, hasObject := server.objects[key]
+		if !hasObject {
+			w.WriteHeader(http.StatusNotFound)
+			if server.s3 && r.Method == http.MethodGet {
+				w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			}
+			return
+		}
+		w.Write(body)
+	case r.Method == http.MethodDelete && !server.s3 && strings.HasSuffix(key, "/"):
+		for objectKey := range server.objects {
+			if strings.HasPrefix(objectKey, key) {
+				delete(server.objects, objectKey)
+			}
+		}
+	case r.Method == http.MethodDelete:
+		delete(server.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeS3TestDataStore(t *testing.T, endpoint string, prefix string) *S3TestDataStore {
+	sess, err := session.NewSession(aws.NewConfig().
+		WithEndpoint(endpoint).
+		WithS3ForcePathStyle(true).
+		WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials("test", "test", "")))
+	require.NoError(t, err)
+	return &S3TestDataStore{Client: s3.New(sess), Bucket: "test-data", Prefix: prefix}
+}
+
+// testTestDataStore checks that the given store saves, loads, and deletes data.
+func testTestDataStore(t *testing.T, store TestDataStore) {
+	path := FormatTestDataPath("examples/foo", "TerraformOptions.json")
+
+	exists, err := store.Exists(path)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = store.Load(path)
+	assert.Equal(t, TestDataNotFound{Path: path}, err)
+
+	require.NoError(t, store.Save(path, []byte(`{"foo": "bar"}`)))
+	require.NoError(t, store.Save(FormatTestDataPath("examples/foo", "Other.json"), []byte(`1`)))
+	require.NoError(t, store.Save(FormatTestDataPath("examples/bar", "Other.json"), []byte(`2`)))
+	exists, err = store.Exists(path)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	data, err := store.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo": "bar"}`, string(data))
+
+	require.NoError(t, store.Delete(path))
+	require.NoError(t, store.Delete(path))
+	exists, err = store.Exists(path)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, store.DeleteAll(filepath.Join("examples", "foo", ".test-data")))
+	exists, err = store.Exists(FormatTestDataPath("examples/foo", "Other.json"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = store.Exists(FormatTestDataPath("examples/bar", "Other.json"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestTestDataKey(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := os.Getwd()
+	require.NoError(t, err)
+
+	assert.Equal(t, "examples/foo/.test-data/TerraformOptions.json", TestDataKey(FormatTestDataPath("examples/foo", "TerraformOptions.json")))
+	assert.Equal(t, "examples/foo/.test-data/TerraformOptions.json", TestDataKey(FormatTestDataPath(filepath.Join(workingDir, "examples", "foo"), "TerraformOptions.json")))
+
+	outside := filepath.Join(filepath.Dir(workingDir), "other", ".test-data", "TerraformOptions.json")
+	assert.Equal(t, strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(outside, filepath.VolumeName(outside))), "/"), TestDataKey(outside))
+}
+
+func TestLocalTestDataStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	testTestDataStore(t, &LocalTestDataStore{Dir: dir})
+	assert.FileExists(t, filepath.Join(dir, "examples", "bar", ".test-data", "Other.json"))
+}
+
+func TestS3TestDataStore(t *testing.T) {
+	t.Parallel()
+
+	server, endpoint := runFakeObjectServer(t, true)
+	testTestDataStore(t, newFakeS3TestDataStore(t, endpoint, "pipeline-1"))
+	assert.Equal(t, []string{"test-data/pipeline-1/examples/bar/.test-data/Other.json"}, server.keys())
+}
+
+func TestHTTPTestDataStore(t *testing.T) {
+	t.Parallel()
+
+	server, url := runFakeObjectServer(t, false)
+	testTestDataStore(t, &HTTPTestDataStore{URL: url + "/store/"})
+	assert.Equal(t, []string{"store/examples/bar/.test-data/Other.json"}, server.keys())
+}
+
+func TestGetTestDataStoreE(t *testing.T) {
+	testCases := []struct {
+		location string
+		expected TestDataStore
+	}{
+		{"", &LocalTestDataStore{}},
+		{"/shared/test-data", &LocalTestDataStore{Dir: "/shared/test-data"}},
+		{"file:///shared/test-data", &LocalTestDataStore{Dir: filepath.FromSlash("/shared/test-data")}},
+		{"https://artifacts.example.com/test-data", &HTTPTestDataStore{URL: "https://artifacts.example.com/test-data", Headers: map[string]string{"Authorization": "Bearer token"}}},
+	}
+	t.Setenv(TEST_DATA_STORE_HTTP_AUTH_ENV_VAR, "Bearer token")
+	for _, testCase := range testCases {
+		t.Setenv(TEST_DATA_STORE_ENV_VAR, testCase.location)
+		store, err := GetTestDataStoreE()
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, store, testCase.location)
+	}
+
+	t.Setenv(TEST_DATA_STORE_ENV_VAR, "s3://my-bucket/my/prefix")
+	store, err := GetTestDataStoreE()
+	require.NoError(t, err)
+	require.IsType(t, &S3TestDataStore{}, store)
+	assert.Equal(t, "my-bucket", store.(*S3TestDataStore).Bucket)
+	assert.Equal(t, "my/prefix", store.(*S3TestDataStore).Prefix)
+
+	t.Setenv(TEST_DATA_STORE_ENV_VAR, "ftp://example.com")
+	_, err = GetTestDataStoreE()
+	assert.Error(t, err)
+}
+
+func TestSaveAndLoadTerraformOptionsWithS3TestDataStore(t *testing.T) {
+	server, endpoint := runFakeObjectServer(t, true)
+	t.Setenv(TEST_DATA_STORE_ENV_VAR, "s3://test-data/pipeline-1")
+	t.Setenv(TEST_DATA_STORE_S3_ENDPOINT_ENV_VAR, endpoint)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	testFolder := filepath.Join("examples", t.Name())
+	expected := &terraform.Options{TerraformDir: "/abc/def/ghi", Vars: map[string]interface{}{"foo": "bar"}}
+	SaveTerraformOptions(t, testFolder, expected)
+	assert.False(t, files.FileExists(formatTerraformOptionsPath(testFolder)))
+	assert.Equal(t, []string{"test-data/pipeline-1/examples/" + t.Name() + "/.test-data/TerraformOptions.json"}, server.keys())
+
+	actual := LoadTerraformOptions(t, testFolder)
+	assert.Equal(t, expected.TerraformDir, actual.TerraformDir)
+	assert.Equal(t, expected.Vars, actual.Vars)
+	assert.True(t, IsTestDataPresent(t, formatTerraformOptionsPath(testFolder)))
+
+	CleanupTestDataFolder(t, testFolder)
+	assert.Empty(t, server.keys())
+}
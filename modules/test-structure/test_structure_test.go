@@ -68,6 +68,8 @@ func TestValidateAllTerraformModulesOnTerratest(t *testing.T) {
 	opts, optsErr := NewValidationOptions(projectRootDir, []string{}, []string{
 		"test/fixtures/terraform-with-plan-error",
 		"test/fixtures/terragrunt/terragrunt-with-plan-error",
+		"test/fixtures/terraform-module-contract/compliant",
+		"test/fixtures/terraform-module-contract/non-compliant",
 		"examples/terraform-backend-example",
 	})
 	require.NoError(t, optsErr)
@@ -0,0 +1,14 @@
+variable "vpc_cidr" {
+  type    = string
+  default = "10.0.0.0/16"
+}
+
+variable "azs" {
+  type    = list(string)
+  default = ["us-east-1a", "us-east-1b"]
+}
+
+locals {
+  private_subnets = [for i, az in var.azs : cidrsubnet(var.vpc_cidr, 8, i + 1)]
+  subnets_by_az   = zipmap(var.azs, local.private_subnets)
+}
@@ -0,0 +1,13 @@
+terraform {
+  # Leave the config for this backend unspecified so Terratest can point it at a test server.
+  backend "http" {}
+}
+
+variable "foo" {
+  description = "Some data to store as an output of this module"
+  type        = string
+}
+
+output "foo" {
+  value = var.foo
+}
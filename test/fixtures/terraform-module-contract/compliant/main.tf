@@ -0,0 +1,33 @@
+terraform {
+  required_version = ">= 1.3.0"
+
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      version               = "~> 5.0"
+      configuration_aliases = [aws.replica]
+    }
+  }
+}
+
+resource "aws_s3_bucket" "this" {
+  bucket = module.label.id
+}
+
+resource "aws_s3_bucket" "replica" {
+  provider = aws.replica
+  bucket   = "${module.label.id}-replica"
+}
+
+data "aws_caller_identity" "current" {}
+
+resource "terraform_data" "marker" {
+  input = var.name
+}
+
+module "label" {
+  source = "./modules/label"
+
+  name = var.name
+  tags = var.tags
+}
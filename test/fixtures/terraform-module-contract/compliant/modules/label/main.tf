@@ -0,0 +1,15 @@
+variable "name" {
+  type        = string
+  description = "The name to build the label from."
+}
+
+variable "tags" {
+  type        = map(string)
+  description = "The tags to add to the label."
+  default     = {}
+}
+
+output "id" {
+  description = "The label."
+  value       = lower(var.name)
+}
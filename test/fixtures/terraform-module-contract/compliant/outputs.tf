@@ -0,0 +1,10 @@
+output "bucket_arn" {
+  description = "The ARN of the bucket."
+  value       = aws_s3_bucket.this.arn
+}
+
+output "password" {
+  description = "The secret."
+  value       = var.password
+  sensitive   = true
+}
@@ -0,0 +1,26 @@
+variable "name" {
+  type        = string
+  description = "The name of the bucket."
+
+  validation {
+    condition     = length(var.name) > 3
+    error_message = "The name must be longer than 3 characters."
+  }
+}
+
+variable "tags" {
+  type        = map(string)
+  description = <<-EOT
+    The tags to apply to the bucket.
+  EOT
+  default = {
+    Team = "platform"
+  }
+}
+
+variable "password" {
+  type        = string
+  description = "A secret."
+  default     = null
+  sensitive   = true
+}
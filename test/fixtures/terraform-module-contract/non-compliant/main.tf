@@ -0,0 +1,37 @@
+terraform {
+  required_providers {
+    random = "~> 3.0"
+    null = {
+      source = "hashicorp/null"
+    }
+  }
+}
+
+provider "aws" {
+  region = "us-east-1"
+}
+
+provider "aws" {
+  alias  = "west"
+  region = "us-west-2"
+}
+
+variable "untyped" {
+  description = "A variable without a type."
+}
+
+variable "settings" {
+  type = map(any)
+}
+
+resource "random_pet" "this" {}
+
+resource "null_resource" "this" {}
+
+resource "aws_instance" "web" {
+  provider = aws.west
+}
+
+output "pet" {
+  value = random_pet.this.id
+}
@@ -0,0 +1,8 @@
+run "wrong_greeting" {
+  command = plan
+
+  assert {
+    condition     = output.greeting == "Goodbye, terratest!"
+    error_message = "Greeting is not a goodbye"
+  }
+}
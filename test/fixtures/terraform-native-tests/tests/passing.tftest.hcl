@@ -0,0 +1,21 @@
+run "default_greeting" {
+  command = plan
+
+  assert {
+    condition     = output.greeting == "Hello, terratest!"
+    error_message = "Unexpected default greeting"
+  }
+}
+
+run "custom_greeting" {
+  command = plan
+
+  variables {
+    name = "world"
+  }
+
+  assert {
+    condition     = output.greeting == "Hello, world!"
+    error_message = "Unexpected custom greeting"
+  }
+}
@@ -0,0 +1,28 @@
+variable "instance_type" {
+  type    = string
+  default = "t3.micro"
+
+  validation {
+    condition     = startswith(var.instance_type, "t3.")
+    error_message = "Only t3 instance types are supported."
+  }
+}
+
+variable "instance_count" {
+  type    = number
+  default = 1
+}
+
+variable "max_instance_count" {
+  type    = number
+  default = 3
+}
+
+output "instance_count" {
+  value = var.instance_count
+
+  precondition {
+    condition     = var.instance_count <= var.max_instance_count
+    error_message = "The instance count must not exceed the max instance count."
+  }
+}
@@ -0,0 +1,7 @@
+variable "cidr_block" {
+  type = string
+}
+
+output "greeting" {
+  value = "app in ${var.cidr_block}"
+}
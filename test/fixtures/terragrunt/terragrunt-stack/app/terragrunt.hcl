@@ -0,0 +1,15 @@
+locals {
+  name = "app"
+}
+
+dependency "network" {
+  config_path = "../network"
+
+  mock_outputs = {
+    cidr_block = "0.0.0.0/0"
+  }
+}
+
+inputs = {
+  cidr_block = dependency.network.outputs.cidr_block
+}
@@ -0,0 +1,7 @@
+variable "cidr_block" {
+  type = string
+}
+
+output "cidr_block" {
+  value = var.cidr_block
+}
@@ -0,0 +1,3 @@
+inputs = {
+  cidr_block = "10.0.0.0/16"
+}